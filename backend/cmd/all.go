@@ -0,0 +1,35 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+
+	"github.com/furqanmk/reviews-browser/config"
+)
+
+// StartAll runs the API server and the schedulers in one process, sharing a
+// single set of services, until ctx is cancelled or either of them fails.
+func StartAll(ctx context.Context, watcher *config.Watcher) error {
+	svc, err := watchServices(ctx, watcher)
+	if err != nil {
+		return err
+	}
+	defer svc.Close()
+
+	// one failing brings the other down too
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	errs := make(chan error, 2)
+	go func() {
+		errs <- serveAPI(ctx, svc)
+		cancel()
+	}()
+	go func() {
+		errs <- schedule(ctx, svc)
+		cancel()
+	}()
+
+	logger.Info("running API server and schedulers in one process")
+	return errors.Join(<-errs, <-errs)
+}
@@ -3,76 +3,74 @@ package cmd
 import (
 	"context"
 	"fmt"
-	"log"
 	"net/http"
-	"os"
-	"os/signal"
 	"time"
 
 	"github.com/furqanmk/reviews-browser/config"
 	"github.com/furqanmk/reviews-browser/internal/api"
-	"github.com/furqanmk/reviews-browser/internal/clients/appstore"
-	"github.com/furqanmk/reviews-browser/internal/database"
+	"github.com/furqanmk/reviews-browser/internal/jobs"
+	"github.com/furqanmk/reviews-browser/internal/polling"
 )
 
-func StartAPIServer() {
-	ctx := context.Background()
-
-	// Setup context for graceful shutdown
-	ctx, stop := signal.NotifyContext(ctx, os.Interrupt)
-	defer stop()
-
-	// Load the .env file into the environment
-	if err := config.LoadEnv(); err != nil {
-		log.Fatalf("Error loading .env file: %v", err)
-	}
-
-	// Get application configuration
-	cfg, err := config.GetConfig()
+// StartAPIServer serves the HTTP API until ctx is cancelled, then shuts the
+// server down gracefully.
+func StartAPIServer(ctx context.Context, watcher *config.Watcher) error {
+	svc, err := watchServices(ctx, watcher)
 	if err != nil {
-		log.Fatalf("Error getting config: %v", err)
+		return err
 	}
+	defer svc.Close()
 
-	// Initialize database connection
-	db, err := database.NewDBConnection(cfg)
-	if err != nil {
-		log.Fatalf("Failed to connect to database: %v", err)
-	}
-	defer db.Close()
+	return serveAPI(ctx, svc)
+}
 
+func serveAPI(ctx context.Context, svc *services) error {
 	// Start a new HTTP server
 	mux := http.NewServeMux()
 
-	// initialize the App Store API Client
-	apiClient := appstore.NewClient(cfg)
-
 	// Start service
-	api := api.NewAPI(db, apiClient)
-	api.RegisterHandlers(mux)
+	handlers := api.NewAPI(svc.db, svc.client, svc.pipeline, svc.cfg)
+	if err := handlers.RegisterHandlers(mux); err != nil {
+		return err
+	}
+	queue := jobs.NewQueue(polling.NewPollingScheduler(svc.db, svc.client, svc.pipeline).PollOnce)
+	go queue.Run(ctx)
+	handlers.SetJobs(queue)
+	handlers.Listen(ctx, svc.bus)
+	go svc.follow(ctx, handlers.SetConfig)
 
+	// Trace and Instrument read the route from the request the mux matched,
+	// so nothing between them and the mux may copy it. Compress sits outside
+	// them, so that they see responses as handlers wrote them.
 	server := &http.Server{
-		Addr:    fmt.Sprintf(":%s", cfg.ServerPort),
-		Handler: mux,
+		Addr:    fmt.Sprintf(":%s", svc.cfg.ServerPort),
+		Handler: api.RequestID(api.Compress(api.Trace(api.Instrument(mux)))),
 	}
 
 	// Run server in a goroutine
+	serverErr := make(chan error, 1)
 	go func() {
-		log.Printf("Starting server on :%s", cfg.ServerPort)
+		logger.Info("starting server", "port", svc.cfg.ServerPort)
 		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Fatalf("Server error: %v", err)
+			serverErr <- err
 		}
 	}()
 
 	// Wait for interrupt signal
-	<-ctx.Done()
-	log.Println("Shutting down server...")
+	select {
+	case <-ctx.Done():
+	case err := <-serverErr:
+		return fmt.Errorf("server error: %w", err)
+	}
+	logger.Info("shutting down server")
 
 	// Graceful shutdown
 	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 	if err := server.Shutdown(shutdownCtx); err != nil {
-		log.Fatalf("Server forced to shutdown: %v", err)
+		return fmt.Errorf("server forced to shutdown: %w", err)
 	}
 
-	log.Println("Server exited properly")
+	logger.Info("server exited properly")
+	return nil
 }
@@ -0,0 +1,178 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/furqanmk/reviews-browser/internal/database"
+	"github.com/furqanmk/reviews-browser/internal/metadata"
+	"github.com/furqanmk/reviews-browser/internal/model"
+)
+
+func appsCommands() []*command {
+	return []*command{
+		{name: "list", summary: "List tracked apps", run: runAppsList},
+		{name: "add", args: "<app-id>", summary: "Start tracking an app", run: runAppsAdd},
+		{name: "remove", args: "<app-id>", summary: "Stop tracking an app", run: runAppsRemove},
+		{name: "set-interval", args: "<app-id> <seconds>", summary: "Change how often an app is polled", run: runAppsSetInterval},
+		{name: "refresh", args: "[<app-id>...]", summary: "Look up apps' App Store details now", run: runAppsRefresh},
+	}
+}
+
+func runAppsList(args []string) error {
+	fs, flags := newFlagSet("apps list", "", "List tracked apps.")
+	asJSON := fs.Bool("json", false, "print JSON instead of a table")
+	if err := parse(fs, args, 0, 0); err != nil {
+		return err
+	}
+	db, err := openDB(flags)
+	if err != nil {
+		return err
+	}
+
+	apps, err := db.GetApps(context.Background())
+	if err != nil {
+		return err
+	}
+	if *asJSON {
+		return json.NewEncoder(stdout).Encode(apps)
+	}
+
+	w := tabwriter.NewWriter(stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tNAME\tLAST FETCHED\tLAST SUCCESS\tPOLL EVERY\tLAST ERROR")
+	for _, app := range apps {
+		lastSuccess := "never"
+		if !app.LastSuccess.IsZero() {
+			lastSuccess = app.LastSuccess.Format(time.RFC3339)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", app.ID, app.Metadata.Name, app.LastFetched.Format(time.RFC3339), lastSuccess,
+			time.Duration(app.PollEverySeconds)*time.Second, app.LastError)
+	}
+	return w.Flush()
+}
+
+func runAppsAdd(args []string) error {
+	fs, flags := newFlagSet("apps add", "<app-id>", "Start tracking an app. It is polled as soon as the schedulers next start.")
+	pollEvery := fs.Int("poll-every", 300, "`seconds` between polls")
+	if err := parse(fs, args, 1, 1); err != nil {
+		return err
+	}
+	appID, err := parseAppID(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	if *pollEvery <= 0 {
+		return usagef("apps add: -poll-every must be positive")
+	}
+	db, err := openDB(flags)
+	if err != nil {
+		return err
+	}
+
+	err = db.AddApp(context.Background(), model.App{ID: appID, PollEverySeconds: *pollEvery})
+	if err == database.ErrExists {
+		return fmt.Errorf("app %s is already tracked", appID)
+	}
+	return err
+}
+
+func runAppsRemove(args []string) error {
+	fs, flags := newFlagSet("apps remove", "<app-id>", "Stop tracking an app.")
+	if err := parse(fs, args, 1, 1); err != nil {
+		return err
+	}
+	db, err := openDB(flags)
+	if err != nil {
+		return err
+	}
+
+	err = db.RemoveApp(context.Background(), fs.Arg(0))
+	if err == database.ErrNotFound {
+		return fmt.Errorf("app %s is not tracked", fs.Arg(0))
+	}
+	return err
+}
+
+func runAppsSetInterval(args []string) error {
+	fs, flags := newFlagSet("apps set-interval", "<app-id> <seconds>", "Change how often an app is polled.")
+	if err := parse(fs, args, 2, 2); err != nil {
+		return err
+	}
+	seconds, err := strconv.Atoi(fs.Arg(1))
+	if err != nil || seconds <= 0 {
+		return usagef("apps set-interval: seconds must be a positive integer")
+	}
+	db, err := openDB(flags)
+	if err != nil {
+		return err
+	}
+
+	err = db.SetPollInterval(context.Background(), fs.Arg(0), seconds)
+	if err == database.ErrNotFound {
+		return fmt.Errorf("app %s is not tracked", fs.Arg(0))
+	}
+	return err
+}
+
+func runAppsRefresh(args []string) error {
+	fs, flags := newFlagSet("apps refresh", "[<app-id>...]",
+		"Look up the name, icon, version and store rating of the given tracked apps, or of every tracked app, from the App Store now.")
+	if err := parse(fs, args, 0, -1); err != nil {
+		return err
+	}
+	cfg, err := flags.load()
+	if err != nil {
+		return err
+	}
+	svc, err := newServices(cfg)
+	if err != nil {
+		return err
+	}
+	defer svc.Close()
+
+	ctx, stop := signalContext()
+	defer stop()
+	appIDs := fs.Args()
+	if len(appIDs) == 0 {
+		apps, err := svc.db.GetApps(ctx)
+		if err != nil {
+			return err
+		}
+		for _, app := range apps {
+			appIDs = append(appIDs, app.ID)
+		}
+	}
+
+	refresher := metadata.NewRefresher(svc.db, svc.client, cfg)
+	for _, appID := range appIDs {
+		details, err := refresher.Refresh(ctx, appID)
+		if err == database.ErrNotFound {
+			return fmt.Errorf("app %s is not tracked", appID)
+		}
+		if err != nil {
+			return fmt.Errorf("refreshing app %s: %w", appID, err)
+		}
+		fmt.Fprintf(stdout, "%s: %s by %s, version %s\n", appID, details.Name, details.Developer, details.Version)
+	}
+	return nil
+}
+
+// parseAppID checks an App Store app ID, which is numeric.
+func parseAppID(s string) (string, error) {
+	if !model.ValidAppID(s) {
+		return "", usagef("invalid app ID %q: must be numeric", s)
+	}
+	return s, nil
+}
+
+func openDB(flags *configFlags) (*database.DB, error) {
+	cfg, err := flags.load()
+	if err != nil {
+		return nil, err
+	}
+	return database.NewDBConnection(cfg)
+}
@@ -0,0 +1,253 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+
+	"github.com/furqanmk/reviews-browser/config"
+)
+
+// Exit codes returned by Run.
+const (
+	ExitOK    = 0
+	ExitError = 1
+	ExitUsage = 2
+)
+
+const programName = "reviews-browser"
+
+// command is a CLI subcommand. Leaf commands have run; groups have sub.
+type command struct {
+	name    string
+	aliases []string
+	args    string
+	summary string
+	run     func(args []string) error
+	sub     []*command
+}
+
+// usageError marks errors caused by bad command-line input.
+type usageError struct {
+	msg string
+}
+
+func (e *usageError) Error() string {
+	return e.msg
+}
+
+func usagef(format string, args ...any) error {
+	return &usageError{msg: fmt.Sprintf(format, args...)}
+}
+
+var (
+	stdout io.Writer = os.Stdout
+	stderr io.Writer = os.Stderr
+)
+
+// Run executes the command line and returns the process exit code: ExitOK on
+// success, ExitUsage for bad arguments and ExitError when the command fails.
+func Run(args []string) int {
+	err := dispatch(commands(), args, programName)
+	var usage *usageError
+	switch {
+	case err == nil, errors.Is(err, flag.ErrHelp):
+		return ExitOK
+	case errors.As(err, &usage):
+		fmt.Fprintln(stderr, err)
+		return ExitUsage
+	default:
+		fmt.Fprintln(stderr, "error:", err)
+		return ExitError
+	}
+}
+
+func commands() []*command {
+	return []*command{
+		{name: "serve", aliases: []string{"api"}, summary: "Run the HTTP API server", run: runServe},
+		{name: "schedulers", summary: "Run the polling, cleanup and metadata schedulers", run: runSchedulers},
+		{name: "all", summary: "Run the API server and schedulers in one process", run: runAll},
+		{name: "apps", summary: "Manage tracked apps", sub: appsCommands()},
+		{name: "reviews", summary: "Browse stored reviews", sub: reviewsCommands()},
+		{name: "poll", summary: "Fetch reviews from the App Store", sub: pollCommands()},
+		{name: "import", args: "<file>", summary: "Import reviews from a CSV or NDJSON file", run: runImport},
+		{name: "cleanup", summary: "Purge old reviews", sub: cleanupCommands()},
+		{name: "keys", summary: "Manage API keys", sub: keysCommands()},
+		{name: "db", summary: "Maintain the data store", sub: dbCommands()},
+		{name: "config", summary: "Inspect the configuration", sub: configCommands()},
+	}
+}
+
+func dispatch(cmds []*command, args []string, prefix string) error {
+	if len(args) == 0 {
+		printCommands(stderr, cmds, prefix)
+		return usagef("%s: missing command", prefix)
+	}
+	if name := args[0]; name == "help" || name == "-h" || name == "--help" || name == "-help" {
+		printCommands(stdout, cmds, prefix)
+		return nil
+	}
+
+	for _, c := range cmds {
+		if c.name != args[0] && !slices.Contains(c.aliases, args[0]) {
+			continue
+		}
+		if c.sub != nil {
+			return dispatch(c.sub, args[1:], prefix+" "+c.name)
+		}
+		return c.run(args[1:])
+	}
+
+	printCommands(stderr, cmds, prefix)
+	return usagef("%s: unknown command %q", prefix, args[0])
+}
+
+func printCommands(w io.Writer, cmds []*command, prefix string) {
+	fmt.Fprintf(w, "Usage: %s <command> [flags]\n\nCommands:\n", prefix)
+	tw := tabwriter.NewWriter(w, 0, 4, 3, ' ', 0)
+	for _, c := range cmds {
+		name := c.name
+		if c.args != "" {
+			name += " " + c.args
+		}
+		fmt.Fprintf(tw, "  %s\t%s\n", name, c.summary)
+	}
+	tw.Flush()
+	fmt.Fprintf(w, "\nRun '%s <command> -h' for the flags a command takes.\n", prefix)
+}
+
+// configFlags are accepted by every command. Flags for individual settings
+// override the config file and the environment.
+type configFlags struct {
+	configFile string
+	envFile    string
+	overrides  func() map[string]string
+}
+
+// newFlagSet creates the flag set for a leaf command, with the config flags
+// already registered.
+func newFlagSet(name, args, summary string) (*flag.FlagSet, *configFlags) {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintf(stderr, "Usage: %s %s [flags] %s\n\n%s\n\nFlags:\n", programName, name, args, summary)
+		fs.PrintDefaults()
+	}
+
+	f := &configFlags{}
+	fs.StringVar(&f.configFile, "config", "", "`path` of a YAML or TOML config file (CONFIG_FILE)")
+	fs.StringVar(&f.envFile, "env-file", "", "`path` of the env file to load (default .env, if present)")
+	f.overrides = config.RegisterFlags(fs)
+	return fs, f
+}
+
+// parse parses args, converting flag errors into usage errors and checking the
+// number of positional arguments.
+func parse(fs *flag.FlagSet, args []string, minArgs, maxArgs int) error {
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return err
+		}
+		return &usageError{msg: err.Error()}
+	}
+	if n := fs.NArg(); n < minArgs || maxArgs >= 0 && n > maxArgs {
+		fs.Usage()
+		return usagef("%s: wrong number of arguments", fs.Name())
+	}
+	return nil
+}
+
+// load reads the configuration, layering the flags that were set over the
+// config file, environment and defaults.
+func (f *configFlags) load() (*config.Config, error) {
+	return config.Load(f.options())
+}
+
+// watch loads the configuration like load, returning a watcher that reloads
+// it the same way, for long-running commands.
+func (f *configFlags) watch() (*config.Watcher, error) {
+	opts := f.options()
+	cfg, err := config.Load(opts)
+	if err != nil {
+		return nil, err
+	}
+	return config.NewWatcher(cfg, opts), nil
+}
+
+func (f *configFlags) options() config.Options {
+	return config.Options{
+		File:           f.configFile,
+		EnvFile:        f.envFile,
+		Overrides:      f.overrides(),
+		OverrideSource: "flag",
+	}
+}
+
+// signalContext is cancelled on interrupt or termination, for long-running
+// commands.
+func signalContext() (context.Context, context.CancelFunc) {
+	return signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+}
+
+func runServe(args []string) error {
+	fs, flags := newFlagSet("serve", "", "Run the HTTP API server.")
+	if err := parse(fs, args, 0, 0); err != nil {
+		return err
+	}
+	watcher, err := flags.watch()
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signalContext()
+	defer stop()
+	return StartAPIServer(ctx, watcher)
+}
+
+func runSchedulers(args []string) error {
+	fs, flags := newFlagSet("schedulers", "", "Run the polling, cleanup and metadata schedulers.")
+	if err := parse(fs, args, 0, 0); err != nil {
+		return err
+	}
+	watcher, err := flags.watch()
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signalContext()
+	defer stop()
+	return StartSchedulers(ctx, watcher)
+}
+
+func runAll(args []string) error {
+	fs, flags := newFlagSet("all", "", "Run the API server and the schedulers in one process, sharing one store, App Store client and event bus.")
+	if err := parse(fs, args, 0, 0); err != nil {
+		return err
+	}
+	watcher, err := flags.watch()
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signalContext()
+	defer stop()
+	return StartAll(ctx, watcher)
+}
+
+// truncate shortens s to at most n runes for table output.
+func truncate(s string, n int) string {
+	s = strings.Join(strings.Fields(s), " ")
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n-1]) + "…"
+}
@@ -0,0 +1,86 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRun_ExitCodes(t *testing.T) {
+	dir := t.TempDir()
+	envFile := filepath.Join(dir, ".env")
+	require.NoError(t, os.WriteFile(envFile, []byte("RECENCY_CUTOFF_HRS=48\n"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "apps.csv"), []byte("id,last_fetched,poll_every_seconds\n"), 0644))
+	created := time.Now().UTC().Add(-time.Hour).Format(time.RFC3339)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "reviews.csv"), []byte("id,app_id,author,title,content,rating,date\n"+
+		"r1,123,Ann,\"Great, really\",Loved it,5,"+created+"\n"+
+		"r2,456,Bob,Meh,Crashes,2,"+created+"\n"), 0644))
+
+	var out bytes.Buffer
+	stdout, stderr = &out, &out
+	t.Cleanup(func() { stdout, stderr = os.Stdout, os.Stderr })
+
+	flags := []string{"-env-file", envFile, "-apps-csv", filepath.Join(dir, "apps.csv"), "-keys-csv", filepath.Join(dir, "keys.csv"),
+		"-reviews-csv", filepath.Join(dir, "reviews.csv"), "-triage-csv", filepath.Join(dir, "triage.csv"),
+		"-versions-csv", filepath.Join(dir, "versions.csv")}
+	run := func(args ...string) int {
+		out.Reset()
+		return Run(append(args[:2:2], append(flags, args[2:]...)...))
+	}
+
+	require.Equal(t, ExitUsage, Run(nil))
+	require.Equal(t, ExitUsage, Run([]string{"bogus"}))
+	require.Equal(t, ExitOK, Run([]string{"help"}))
+
+	require.Equal(t, ExitOK, run("apps", "add", "123"))
+	require.Equal(t, ExitError, run("apps", "add", "123"))
+	require.Equal(t, ExitUsage, run("apps", "add", "not-a-number"))
+	require.Equal(t, ExitOK, run("apps", "set-interval", "123", "90"))
+
+	require.Equal(t, ExitOK, run("apps", "list"))
+	require.Contains(t, out.String(), "1m30s")
+
+	require.Equal(t, ExitOK, run("apps", "remove", "123"))
+	require.Equal(t, ExitError, run("apps", "remove", "123"))
+
+	require.Equal(t, ExitUsage, run("keys", "create", "-role", "owner", "ops"))
+	require.Equal(t, ExitUsage, run("keys", "create", "-apps", "123,123", "ops"))
+	require.Equal(t, ExitUsage, run("keys", "create", "-apps", "123,", "ops"))
+	require.Equal(t, ExitUsage, run("keys", "create", "-role", "feed", "ops"))
+	require.Equal(t, ExitOK, run("keys", "create", "-role", "triager", "-apps", "123", "ops"))
+	require.Contains(t, out.String(), "rb_")
+	require.Equal(t, ExitOK, run("keys", "list"))
+	require.Contains(t, out.String(), "triager")
+	require.Equal(t, ExitError, run("keys", "revoke", "nope"))
+
+	require.Equal(t, ExitOK, run("reviews", "export", "-columns", "id,title,triage_status", "123"))
+	require.Equal(t, "id,title,triage_status\nr1,\"Great, really\",new\n", out.String())
+	require.Equal(t, ExitOK, run("reviews", "export", "-format", "ndjson", "-columns", "id"))
+	require.Equal(t, "{\"id\":\"r1\"}\n{\"id\":\"r2\"}\n", out.String())
+	require.Equal(t, ExitUsage, run("reviews", "export", "-format", "xlsx"))
+	require.Equal(t, ExitUsage, run("reviews", "export", "-columns", "bogus"))
+
+	// import is a command of its own, not in a group
+	importFile := func(args ...string) int {
+		out.Reset()
+		return Run(append(append([]string{"import"}, flags...), args...))
+	}
+	dump := filepath.Join(dir, "dump.csv")
+	require.NoError(t, os.WriteFile(dump, []byte("id,rating,date,content\n"+
+		"r1,5,"+created+",Already stored\n"+
+		"r3,4,"+created+",Works offline now\n"+
+		"r4,0,"+created+",No rating\n"), 0644))
+	require.Equal(t, ExitOK, importFile("-app", "123", "-map", "created_at=date", "-dry-run", dump))
+	require.Contains(t, out.String(), "Dry run: 2 of 3 rows are valid")
+	require.Contains(t, out.String(), "line 4 (review r4)")
+	require.Equal(t, ExitOK, importFile("-app", "123", "-map", "created_at=date", dump), out.String())
+	require.Contains(t, out.String(), "1 new reviews, 1 duplicates")
+	require.Equal(t, ExitOK, run("reviews", "export", "-columns", "id", "123"))
+	require.Equal(t, "id\nr1\nr3\n", out.String())
+	require.Equal(t, ExitError, importFile("-app", "123", dump), "no created_at column")
+	require.Equal(t, ExitUsage, importFile("-map", "stars=rating", dump))
+}
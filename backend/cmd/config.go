@@ -0,0 +1,39 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"text/tabwriter"
+)
+
+func configCommands() []*command {
+	return []*command{
+		{name: "print", summary: "Show effective settings and where each came from", run: runConfigPrint},
+	}
+}
+
+func runConfigPrint(args []string) error {
+	fs, flags := newFlagSet("config print", "", "Show the effective value of every setting and its source: default, config file, environment variable or flag. Secrets are masked.")
+	asJSON := fs.Bool("json", false, "print JSON instead of a table")
+	if err := parse(fs, args, 0, 0); err != nil {
+		return err
+	}
+	cfg, err := flags.load()
+	if err != nil {
+		return err
+	}
+
+	settings := cfg.Settings()
+	if *asJSON {
+		enc := json.NewEncoder(stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(settings)
+	}
+
+	tw := tabwriter.NewWriter(stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "KEY\tVALUE\tSOURCE")
+	for _, s := range settings {
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", s.Key, s.Value, s.Source)
+	}
+	return tw.Flush()
+}
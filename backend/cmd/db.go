@@ -0,0 +1,86 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"time"
+)
+
+func dbCommands() []*command {
+	return []*command{
+		{name: "migrate", summary: "Create missing tables and upgrade old ones", run: runDBMigrate},
+		{name: "check", summary: "Report schema problems; exits non-zero if any", run: runDBCheck},
+		{name: "backup", summary: "Copy the data files to a backup directory", run: runDBBackup},
+		{name: "restore", args: "<dir>", summary: "Replace the data files from a backup", run: runDBRestore},
+	}
+}
+
+func runDBMigrate(args []string) error {
+	fs, flags := newFlagSet("db migrate", "", "Create missing tables and rewrite tables with an older schema.")
+	if err := parse(fs, args, 0, 0); err != nil {
+		return err
+	}
+	db, err := openDB(flags)
+	if err != nil {
+		return err
+	}
+	return db.Migrate(context.Background())
+}
+
+func runDBCheck(args []string) error {
+	fs, flags := newFlagSet("db check", "", "Report schema problems. Exits with status 1 if any are found.")
+	if err := parse(fs, args, 0, 0); err != nil {
+		return err
+	}
+	db, err := openDB(flags)
+	if err != nil {
+		return err
+	}
+
+	problems, err := db.Check(context.Background())
+	if err != nil {
+		return err
+	}
+	for _, problem := range problems {
+		fmt.Fprintln(stdout, problem)
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("%d problem(s) found", len(problems))
+	}
+	fmt.Fprintln(stdout, "ok")
+	return nil
+}
+
+func runDBBackup(args []string) error {
+	fs, flags := newFlagSet("db backup", "", "Copy the data files into a new backup directory and print its path.")
+	dir := fs.String("dir", "", "backup `directory` (default ./backups/<timestamp>)")
+	if err := parse(fs, args, 0, 0); err != nil {
+		return err
+	}
+	db, err := openDB(flags)
+	if err != nil {
+		return err
+	}
+
+	if *dir == "" {
+		*dir = filepath.Join("backups", time.Now().UTC().Format("20060102T150405Z"))
+	}
+	if err := db.Backup(context.Background(), *dir); err != nil {
+		return err
+	}
+	fmt.Fprintln(stdout, *dir)
+	return nil
+}
+
+func runDBRestore(args []string) error {
+	fs, flags := newFlagSet("db restore", "<dir>", "Replace the data files with the copies in a backup directory.")
+	if err := parse(fs, args, 1, 1); err != nil {
+		return err
+	}
+	db, err := openDB(flags)
+	if err != nil {
+		return err
+	}
+	return db.Restore(context.Background(), fs.Arg(0))
+}
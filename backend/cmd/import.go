@@ -0,0 +1,121 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/furqanmk/reviews-browser/internal/importer"
+)
+
+func runImport(args []string) error {
+	fs, flags := newFlagSet("import", "<file>",
+		"Import reviews from a CSV or NDJSON file, or standard input if the file is -, through the same redaction,\n"+
+			"language detection and deduplication as polled reviews. Rows need an id, rating (1-5) and created_at\n"+
+			"(RFC 3339, \"2006-01-02 15:04:05\" or \"2006-01-02\", UTC without a zone), and an app_id unless -app is given.\n"+
+			"Reads and cleanups only keep reviews within recency_cutoff, so raise it to keep older reviews.")
+	app := fs.String("app", "", "`app-id` of rows without an app_id")
+	format := fs.String("format", "", "input format: "+strings.Join(importer.Formats, ", ")+" (default from the file name, else csv)")
+	mapping := fs.String("map", "", "comma-separated `field=column` pairs naming the columns fields are read from, e.g. created_at=date")
+	dryRun := fs.Bool("dry-run", false, "validate the file without storing anything")
+	asJSON := fs.Bool("json", false, "print the report as JSON")
+	if err := parse(fs, args, 1, 1); err != nil {
+		return err
+	}
+	if *app != "" {
+		if _, err := parseAppID(*app); err != nil {
+			return err
+		}
+	}
+	fields, err := importer.ParseMapping(*mapping)
+	if err != nil {
+		return usagef("import: %v", err)
+	}
+	name := fs.Arg(0)
+	if *format == "" {
+		*format = importer.FormatOf(name)
+	}
+	if !slices.Contains(importer.Formats, *format) {
+		return usagef("import: unknown format %q", *format)
+	}
+
+	var in io.Reader = os.Stdin
+	if name != "-" {
+		file, err := os.Open(name)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		in = file
+	}
+	cfg, err := flags.load()
+	if err != nil {
+		return err
+	}
+	svc, err := newServices(cfg)
+	if err != nil {
+		return err
+	}
+	defer svc.Close()
+
+	ctx, stop := signalContext()
+	defer stop()
+	report, err := importer.Import(ctx, in, svc.pipeline, importer.Options{
+		Format:  *format,
+		AppID:   *app,
+		Mapping: fields,
+		DryRun:  *dryRun,
+		Cutoff:  cfg.RecencyCutoff,
+		Progress: func(r importer.Report) {
+			fmt.Fprintf(stderr, "%d rows read: %d valid, %d invalid, %d new, %d duplicates\n", r.Rows, r.Valid, r.Invalid, r.New, r.Duplicate)
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("importing %s: %w", name, err)
+	}
+
+	if *asJSON {
+		if err := json.NewEncoder(stdout).Encode(report); err != nil {
+			return err
+		}
+	} else {
+		printImportReport(report)
+	}
+	if report.Failed > 0 {
+		return fmt.Errorf("%d reviews could not be stored", report.Failed)
+	}
+	return nil
+}
+
+func printImportReport(r importer.Report) {
+	if r.DryRun {
+		fmt.Fprintf(stdout, "Dry run: %d of %d rows are valid and would be imported\n", r.Valid, r.Rows)
+	} else {
+		fmt.Fprintf(stdout, "Imported %d of %d rows: %d new reviews, %d duplicates, %d failed\n", r.Valid, r.Rows, r.New, r.Duplicate, r.Failed)
+	}
+	if r.Stale > 0 {
+		fmt.Fprintf(stdout, "%d reviews are older than the recency cutoff and were left out; raise recency_cutoff to import them\n", r.Stale)
+	}
+	if r.Invalid > 0 {
+		fmt.Fprintf(stdout, "%d rows were skipped:\n", r.Invalid)
+	}
+	listed := 0
+	for _, p := range r.Problems {
+		switch {
+		case p.Line == 0:
+			fmt.Fprintf(stdout, "Error: %s\n", p.Reason)
+			continue
+		case p.ID != "":
+			fmt.Fprintf(stdout, "  line %d (review %s): %s\n", p.Line, p.ID, p.Reason)
+		default:
+			fmt.Fprintf(stdout, "  line %d: %s\n", p.Line, p.Reason)
+		}
+		listed++
+	}
+	if listed < r.Invalid {
+		fmt.Fprintf(stdout, "  and %d more\n", r.Invalid-listed)
+	}
+}
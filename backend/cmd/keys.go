@@ -0,0 +1,122 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/furqanmk/reviews-browser/internal/auth"
+	"github.com/furqanmk/reviews-browser/internal/database"
+	"github.com/furqanmk/reviews-browser/internal/model"
+)
+
+func keysCommands() []*command {
+	return []*command{
+		{name: "list", summary: "List API keys", run: runKeysList},
+		{name: "create", args: "<name>", summary: "Create an API key", run: runKeysCreate},
+		{name: "revoke", args: "<key-id>", summary: "Revoke an API key", run: runKeysRevoke},
+	}
+}
+
+func runKeysList(args []string) error {
+	fs, flags := newFlagSet("keys list", "", "List API keys. The keys themselves are not stored, so can't be shown.")
+	asJSON := fs.Bool("json", false, "print JSON instead of a table")
+	if err := parse(fs, args, 0, 0); err != nil {
+		return err
+	}
+	db, err := openDB(flags)
+	if err != nil {
+		return err
+	}
+
+	keys, err := db.GetAPIKeys(context.Background())
+	if err != nil {
+		return err
+	}
+	if *asJSON {
+		return json.NewEncoder(stdout).Encode(keys)
+	}
+
+	w := tabwriter.NewWriter(stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tNAME\tROLE\tAPPS\tCREATED\tLAST USED")
+	for _, key := range keys {
+		apps := "all"
+		if len(key.Apps) > 0 {
+			apps = strings.Join(key.Apps, ",")
+		}
+		lastUsed := "never"
+		if key.LastUsedAt != nil {
+			lastUsed = key.LastUsedAt.Format(time.RFC3339)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", key.ID, key.Name, key.Role, apps, key.CreatedAt.Format(time.RFC3339), lastUsed)
+	}
+	return w.Flush()
+}
+
+func runKeysCreate(args []string) error {
+	fs, flags := newFlagSet("keys create", "<name>", "Create an API key and print it. It is shown only this once.")
+	role := fs.String("role", model.RoleViewer, "`role` of the key: feed, viewer, triager or admin")
+	apps := fs.String("apps", "", "comma-separated `app-ids` the key is limited to; all apps if empty")
+	if err := parse(fs, args, 1, 1); err != nil {
+		return err
+	}
+	if !auth.ValidRole(*role) {
+		return usagef("keys create: -role must be feed, viewer, triager or admin")
+	}
+	var appIDs []string
+	if *apps != "" {
+		for _, app := range strings.Split(*apps, ",") {
+			appID, err := parseAppID(strings.TrimSpace(app))
+			if err != nil {
+				return err
+			}
+			if slices.Contains(appIDs, appID) {
+				return usagef("keys create: app %s is listed twice", appID)
+			}
+			appIDs = append(appIDs, appID)
+		}
+	}
+	if *role == model.RoleFeed && len(appIDs) != 1 {
+		return usagef("keys create: feed keys must be limited to one app with -apps")
+	}
+	db, err := openDB(flags)
+	if err != nil {
+		return err
+	}
+
+	id, secret, hash := auth.NewKey()
+	err = db.AddAPIKey(context.Background(), model.APIKey{
+		ID:        id,
+		Name:      fs.Arg(0),
+		Role:      *role,
+		Apps:      appIDs,
+		CreatedAt: time.Now().UTC(),
+		Hash:      hash,
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(stdout, "Created key %s. Store it now, it can't be shown again:\n%s\n", id, secret)
+	return nil
+}
+
+func runKeysRevoke(args []string) error {
+	fs, flags := newFlagSet("keys revoke", "<key-id>", "Revoke an API key. Requests using it are refused straight away.")
+	if err := parse(fs, args, 1, 1); err != nil {
+		return err
+	}
+	db, err := openDB(flags)
+	if err != nil {
+		return err
+	}
+
+	err = db.RemoveAPIKey(context.Background(), fs.Arg(0))
+	if err == database.ErrNotFound {
+		return fmt.Errorf("no key with ID %s", fs.Arg(0))
+	}
+	return err
+}
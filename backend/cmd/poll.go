@@ -0,0 +1,90 @@
+package cmd
+
+import (
+	"context"
+	"flag"
+	"fmt"
+
+	"github.com/furqanmk/reviews-browser/internal/model"
+	"github.com/furqanmk/reviews-browser/internal/polling"
+)
+
+func pollCommands() []*command {
+	return []*command{
+		{name: "once", args: "<app-id>", summary: "Fetch and store an app's recent reviews now", run: runPollOnce},
+		{name: "backfill", args: "<app-id>", summary: "Fetch and store every review the App Store still has for an app", run: runPollBackfill},
+	}
+}
+
+func cleanupCommands() []*command {
+	return []*command{
+		{name: "run", summary: "Purge reviews older than the recency cutoff now", run: runCleanup},
+	}
+}
+
+func runPollOnce(args []string) error {
+	fs, flags := newFlagSet("poll once", "<app-id>", "Fetch an app's recent reviews from the App Store and store them.")
+	return poll(fs, flags, args, (*polling.PollingScheduler).PollOnce)
+}
+
+func runPollBackfill(args []string) error {
+	fs, flags := newFlagSet("poll backfill", "<app-id>",
+		"Fetch every page of an app's App Store feed, whatever poll_max_pages, and store the reviews not stored yet.\n"+
+			"Reviews older than recency_cutoff are left out, as reads and cleanups only keep reviews within it, so raise it first to keep more history.")
+	return poll(fs, flags, args, (*polling.PollingScheduler).Backfill)
+}
+
+// poll runs one poll of the app named by args with pollApp and reports it.
+func poll(fs *flag.FlagSet, flags *configFlags, args []string,
+	pollApp func(*polling.PollingScheduler, context.Context, string) (model.PollRun, error)) error {
+	if err := parse(fs, args, 1, 1); err != nil {
+		return err
+	}
+	appID, err := parseAppID(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	cfg, err := flags.load()
+	if err != nil {
+		return err
+	}
+	svc, err := newServices(cfg)
+	if err != nil {
+		return err
+	}
+	defer svc.Close()
+
+	ctx, stop := signalContext()
+	defer stop()
+	scheduler := polling.NewPollingScheduler(svc.db, svc.client, svc.pipeline)
+	run, err := pollApp(scheduler, ctx, appID)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(stdout, "Fetched %d pages: %d new reviews, %d duplicates\n", run.Pages, run.New, run.Duplicate)
+	if run.Stale > 0 {
+		fmt.Fprintf(stdout, "%d reviews are older than the recency cutoff and were left out; raise recency_cutoff to keep them\n", run.Stale)
+	}
+	return nil
+}
+
+func runCleanup(args []string) error {
+	fs, flags := newFlagSet("cleanup run", "", "Purge reviews older than the recency cutoff.")
+	if err := parse(fs, args, 0, 0); err != nil {
+		return err
+	}
+	db, err := openDB(flags)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	ctx, stop := signalContext()
+	defer stop()
+	removed, err := db.CleanUpOldReviews(ctx)
+	if err != nil {
+		return fmt.Errorf("cleaning up old reviews: %w", err)
+	}
+	fmt.Fprintf(stdout, "Removed %d reviews\n", removed)
+	return nil
+}
@@ -0,0 +1,177 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/furqanmk/reviews-browser/internal/export"
+	"github.com/furqanmk/reviews-browser/internal/model"
+)
+
+func reviewsCommands() []*command {
+	return []*command{
+		{name: "list", args: "<app-id>", summary: "List an app's recent reviews", run: runReviewsList},
+		{name: "search", args: "<app-id> <text>", summary: "Find reviews mentioning some text", run: runReviewsSearch},
+		{name: "export", args: "[<app-id>...]", summary: "Export recent reviews as CSV, NDJSON or JSON", run: runReviewsExport},
+	}
+}
+
+func runReviewsList(args []string) error {
+	fs, flags := newFlagSet("reviews list", "<app-id>", "List an app's recent reviews, newest first.")
+	asJSON := fs.Bool("json", false, "print JSON instead of a table")
+	lang := fs.String("language", "", "only reviews in this language")
+	status := fs.String("status", "", "only reviews with this triage status")
+	limit := fs.Int("limit", 0, "print at most `n` reviews")
+	if err := parse(fs, args, 1, 1); err != nil {
+		return err
+	}
+	db, err := openDB(flags)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	reviews, err := db.GetRecentReviews(ctx, fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	triage, err := db.GetTriage(ctx, fs.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	var matched []model.Review
+	for _, review := range reviews {
+		if *lang != "" && review.Language != *lang {
+			continue
+		}
+		t, ok := triage[review.ID]
+		if !ok {
+			t = model.Triage{ReviewID: review.ID, AppID: review.AppID, Status: model.TriageNew}
+		}
+		if *status != "" && t.Status != *status {
+			continue
+		}
+		review.Triage = &t
+		matched = append(matched, review)
+	}
+	return printReviews(matched, *limit, *asJSON)
+}
+
+func runReviewsSearch(args []string) error {
+	fs, flags := newFlagSet("reviews search", "<app-id> <text>", "Find an app's recent reviews whose author, title or content contains the text, ignoring case.")
+	asJSON := fs.Bool("json", false, "print JSON instead of a table")
+	limit := fs.Int("limit", 0, "print at most `n` reviews")
+	if err := parse(fs, args, 2, 2); err != nil {
+		return err
+	}
+	db, err := openDB(flags)
+	if err != nil {
+		return err
+	}
+
+	reviews, err := db.GetRecentReviews(context.Background(), fs.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	query := strings.ToLower(fs.Arg(1))
+	var matched []model.Review
+	for _, review := range reviews {
+		text := strings.ToLower(review.Author + "\n" + review.Title + "\n" + review.Content)
+		if strings.Contains(text, query) {
+			matched = append(matched, review)
+		}
+	}
+	return printReviews(matched, *limit, *asJSON)
+}
+
+func runReviewsExport(args []string) error {
+	fs, flags := newFlagSet("reviews export", "[<app-id>...]",
+		"Export the recent reviews of the given apps, or of every app, streaming them as they are read.")
+	format := fs.String("format", export.FormatCSV, "output format: "+strings.Join(export.Formats, ", "))
+	columns := fs.String("columns", "", "comma-separated `columns` to export, in order (default all: "+strings.Join(export.Columns(true), ",")+")")
+	bom := fs.Bool("bom", false, "start CSV with a UTF-8 byte order mark")
+	lang := fs.String("language", "", "only reviews in this language")
+	status := fs.String("status", "", "only reviews with this triage status")
+	assignee := fs.String("assignee", "", "only reviews assigned to this person")
+	tag := fs.String("tag", "", "only reviews with this triage tag")
+	excludeSpam := fs.Bool("exclude-spam", false, "leave out suspected spam")
+	output := fs.String("output", "", "write to `file` instead of standard output")
+	if err := parse(fs, args, 0, -1); err != nil {
+		return err
+	}
+	appIDs := fs.Args()
+	for _, arg := range appIDs {
+		if _, err := parseAppID(arg); err != nil {
+			return err
+		}
+	}
+	// the CLI reads the store directly, so it may see the original text
+	cols, err := export.ParseColumns(*columns, true)
+	if err != nil {
+		return usagef("reviews export: %v", err)
+	}
+	opts := export.Options{Format: *format, Columns: cols, BOM: *bom}
+	if _, err := export.NewWriter(io.Discard, opts); err != nil {
+		return usagef("reviews export: %v", err)
+	}
+	db, err := openDB(flags)
+	if err != nil {
+		return err
+	}
+
+	w := stdout
+	if *output != "" {
+		file, err := os.Create(*output)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		w = file
+	}
+	buffered := bufio.NewWriter(w)
+	out, err := export.NewWriter(buffered, opts)
+	if err != nil {
+		return err
+	}
+
+	filter := export.Filter{Language: *lang, Status: *status, Assignee: *assignee, Tag: *tag, ExcludeSpam: *excludeSpam}
+	if err := export.Export(context.Background(), db, out, appIDs, filter); err != nil {
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	if err := buffered.Flush(); err != nil {
+		return err
+	}
+	if *output != "" {
+		fmt.Fprintf(stderr, "Exported %d reviews to %s\n", out.Count(), *output)
+	}
+	return nil
+}
+
+func printReviews(reviews []model.Review, limit int, asJSON bool) error {
+	if limit > 0 && len(reviews) > limit {
+		reviews = reviews[:limit]
+	}
+	if asJSON {
+		return json.NewEncoder(stdout).Encode(reviews)
+	}
+
+	w := tabwriter.NewWriter(stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tDATE\tRATING\tLANG\tAUTHOR\tTITLE")
+	for _, r := range reviews {
+		fmt.Fprintf(w, "%s\t%s\t%d\t%s\t%s\t%s\n",
+			r.ID, r.CreatedAt.Format(time.RFC3339), r.Rating, r.Language, truncate(r.Author, 20), truncate(r.Title, 40))
+	}
+	return w.Flush()
+}
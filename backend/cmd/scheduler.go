@@ -2,52 +2,49 @@ package cmd
 
 import (
 	"context"
-	"log"
+	"fmt"
 
 	"github.com/furqanmk/reviews-browser/config"
 	"github.com/furqanmk/reviews-browser/internal/cleanup"
-	"github.com/furqanmk/reviews-browser/internal/clients/appstore"
-	"github.com/furqanmk/reviews-browser/internal/database"
+	"github.com/furqanmk/reviews-browser/internal/metadata"
 	"github.com/furqanmk/reviews-browser/internal/polling"
 )
 
-func StartSchedulers() {
-	ctx := context.Background()
-
-	// load up environment variables
-	err := config.LoadEnv()
+// StartSchedulers runs the polling, cleanup and metadata schedulers until ctx is cancelled.
+func StartSchedulers(ctx context.Context, watcher *config.Watcher) error {
+	svc, err := watchServices(ctx, watcher)
 	if err != nil {
-		log.Fatalf("failed to load env: %v", err)
+		return err
 	}
+	defer svc.Close()
 
-	cfg, err := config.GetConfig()
-	if err != nil {
-		log.Fatalf("failed to get config: %v", err)
-	}
-
-	// Initialize database connection
-	db, err := database.NewDBConnection(cfg)
-	if err != nil {
-		log.Fatalf("failed to connect to database: %v", err)
-	}
-
-	// Initialize App Store client
-	appClient := appstore.NewClient(cfg)
+	// the API server serves /metrics itself; alone, the schedulers need a listener
+	go serveMetrics(ctx, svc.cfg.MetricsPort)
+	return schedule(ctx, svc)
+}
 
+func schedule(ctx context.Context, svc *services) error {
 	// Start the polling scheduler
-	polling := polling.NewPollingScheduler(db, appClient)
-	err = polling.Start(ctx)
+	polling := polling.NewPollingScheduler(svc.db, svc.client, svc.pipeline)
+	err := polling.Start(ctx)
 	if err != nil {
-		log.Fatalf("failed to start polling scheduler: %v", err)
+		return fmt.Errorf("failed to start polling scheduler: %w", err)
 	}
 
 	// Start the cleanup scheduler
-	cleanup := cleanup.NewCleanupScheduler(db, cfg)
+	cleanup := cleanup.NewCleanupScheduler(svc.db, svc.cfg)
 	cleanup.Start(ctx)
+	go svc.follow(ctx, cleanup.SetConfig)
+
+	// Start refreshing the apps' App Store details
+	refresher := metadata.NewRefresher(svc.db, svc.client, svc.cfg)
+	refresher.Start(ctx)
+	go svc.follow(ctx, refresher.SetConfig)
 
 	// wait for interrupt signal
 	<-ctx.Done()
 
 	// stop the scheduler on done
-	defer polling.Stop()
+	polling.Stop()
+	return nil
 }
@@ -0,0 +1,139 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/furqanmk/reviews-browser/config"
+	"github.com/furqanmk/reviews-browser/internal/clients/appstore"
+	"github.com/furqanmk/reviews-browser/internal/database"
+	"github.com/furqanmk/reviews-browser/internal/events"
+	"github.com/furqanmk/reviews-browser/internal/ingest"
+	"github.com/furqanmk/reviews-browser/internal/logging"
+	"github.com/furqanmk/reviews-browser/internal/redact"
+	"github.com/furqanmk/reviews-browser/internal/tracing"
+)
+
+var logger = logging.For("cmd")
+
+// services are the long-lived components the API and the schedulers are built
+// from. Running both in one process shares a single instance of each, so they
+// use one store, one App Store client and its rate limit, and one event bus.
+type services struct {
+	cfg      *config.Config
+	watcher  *config.Watcher
+	db       *database.DB
+	client   *appstore.Client
+	bus      *events.Bus
+	pipeline *ingest.Pipeline
+	// stopTracing flushes spans not yet exported.
+	stopTracing func(context.Context) error
+}
+
+func newServices(cfg *config.Config) (*services, error) {
+	configureLogging(cfg)
+
+	stopTracing, err := tracing.Setup(context.Background(), tracing.Options{
+		Exporter: cfg.TracingExporter,
+		File:     cfg.TracingFile,
+		Endpoint: cfg.TracingEndpoint,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Initialize database connection
+	db, err := database.NewDBConnection(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to database: %w", err)
+	}
+
+	bus := events.NewBus()
+
+	// Reviews are redacted and tagged with their language before being stored
+	pipeline, err := newPipeline(cfg, db, bus)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build ingestion pipeline: %w", err)
+	}
+
+	return &services{
+		cfg:         cfg,
+		db:          db,
+		client:      appstore.NewClient(cfg),
+		bus:         bus,
+		pipeline:    pipeline,
+		stopTracing: stopTracing,
+	}, nil
+}
+
+// watchServices builds the services from the watcher's configuration and keeps
+// them following its snapshots until ctx is cancelled.
+func watchServices(ctx context.Context, watcher *config.Watcher) (*services, error) {
+	svc, err := newServices(watcher.Current())
+	if err != nil {
+		return nil, err
+	}
+	svc.watcher = watcher
+
+	go watcher.Watch(ctx, configCheckInterval)
+	go svc.follow(ctx, svc.db.SetConfig, svc.client.SetConfig, configureLogging)
+	return svc, nil
+}
+
+// configCheckInterval is how often the config files are checked for changes.
+const configCheckInterval = 5 * time.Second
+
+// follow passes every new configuration snapshot to each apply function until
+// ctx is cancelled. Services built without a watcher never change.
+func (s *services) follow(ctx context.Context, apply ...func(*config.Config)) {
+	if s.watcher == nil {
+		return
+	}
+	snapshots, unsubscribe := s.watcher.Subscribe()
+	defer unsubscribe()
+	for {
+		select {
+		case cfg := <-snapshots:
+			for _, fn := range apply {
+				fn(cfg)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// configureLogging applies the configured log format and levels.
+func configureLogging(cfg *config.Config) {
+	logging.Configure(logging.Options{
+		Format: cfg.LogFormat,
+		Level:  cfg.LogLevel,
+		Levels: cfg.LogLevels,
+	})
+}
+
+func (s *services) Close() {
+	s.db.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := s.stopTracing(ctx); err != nil {
+		logger.Error("flushing traces failed", "error", err)
+	}
+}
+
+// newPipeline builds the ingestion pipeline shared by the API and the pollers:
+// reviews too old to be kept are dropped, PII is masked, then the language of
+// the masked text is detected.
+func newPipeline(cfg *config.Config, db *database.DB, bus *events.Bus) (*ingest.Pipeline, error) {
+	redactor, err := redact.New(cfg.RedactionRules)
+	if err != nil {
+		return nil, err
+	}
+	return ingest.NewPipeline(db, bus,
+		ingest.SkipOlderThan(db.RecencyCutoff),
+		ingest.Redact(redactor, cfg.KeepUnredacted),
+		ingest.DetectLanguage,
+	), nil
+}
@@ -1,9 +1,14 @@
 package config
 
 import (
+	"errors"
 	"fmt"
+	"io/fs"
+	"log/slog"
 	"os"
-	"strconv"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/joho/godotenv"
 )
@@ -11,41 +16,208 @@ import (
 // Config holds the application configuration.
 type Config struct {
 	AppStoreReviewsURL string
-	ServerPort         string
-	ReviewsCSV         string
-	AppsCSV            string
-	RecencyCutoffHrs   int
-	CleanupEveryHrs    int
+	// AppStoreLookupURL is the iTunes Lookup API, with %s for the app ID.
+	AppStoreLookupURL string
+	ServerPort        string
+	// MetricsPort is where the schedulers serve /metrics when run on their
+	// own; the API server serves it alongside the API.
+	MetricsPort string
+	ReviewsCSV  string
+	AppsCSV     string
+	TriageCSV   string
+	PollsCSV    string
+	KeysCSV     string
+	// VersionsCSV holds the data version of each app's reviews.
+	VersionsCSV string
+	// RecencyCutoff is how far back reviews are fetched and kept.
+	RecencyCutoff time.Duration
+	// CleanupEvery is how often reviews past the cutoff are purged.
+	CleanupEvery time.Duration
+	// MetadataRefreshEvery is how often each app's App Store details are
+	// looked up again.
+	MetadataRefreshEvery time.Duration
+	// AppStoreMinInterval is the minimum gap between App Store requests.
+	AppStoreMinInterval time.Duration
+	// PollMaxPages caps the feed pages fetched per poll; 0 means no cap.
+	PollMaxPages int
+	// PollHistoryLimit is how many poll runs are kept per app; 0 keeps all.
+	PollHistoryLimit int
+	LogLevel         slog.Level
+	// LogLevels overrides LogLevel by component, e.g. "polling".
+	LogLevels map[string]slog.Level
+	// LogFormat is "text" or "json".
+	LogFormat string
+	// TracingExporter is where spans are sent: "none", "stdout", "file" (to
+	// TracingFile) or "otlp" (to TracingEndpoint).
+	TracingExporter string
+	TracingFile     string
+	TracingEndpoint string
+	// RedactionRules names the PII rules applied to reviews before storage.
+	RedactionRules []string
+	// KeepUnredacted stores the original text of redacted reviews, readable
+	// only with an admin API key.
+	KeepUnredacted bool
+	// RequireAPIKeys turns away API requests without a valid API key. Without
+	// it, requests without a key may only use viewer routes.
+	RequireAPIKeys bool
+	// RateLimitReads and RateLimitFetches are how many API requests each
+	// client may make per minute, of cheap reads and of those that fetch from
+	// the App Store; 0 means no limit.
+	RateLimitReads   int
+	RateLimitFetches int
+	// AdminAPIKey is an admin key for every app that isn't stored with the
+	// others, for bootstrapping key management.
+	AdminAPIKey string
+
+	// sources records where each setting's value came from, by key.
+	sources map[string]string
+}
+
+// Options controls where Load reads configuration from.
+type Options struct {
+	// File is a YAML or TOML config file. If empty, CONFIG_FILE is used, and
+	// if that is unset no file is read.
+	File string
+	// EnvFile is a dotenv file loaded into the environment first. Variables
+	// already set in the environment win. If empty, .env is loaded when it
+	// exists.
+	EnvFile string
+	// Overrides are raw values by setting key, applied last. The CLI fills
+	// these from flags.
+	Overrides map[string]string
+	// OverrideSource describes where overrides came from, e.g. "flag".
+	OverrideSource string
 }
 
-// LoadEnv loads env vars from .env file
-func LoadEnv() error {
-	if err := godotenv.Load(); err != nil {
-		return fmt.Errorf("error loading .env file")
+// Load builds the configuration in layers: defaults, then the config file,
+// then environment variables, then overrides. Every problem found, whether a
+// value that doesn't parse or a setting that fails validation, is reported in
+// one aggregated error.
+func Load(opts Options) (*Config, error) {
+	if err := loadEnvFile(opts.EnvFile); err != nil {
+		return nil, err
 	}
-	return nil
+
+	cfg := &Config{sources: make(map[string]string)}
+	var errs []error
+
+	// defaults
+	for _, f := range fields {
+		if err := f.set(cfg, f.def); err != nil {
+			panic(fmt.Sprintf("config: bad default for %s: %v", f.key, err))
+		}
+		cfg.sources[f.key] = "default"
+	}
+
+	// config file
+	file := opts.File
+	if file == "" {
+		file = os.Getenv("CONFIG_FILE")
+	}
+	if file != "" {
+		values, err := readFile(file)
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range fields {
+			if raw, ok := values[f.key]; ok {
+				errs = append(errs, apply(cfg, f, raw, "file "+file))
+			}
+		}
+		for key := range values {
+			if lookup(key) == nil {
+				errs = append(errs, fmt.Errorf("%s: unknown setting in %s", key, file))
+			}
+		}
+	}
+
+	// environment
+	for _, f := range fields {
+		if f.legacyEnv != "" {
+			if raw, ok := os.LookupEnv(f.legacyEnv); ok && raw != "" {
+				errs = append(errs, apply(cfg, f, raw+f.legacyUnit, "env "+f.legacyEnv))
+			}
+		}
+		if raw, ok := os.LookupEnv(f.env); ok && (raw != "" || f.allowEmpty) {
+			errs = append(errs, apply(cfg, f, raw, "env "+f.env))
+		}
+	}
+
+	// overrides
+	source := opts.OverrideSource
+	if source == "" {
+		source = "override"
+	}
+	for key, raw := range opts.Overrides {
+		f := lookup(key)
+		if f == nil {
+			errs = append(errs, fmt.Errorf("%s: unknown setting", key))
+			continue
+		}
+		errs = append(errs, apply(cfg, *f, raw, source+" -"+f.flag))
+	}
+
+	// settings that failed to parse keep their previous value, so validating
+	// anyway only adds problems that are real
+	errs = append(errs, cfg.Validate())
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	return cfg, nil
 }
 
-// GetConfig returns the configuration loaded from environment variables.
-func GetConfig() (*Config, error) {
-	return &Config{
-		AppStoreReviewsURL: os.Getenv("APPSTORE_REVIEW_URL"),
-		ServerPort:         os.Getenv("SERVER_PORT"),
-		ReviewsCSV:         os.Getenv("REVIEWS_CSV_PATH"),
-		AppsCSV:            os.Getenv("APPS_CSV_PATH"),
-		RecencyCutoffHrs:   getEnvAsInt("RECENCY_CUTOFF_HRS", 48),
-		CleanupEveryHrs:    getEnvAsInt("CLEANUP_EVERY_HRS", 1),
-	}, nil
+// Source reports where the value of a setting came from: "default", a config
+// file, an environment variable or a flag.
+func (c *Config) Source(key string) string {
+	if source, ok := c.sources[key]; ok {
+		return source
+	}
+	return "unknown"
 }
 
-func getEnvAsInt(key string, defaultValue int) int {
-	value := os.Getenv(key)
-	if value == "" {
-		return defaultValue
+// envFileKeys records the variables set from the env file, so that reloading
+// it can change them while variables from the real environment still win.
+var envFileKeys = struct {
+	sync.Mutex
+	keys map[string]bool
+}{keys: make(map[string]bool)}
+
+// loadEnvFile loads a dotenv file into the environment. The default .env is
+// optional; a file named explicitly must exist. Variables already set by the
+// environment are left alone, but ones set by an earlier load of the file are
+// updated, or unset if the file no longer has them.
+func loadEnvFile(path string) error {
+	explicit := path != ""
+	if !explicit {
+		path = ".env"
+	}
+	values, err := godotenv.Read(path)
+	if err != nil && (explicit || !errors.Is(err, fs.ErrNotExist)) {
+		return fmt.Errorf("error loading env file: %w", err)
 	}
-	intValue, err := strconv.Atoi(value)
-	if err != nil {
-		return defaultValue
+
+	envFileKeys.Lock()
+	defer envFileKeys.Unlock()
+	for key := range envFileKeys.keys {
+		if _, ok := values[key]; !ok {
+			os.Unsetenv(key)
+			delete(envFileKeys.keys, key)
+		}
 	}
-	return intValue
+	for key, value := range values {
+		if _, set := os.LookupEnv(key); set && !envFileKeys.keys[key] {
+			continue
+		}
+		os.Setenv(key, value)
+		envFileKeys.keys[key] = true
+	}
+	return nil
+}
+
+func apply(cfg *Config, f field, raw, source string) error {
+	if err := f.set(cfg, strings.TrimSpace(raw)); err != nil {
+		return fmt.Errorf("%s (%s): %w", f.key, source, err)
+	}
+	cfg.sources[f.key] = source
+	return nil
 }
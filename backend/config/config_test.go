@@ -0,0 +1,114 @@
+package config_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/furqanmk/reviews-browser/config"
+	"github.com/stretchr/testify/require"
+)
+
+func writeFile(t *testing.T, name, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	return path
+}
+
+// envFile writes a dotenv file, and unsets what it set once the test ends.
+func envFile(t *testing.T, content string) string {
+	path := writeFile(t, ".env", content)
+	t.Cleanup(func() {
+		config.Load(config.Options{EnvFile: writeFile(t, "empty.env", "")})
+	})
+	return path
+}
+
+func TestLoad_Defaults(t *testing.T) {
+	cfg, err := config.Load(config.Options{EnvFile: envFile(t, "")})
+	require.NoError(t, err)
+	require.Equal(t, "8080", cfg.ServerPort)
+	require.Equal(t, 48*time.Hour, cfg.RecencyCutoff)
+	require.Equal(t, 500*time.Millisecond, cfg.AppStoreMinInterval)
+	require.Equal(t, []string{"email", "card_number", "order_id", "phone"}, cfg.RedactionRules)
+	require.Equal(t, "default", cfg.Source("server_port"))
+}
+
+func TestLoad_Precedence(t *testing.T) {
+	file := writeFile(t, "config.yaml", "server_port: 9000\nrecency_cutoff: 24h\ncleanup_every: 2h\nredaction_rules: [email, phone]\n")
+	t.Setenv("RECENCY_CUTOFF", "12h")
+	t.Setenv("CLEANUP_EVERY", "3h")
+
+	cfg, err := config.Load(config.Options{
+		File:           file,
+		EnvFile:        envFile(t, ""),
+		Overrides:      map[string]string{"cleanup_every": "4h"},
+		OverrideSource: "flag",
+	})
+	require.NoError(t, err)
+
+	require.Equal(t, "9000", cfg.ServerPort)
+	require.Equal(t, "file "+file, cfg.Source("server_port"))
+	require.Equal(t, []string{"email", "phone"}, cfg.RedactionRules)
+	require.Equal(t, 12*time.Hour, cfg.RecencyCutoff)
+	require.Equal(t, "env RECENCY_CUTOFF", cfg.Source("recency_cutoff"))
+	require.Equal(t, 4*time.Hour, cfg.CleanupEvery)
+	require.Equal(t, "flag -cleanup-every", cfg.Source("cleanup_every"))
+}
+
+func TestLoad_TOML(t *testing.T) {
+	file := writeFile(t, "config.toml", "server_port = 9001\nappstore_min_interval = \"1s\"\n")
+	cfg, err := config.Load(config.Options{File: file, EnvFile: envFile(t, "")})
+	require.NoError(t, err)
+	require.Equal(t, "9001", cfg.ServerPort)
+	require.Equal(t, time.Second, cfg.AppStoreMinInterval)
+}
+
+func TestLoad_LegacyEnv(t *testing.T) {
+	env := envFile(t, "RECENCY_CUTOFF_HRS=72\nAPPSTORE_MIN_INTERVAL_MS=250\nCLEANUP_EVERY_HRS=2\nCLEANUP_EVERY=30m\n")
+	cfg, err := config.Load(config.Options{EnvFile: env})
+	require.NoError(t, err)
+	require.Equal(t, 72*time.Hour, cfg.RecencyCutoff)
+	require.Equal(t, 250*time.Millisecond, cfg.AppStoreMinInterval)
+	// the new name wins over the legacy one
+	require.Equal(t, 30*time.Minute, cfg.CleanupEvery)
+}
+
+func TestLoad_EnvFile(t *testing.T) {
+	_, err := config.Load(config.Options{EnvFile: filepath.Join(t.TempDir(), "missing.env")})
+	require.Error(t, err)
+}
+
+func TestLoad_ReportsEveryProblem(t *testing.T) {
+	t.Setenv("SERVER_PORT", "70000")
+	t.Setenv("CLEANUP_EVERY", "soon")
+	_, err := config.Load(config.Options{
+		EnvFile: envFile(t, ""),
+		File:    writeFile(t, "config.yaml", "colour: blue\n"),
+	})
+	require.Error(t, err)
+	require.ErrorContains(t, err, "cleanup_every (env CLEANUP_EVERY)")
+	require.ErrorContains(t, err, "colour: unknown setting")
+
+	_, err = config.Load(config.Options{
+		EnvFile: envFile(t, ""),
+		Overrides: map[string]string{
+			"appstore_review_url": "https://example.com/%d/%s",
+			"recency_cutoff":      "0s",
+		},
+	})
+	require.Error(t, err)
+	require.ErrorContains(t, err, "appstore_review_url")
+	require.ErrorContains(t, err, "server_port")
+	require.ErrorContains(t, err, "recency_cutoff")
+}
+
+func TestSettings_MasksSecrets(t *testing.T) {
+	t.Setenv("ADMIN_API_KEY", "hunter2")
+	cfg, err := config.Load(config.Options{EnvFile: envFile(t, "")})
+	require.NoError(t, err)
+	for _, s := range cfg.Settings() {
+		require.NotContains(t, s.Value, "hunter2")
+	}
+}
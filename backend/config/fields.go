@@ -0,0 +1,393 @@
+package config
+
+import (
+	"fmt"
+	"log/slog"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// field describes one setting: its key in config files, the environment
+// variable and flag that set it, and how to read and write it on a Config.
+type field struct {
+	key   string
+	env   string
+	flag  string
+	usage string
+	def   string
+	// legacyEnv is an older variable still honoured, whose bare number is in
+	// legacyUnit (e.g. RECENCY_CUTOFF_HRS=48 means "48h").
+	legacyEnv  string
+	legacyUnit string
+	// allowEmpty lets an empty environment variable clear the setting.
+	allowEmpty bool
+	secret     bool
+	// restart marks settings read once at startup, which a reload leaves
+	// unchanged.
+	restart bool
+	get     func(c *Config) string
+	set     func(c *Config, raw string) error
+}
+
+var fields = []field{
+	{
+		key:   "appstore_review_url",
+		env:   "APPSTORE_REVIEW_URL",
+		flag:  "appstore-url",
+		usage: "App Store reviews feed URL, with %s for the app ID and %d for the page",
+		def:   "https://itunes.apple.com/us/rss/customerreviews/id=%s/sortBy=mostRecent/page=%d/json",
+		get:   func(c *Config) string { return c.AppStoreReviewsURL },
+		set:   setString(func(c *Config) *string { return &c.AppStoreReviewsURL }),
+	},
+	{
+		key:   "appstore_lookup_url",
+		env:   "APPSTORE_LOOKUP_URL",
+		flag:  "appstore-lookup-url",
+		usage: "iTunes Lookup API URL, with %s for the app ID",
+		def:   "https://itunes.apple.com/lookup?id=%s&country=us",
+		get:   func(c *Config) string { return c.AppStoreLookupURL },
+		set:   setString(func(c *Config) *string { return &c.AppStoreLookupURL }),
+	},
+	{
+		key:     "server_port",
+		restart: true,
+		env:     "SERVER_PORT",
+		flag:    "port",
+		usage:   "HTTP server port",
+		def:     "8080",
+		get:     func(c *Config) string { return c.ServerPort },
+		set:     setString(func(c *Config) *string { return &c.ServerPort }),
+	},
+	{
+		key:        "metrics_port",
+		env:        "METRICS_PORT",
+		flag:       "metrics-port",
+		usage:      "port the schedulers serve /metrics on, or empty for none",
+		def:        "9091",
+		allowEmpty: true,
+		restart:    true,
+		get:        func(c *Config) string { return c.MetricsPort },
+		set:        setString(func(c *Config) *string { return &c.MetricsPort }),
+	},
+	{
+		key:     "reviews_csv",
+		restart: true,
+		env:     "REVIEWS_CSV_PATH",
+		flag:    "reviews-csv",
+		usage:   "path of the reviews table",
+		def:     "./data/reviews.csv",
+		get:     func(c *Config) string { return c.ReviewsCSV },
+		set:     setString(func(c *Config) *string { return &c.ReviewsCSV }),
+	},
+	{
+		key:     "apps_csv",
+		restart: true,
+		env:     "APPS_CSV_PATH",
+		flag:    "apps-csv",
+		usage:   "path of the apps table",
+		def:     "./data/apps.csv",
+		get:     func(c *Config) string { return c.AppsCSV },
+		set:     setString(func(c *Config) *string { return &c.AppsCSV }),
+	},
+	{
+		key:     "triage_csv",
+		restart: true,
+		env:     "TRIAGE_CSV_PATH",
+		flag:    "triage-csv",
+		usage:   "path of the triage table",
+		def:     "./data/triage.csv",
+		get:     func(c *Config) string { return c.TriageCSV },
+		set:     setString(func(c *Config) *string { return &c.TriageCSV }),
+	},
+	{
+		key:     "polls_csv",
+		restart: true,
+		env:     "POLLS_CSV_PATH",
+		flag:    "polls-csv",
+		usage:   "path of the poll history table",
+		def:     "./data/polls.csv",
+		get:     func(c *Config) string { return c.PollsCSV },
+		set:     setString(func(c *Config) *string { return &c.PollsCSV }),
+	},
+	{
+		key:     "keys_csv",
+		restart: true,
+		env:     "KEYS_CSV_PATH",
+		flag:    "keys-csv",
+		usage:   "path of the API keys table",
+		def:     "./data/keys.csv",
+		get:     func(c *Config) string { return c.KeysCSV },
+		set:     setString(func(c *Config) *string { return &c.KeysCSV }),
+	},
+	{
+		key:     "versions_csv",
+		restart: true,
+		env:     "VERSIONS_CSV_PATH",
+		flag:    "versions-csv",
+		usage:   "path of the table of per-app data versions",
+		def:     "./data/versions.csv",
+		get:     func(c *Config) string { return c.VersionsCSV },
+		set:     setString(func(c *Config) *string { return &c.VersionsCSV }),
+	},
+	{
+		key:        "recency_cutoff",
+		env:        "RECENCY_CUTOFF",
+		flag:       "recency-cutoff",
+		usage:      "how far back reviews are fetched and kept",
+		def:        "48h",
+		legacyEnv:  "RECENCY_CUTOFF_HRS",
+		legacyUnit: "h",
+		get:        func(c *Config) string { return c.RecencyCutoff.String() },
+		set:        setDuration(func(c *Config) *time.Duration { return &c.RecencyCutoff }),
+	},
+	{
+		key:        "cleanup_every",
+		env:        "CLEANUP_EVERY",
+		flag:       "cleanup-every",
+		usage:      "how often reviews past the cutoff are purged",
+		def:        "1h",
+		legacyEnv:  "CLEANUP_EVERY_HRS",
+		legacyUnit: "h",
+		get:        func(c *Config) string { return c.CleanupEvery.String() },
+		set:        setDuration(func(c *Config) *time.Duration { return &c.CleanupEvery }),
+	},
+	{
+		key:   "metadata_refresh_every",
+		env:   "METADATA_REFRESH_EVERY",
+		flag:  "metadata-refresh-every",
+		usage: "how often each app's name, icon and other App Store details are looked up again",
+		def:   "24h",
+		get:   func(c *Config) string { return c.MetadataRefreshEvery.String() },
+		set:   setDuration(func(c *Config) *time.Duration { return &c.MetadataRefreshEvery }),
+	},
+	{
+		key:        "appstore_min_interval",
+		env:        "APPSTORE_MIN_INTERVAL",
+		flag:       "appstore-min-interval",
+		usage:      "minimum gap between App Store requests",
+		def:        "500ms",
+		legacyEnv:  "APPSTORE_MIN_INTERVAL_MS",
+		legacyUnit: "ms",
+		get:        func(c *Config) string { return c.AppStoreMinInterval.String() },
+		set:        setDuration(func(c *Config) *time.Duration { return &c.AppStoreMinInterval }),
+	},
+	{
+		key:   "poll_max_pages",
+		env:   "POLL_MAX_PAGES",
+		flag:  "poll-max-pages",
+		usage: "most feed pages fetched per poll, 0 for no limit",
+		def:   "10",
+		get:   func(c *Config) string { return strconv.Itoa(c.PollMaxPages) },
+		set:   setInt(func(c *Config) *int { return &c.PollMaxPages }),
+	},
+	{
+		key:   "poll_history_limit",
+		env:   "POLL_HISTORY_LIMIT",
+		flag:  "poll-history-limit",
+		usage: "poll runs kept per app, 0 to keep all",
+		def:   "100",
+		get:   func(c *Config) string { return strconv.Itoa(c.PollHistoryLimit) },
+		set:   setInt(func(c *Config) *int { return &c.PollHistoryLimit }),
+	},
+	{
+		key:   "log_level",
+		env:   "LOG_LEVEL",
+		flag:  "log-level",
+		usage: "least severe level logged: debug, info, warn or error",
+		def:   "info",
+		get:   func(c *Config) string { return strings.ToLower(c.LogLevel.String()) },
+		set: func(c *Config, raw string) error {
+			if err := c.LogLevel.UnmarshalText([]byte(raw)); err != nil {
+				return fmt.Errorf("%q is not a log level", raw)
+			}
+			return nil
+		},
+	},
+	{
+		key:   "log_levels",
+		env:   "LOG_LEVELS",
+		flag:  "log-levels",
+		usage: "per-component levels overriding log_level, e.g. polling=debug,appstore=warn",
+		def:   "",
+		get: func(c *Config) string {
+			pairs := make([]string, 0, len(c.LogLevels))
+			for component, level := range c.LogLevels {
+				pairs = append(pairs, component+"="+strings.ToLower(level.String()))
+			}
+			sort.Strings(pairs)
+			return strings.Join(pairs, ",")
+		},
+		set: func(c *Config, raw string) error {
+			c.LogLevels = make(map[string]slog.Level)
+			for _, pair := range strings.Split(raw, ",") {
+				if pair = strings.TrimSpace(pair); pair == "" {
+					continue
+				}
+				component, name, ok := strings.Cut(pair, "=")
+				var level slog.Level
+				if !ok || component == "" || level.UnmarshalText([]byte(name)) != nil {
+					return fmt.Errorf("%q is not a component=level pair", pair)
+				}
+				c.LogLevels[strings.TrimSpace(component)] = level
+			}
+			return nil
+		},
+	},
+	{
+		key:   "log_format",
+		env:   "LOG_FORMAT",
+		flag:  "log-format",
+		usage: "log output format: text or json",
+		def:   "text",
+		get:   func(c *Config) string { return c.LogFormat },
+		set:   setString(func(c *Config) *string { return &c.LogFormat }),
+	},
+	{
+		key:     "tracing_exporter",
+		env:     "TRACING_EXPORTER",
+		flag:    "tracing-exporter",
+		usage:   "where traces are sent: none, stdout, file or otlp",
+		def:     "none",
+		restart: true,
+		get:     func(c *Config) string { return c.TracingExporter },
+		set:     setString(func(c *Config) *string { return &c.TracingExporter }),
+	},
+	{
+		key:     "tracing_file",
+		env:     "TRACING_FILE",
+		flag:    "tracing-file",
+		usage:   "file the file exporter appends spans to",
+		def:     "./traces.json",
+		restart: true,
+		get:     func(c *Config) string { return c.TracingFile },
+		set:     setString(func(c *Config) *string { return &c.TracingFile }),
+	},
+	{
+		key:        "tracing_endpoint",
+		env:        "TRACING_ENDPOINT",
+		flag:       "tracing-endpoint",
+		usage:      "OTLP/HTTP collector address for the otlp exporter, e.g. localhost:4318",
+		def:        "",
+		allowEmpty: true,
+		restart:    true,
+		get:        func(c *Config) string { return c.TracingEndpoint },
+		set:        setString(func(c *Config) *string { return &c.TracingEndpoint }),
+	},
+	{
+		key:        "redaction_rules",
+		env:        "REDACTION_RULES",
+		flag:       "redaction-rules",
+		usage:      "comma-separated PII rules applied before storage, or none",
+		def:        "email,card_number,order_id,phone",
+		allowEmpty: true,
+		restart:    true,
+		get:        func(c *Config) string { return strings.Join(c.RedactionRules, ",") },
+		set: func(c *Config, raw string) error {
+			c.RedactionRules = nil
+			for _, item := range strings.Split(raw, ",") {
+				if item = strings.TrimSpace(item); item != "" && item != "none" {
+					c.RedactionRules = append(c.RedactionRules, item)
+				}
+			}
+			return nil
+		},
+	},
+	{
+		key:     "redaction_keep_original",
+		restart: true,
+		env:     "REDACTION_KEEP_ORIGINAL",
+		flag:    "redaction-keep-original",
+		usage:   "keep the unredacted text, readable only with the admin API key",
+		def:     "false",
+		get:     func(c *Config) string { return strconv.FormatBool(c.KeepUnredacted) },
+		set:     setBool(func(c *Config) *bool { return &c.KeepUnredacted }),
+	},
+	{
+		key:   "require_api_keys",
+		env:   "REQUIRE_API_KEYS",
+		flag:  "require-api-keys",
+		usage: "turn away all API requests without a valid API key, not only triager and admin ones",
+		def:   "true",
+		get:   func(c *Config) string { return strconv.FormatBool(c.RequireAPIKeys) },
+		set:   setBool(func(c *Config) *bool { return &c.RequireAPIKeys }),
+	},
+	{
+		key:   "rate_limit_reads",
+		env:   "RATE_LIMIT_READS",
+		flag:  "rate-limit-reads",
+		usage: "API read requests allowed per client per minute, 0 for no limit",
+		def:   "300",
+		get:   func(c *Config) string { return strconv.Itoa(c.RateLimitReads) },
+		set:   setInt(func(c *Config) *int { return &c.RateLimitReads }),
+	},
+	{
+		key:   "rate_limit_fetches",
+		env:   "RATE_LIMIT_FETCHES",
+		flag:  "rate-limit-fetches",
+		usage: "API requests that fetch from the App Store allowed per client per minute, 0 for no limit",
+		def:   "6",
+		get:   func(c *Config) string { return strconv.Itoa(c.RateLimitFetches) },
+		set:   setInt(func(c *Config) *int { return &c.RateLimitFetches }),
+	},
+	{
+		key:    "admin_api_key",
+		env:    "ADMIN_API_KEY",
+		flag:   "admin-api-key",
+		usage:  "admin API key for every app, kept out of the keys table",
+		def:    "",
+		secret: true,
+		get:    func(c *Config) string { return c.AdminAPIKey },
+		set:    setString(func(c *Config) *string { return &c.AdminAPIKey }),
+	},
+}
+
+func lookup(key string) *field {
+	for i := range fields {
+		if fields[i].key == key {
+			return &fields[i]
+		}
+	}
+	return nil
+}
+
+func setString(ptr func(c *Config) *string) func(*Config, string) error {
+	return func(c *Config, raw string) error {
+		*ptr(c) = raw
+		return nil
+	}
+}
+
+func setInt(ptr func(c *Config) *int) func(*Config, string) error {
+	return func(c *Config, raw string) error {
+		v, err := strconv.Atoi(raw)
+		if err != nil {
+			return fmt.Errorf("%q is not a whole number", raw)
+		}
+		*ptr(c) = v
+		return nil
+	}
+}
+
+func setBool(ptr func(c *Config) *bool) func(*Config, string) error {
+	return func(c *Config, raw string) error {
+		v, err := strconv.ParseBool(raw)
+		if err != nil {
+			return fmt.Errorf("%q is not a boolean", raw)
+		}
+		*ptr(c) = v
+		return nil
+	}
+}
+
+func setDuration(ptr func(c *Config) *time.Duration) func(*Config, string) error {
+	return func(c *Config, raw string) error {
+		v, err := time.ParseDuration(raw)
+		if err != nil {
+			return fmt.Errorf("%q is not a duration like 90s, 15m or 48h", raw)
+		}
+		*ptr(c) = v
+		return nil
+	}
+}
@@ -0,0 +1,50 @@
+package config
+
+import (
+	"flag"
+	"fmt"
+)
+
+// Setting is the effective value of one setting and where it came from.
+type Setting struct {
+	Key    string `json:"key"`
+	Value  string `json:"value"`
+	Source string `json:"source"`
+}
+
+// Settings lists every setting's effective value and source. Secrets are
+// masked.
+func (c *Config) Settings() []Setting {
+	settings := make([]Setting, 0, len(fields))
+	for _, f := range fields {
+		value := f.get(c)
+		if f.secret && value != "" {
+			value = "********"
+		}
+		settings = append(settings, Setting{Key: f.key, Value: value, Source: c.Source(f.key)})
+	}
+	return settings
+}
+
+// RegisterFlags adds a flag for every setting to fs. After fs is parsed, the
+// returned function gives the values of the flags that were set, keyed by
+// setting, ready for Options.Overrides.
+func RegisterFlags(fs *flag.FlagSet) func() map[string]string {
+	values := make(map[string]*string, len(fields))
+	for _, f := range fields {
+		usage := fmt.Sprintf("%s (%s)", f.usage, f.env)
+		values[f.flag] = fs.String(f.flag, "", usage)
+	}
+
+	return func() map[string]string {
+		overrides := make(map[string]string)
+		fs.Visit(func(fl *flag.Flag) {
+			for _, f := range fields {
+				if f.flag == fl.Name {
+					overrides[f.key] = *values[f.flag]
+				}
+			}
+		})
+		return overrides
+	}
+}
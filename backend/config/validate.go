@@ -0,0 +1,116 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Validate checks every setting and reports all problems at once.
+func (c *Config) Validate() error {
+	var errs []error
+	fail := func(key, format string, args ...any) {
+		errs = append(errs, fmt.Errorf("%s (%s): %s", key, c.Source(key), fmt.Sprintf(format, args...)))
+	}
+
+	if verbs := formatVerbs(c.AppStoreReviewsURL); len(verbs) != 2 || verbs[0] != 's' || verbs[1] != 'd' {
+		fail("appstore_review_url", "must contain exactly two format verbs, %%s for the app ID then %%d for the page")
+	} else if u, err := url.Parse(fmt.Sprintf(c.AppStoreReviewsURL, "1", 1)); err != nil || u.Scheme == "" || u.Host == "" {
+		fail("appstore_review_url", "must be an absolute URL")
+	}
+	if verbs := formatVerbs(c.AppStoreLookupURL); len(verbs) != 1 || verbs[0] != 's' {
+		fail("appstore_lookup_url", "must contain exactly one format verb, %%s for the app ID")
+	} else if u, err := url.Parse(fmt.Sprintf(c.AppStoreLookupURL, "1")); err != nil || u.Scheme == "" || u.Host == "" {
+		fail("appstore_lookup_url", "must be an absolute URL")
+	}
+
+	if !validPort(c.ServerPort) {
+		fail("server_port", "must be a port number between 1 and 65535, got %q", c.ServerPort)
+	}
+	if c.MetricsPort != "" && !validPort(c.MetricsPort) {
+		fail("metrics_port", "must be a port number between 1 and 65535 or empty, got %q", c.MetricsPort)
+	}
+
+	for key, path := range map[string]string{
+		"reviews_csv":  c.ReviewsCSV,
+		"apps_csv":     c.AppsCSV,
+		"triage_csv":   c.TriageCSV,
+		"polls_csv":    c.PollsCSV,
+		"keys_csv":     c.KeysCSV,
+		"versions_csv": c.VersionsCSV,
+	} {
+		if path == "" {
+			fail(key, "must not be empty")
+		}
+	}
+
+	if c.RecencyCutoff <= 0 {
+		fail("recency_cutoff", "must be positive")
+	}
+	if c.CleanupEvery <= 0 {
+		fail("cleanup_every", "must be positive")
+	}
+	if c.MetadataRefreshEvery <= 0 {
+		fail("metadata_refresh_every", "must be positive")
+	}
+	if c.AppStoreMinInterval < 0 {
+		fail("appstore_min_interval", "must not be negative")
+	}
+	if c.LogFormat != "text" && c.LogFormat != "json" {
+		fail("log_format", "must be text or json, got %q", c.LogFormat)
+	}
+	switch c.TracingExporter {
+	case "none", "stdout", "otlp":
+	case "file":
+		if c.TracingFile == "" {
+			fail("tracing_file", "must be set for the file exporter")
+		}
+	default:
+		fail("tracing_exporter", "must be none, stdout, file or otlp, got %q", c.TracingExporter)
+	}
+	if c.PollMaxPages < 0 {
+		fail("poll_max_pages", "must not be negative")
+	}
+	if c.PollHistoryLimit < 0 {
+		fail("poll_history_limit", "must not be negative")
+	}
+	if c.RateLimitReads < 0 {
+		fail("rate_limit_reads", "must not be negative")
+	}
+	if c.RateLimitFetches < 0 {
+		fail("rate_limit_fetches", "must not be negative")
+	}
+
+	return errors.Join(errs...)
+}
+
+func validPort(s string) bool {
+	port, err := strconv.Atoi(s)
+	return err == nil && port >= 1 && port <= 65535
+}
+
+// formatVerbs returns the verb letter of each fmt directive in s, skipping %%.
+func formatVerbs(s string) []byte {
+	var verbs []byte
+	for i := 0; i < len(s); i++ {
+		if s[i] != '%' {
+			continue
+		}
+		// skip flags, width and precision
+		j := i + 1
+		for j < len(s) && strings.IndexByte("+-# 0123456789.", s[j]) >= 0 {
+			j++
+		}
+		if j >= len(s) {
+			verbs = append(verbs, '!')
+			break
+		}
+		if s[j] != '%' {
+			verbs = append(verbs, s[j])
+		}
+		i = j
+	}
+	return verbs
+}
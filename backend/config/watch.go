@@ -0,0 +1,170 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/furqanmk/reviews-browser/internal/logging"
+)
+
+var logger = logging.For("config")
+
+// Watcher holds the current configuration and reloads it on SIGHUP or when the
+// config or env file changes. Each reload that validates is published to
+// subscribers as a new snapshot; a reload that fails keeps the old one.
+//
+// Settings marked restart-only, such as the port and table paths, keep their
+// startup values in every snapshot.
+type Watcher struct {
+	opts    Options
+	current atomic.Pointer[Config]
+
+	mu   sync.Mutex
+	subs map[chan *Config]struct{}
+}
+
+// NewWatcher creates a watcher starting from cfg, which Reload rebuilds with
+// the same options cfg was loaded with.
+func NewWatcher(cfg *Config, opts Options) *Watcher {
+	w := &Watcher{opts: opts, subs: make(map[chan *Config]struct{})}
+	w.current.Store(cfg)
+	return w
+}
+
+// Current returns the latest snapshot. Snapshots are never modified, so callers
+// may keep and read them freely.
+func (w *Watcher) Current() *Config {
+	return w.current.Load()
+}
+
+// Subscribe returns a channel receiving every new snapshot and a function that
+// ends the subscription. A slow subscriber only misses intermediate snapshots,
+// never the latest.
+func (w *Watcher) Subscribe() (<-chan *Config, func()) {
+	ch := make(chan *Config, 1)
+	w.mu.Lock()
+	w.subs[ch] = struct{}{}
+	w.mu.Unlock()
+
+	return ch, func() {
+		w.mu.Lock()
+		delete(w.subs, ch)
+		w.mu.Unlock()
+	}
+}
+
+// Reload loads the configuration again and publishes it if it is valid,
+// returning the keys whose values changed.
+func (w *Watcher) Reload() ([]string, error) {
+	next, err := Load(w.opts)
+	if err != nil {
+		return nil, err
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	current := w.current.Load()
+	var changed []string
+	for _, f := range fields {
+		if f.get(next) == f.get(current) {
+			continue
+		}
+		if f.restart {
+			logger.Warn("setting changed, restart to apply it", "key", f.key)
+			f.set(next, f.get(current))
+			next.sources[f.key] = current.sources[f.key]
+			continue
+		}
+		changed = append(changed, f.key)
+	}
+	if len(changed) == 0 {
+		return nil, nil
+	}
+
+	w.current.Store(next)
+	for ch := range w.subs {
+		// replace a snapshot the subscriber hasn't taken yet
+		select {
+		case <-ch:
+		default:
+		}
+		ch <- next
+	}
+	return changed, nil
+}
+
+// Watch reloads on SIGHUP, and when the config or env file's modification
+// time changes, checked every interval, until ctx is cancelled.
+func (w *Watcher) Watch(ctx context.Context, interval time.Duration) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	files := w.files()
+	stamps := modTimes(files)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+			logger.Info("reloading on SIGHUP")
+		case <-ticker.C:
+			next := modTimes(files)
+			if next == stamps {
+				continue
+			}
+			stamps = next
+			logger.Info("config file changed, reloading")
+		}
+
+		changed, err := w.Reload()
+		switch {
+		case err != nil:
+			logger.Error("reload failed, keeping current settings", "error", err)
+		case len(changed) == 0:
+			logger.Info("reload found no changes")
+		default:
+			logger.Info("reloaded", "changed", changed)
+		}
+	}
+}
+
+// files returns the config and env files a reload reads.
+func (w *Watcher) files() []string {
+	file := w.opts.File
+	if file == "" {
+		file = os.Getenv("CONFIG_FILE")
+	}
+	envFile := w.opts.EnvFile
+	if envFile == "" {
+		envFile = ".env"
+	}
+	if file == "" {
+		return []string{envFile}
+	}
+	return []string{file, envFile}
+}
+
+// modTimes fingerprints files by modification time and size; missing files
+// count too, so creating one is noticed.
+func modTimes(files []string) string {
+	var stamp string
+	for _, file := range files {
+		if info, err := os.Stat(file); err == nil {
+			stamp += fmt.Sprintf("%s:%d:%d;", file, info.ModTime().UnixNano(), info.Size())
+		} else {
+			stamp += file + ":-;"
+		}
+	}
+	return stamp
+}
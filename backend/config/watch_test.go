@@ -0,0 +1,47 @@
+package config_test
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/furqanmk/reviews-browser/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWatcher_Reload(t *testing.T) {
+	file := writeFile(t, "config.yaml", "recency_cutoff: 24h\nserver_port: 9000\n")
+	opts := config.Options{File: file, EnvFile: envFile(t, "CLEANUP_EVERY=1h\n")}
+	cfg, err := config.Load(opts)
+	require.NoError(t, err)
+
+	w := config.NewWatcher(cfg, opts)
+	snapshots, unsubscribe := w.Subscribe()
+	defer unsubscribe()
+
+	// nothing changed
+	changed, err := w.Reload()
+	require.NoError(t, err)
+	require.Empty(t, changed)
+
+	require.NoError(t, os.WriteFile(file, []byte("recency_cutoff: 12h\nserver_port: 9001\n"), 0644))
+	require.NoError(t, os.WriteFile(opts.EnvFile, []byte("CLEANUP_EVERY=5m\n"), 0644))
+	changed, err = w.Reload()
+	require.NoError(t, err)
+	require.ElementsMatch(t, []string{"recency_cutoff", "cleanup_every"}, changed)
+
+	next := <-snapshots
+	require.Same(t, w.Current(), next)
+	require.Equal(t, 12*time.Hour, next.RecencyCutoff)
+	require.Equal(t, 5*time.Minute, next.CleanupEvery)
+	// restart-only settings keep their startup values
+	require.Equal(t, "9000", next.ServerPort)
+	// the old snapshot is untouched
+	require.Equal(t, 24*time.Hour, cfg.RecencyCutoff)
+
+	// an invalid file keeps the current snapshot
+	require.NoError(t, os.WriteFile(file, []byte("recency_cutoff: -1h\n"), 0644))
+	_, err = w.Reload()
+	require.Error(t, err)
+	require.Same(t, next, w.Current())
+}
@@ -0,0 +1,10 @@
+// Package docs carries the API's OpenAPI description, api.yaml, into the
+// binary, so the server can serve it and check requests against it.
+package docs
+
+import _ "embed"
+
+// APISpec is api.yaml.
+//
+//go:embed api.yaml
+var APISpec []byte
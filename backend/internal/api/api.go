@@ -3,92 +3,594 @@ package api
 import (
 	"context"
 	"encoding/json"
-	"log"
+	"errors"
+	"iter"
 	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"sync/atomic"
+	"time"
 
+	"github.com/furqanmk/reviews-browser/config"
 	"github.com/furqanmk/reviews-browser/internal/clients/appstore"
+	"github.com/furqanmk/reviews-browser/internal/database"
+	"github.com/furqanmk/reviews-browser/internal/events"
+	"github.com/furqanmk/reviews-browser/internal/ingest"
+	"github.com/furqanmk/reviews-browser/internal/jobs"
+	"github.com/furqanmk/reviews-browser/internal/logging"
 	"github.com/furqanmk/reviews-browser/internal/model"
+	"github.com/furqanmk/reviews-browser/internal/ratelimit"
+	"github.com/furqanmk/reviews-browser/internal/spam"
+	"github.com/furqanmk/reviews-browser/internal/stats"
+	"github.com/furqanmk/reviews-browser/internal/trends"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
 type Persistence interface {
 	GetRecentReviews(ctx context.Context, appID string) ([]model.Review, error)
-	InsertReview(ctx context.Context, review model.Review, appID string) error
+	UpdateSpamFlag(ctx context.Context, reviewID string, suspected bool, reason string) error
+	GetReview(ctx context.Context, reviewID string) (model.Review, error)
+	GetTriage(ctx context.Context, appID string) (map[string]model.Triage, error)
+	UpdateTriage(ctx context.Context, triage model.Triage) error
+	GetApps(ctx context.Context) ([]model.App, error)
+	GetPollRuns(ctx context.Context, appID string) ([]model.PollRun, error)
+	CountReviews(ctx context.Context) (map[string]int, error)
+	Ping(ctx context.Context) error
+	GetAPIKeys(ctx context.Context) ([]model.APIKey, error)
+	GetAPIKey(ctx context.Context, id string) (model.APIKey, error)
+	AddAPIKey(ctx context.Context, key model.APIKey) error
+	RemoveAPIKey(ctx context.Context, id string) error
+	TouchAPIKey(ctx context.Context, id string, at time.Time) error
+	GetDataVersion(ctx context.Context, appID string) (model.DataVersion, error)
+	Reviews(ctx context.Context, appIDs ...string) iter.Seq2[model.Review, error]
 }
 
+var logger = logging.For("api")
+
 type API struct {
-	db     Persistence
-	client *appstore.Client
-}
+	db       Persistence
+	client   *appstore.Client
+	pipeline *ingest.Pipeline
+	cfg      atomic.Pointer[config.Config]
+	trends   *trends.Cache
+	jobs     *jobs.Queue
+	limiter  *ratelimit.Limiter
 
-func NewAPI(db Persistence, client *appstore.Client) *API {
-	return &API{db: db, client: client}
+	storeCheck storeCheck
 }
 
-func (a *API) ReadyHandler(w http.ResponseWriter, r *http.Request) {
-	if _, err := w.Write([]byte("API is ready")); err != nil {
-		log.Println("Write error:", err)
+func NewAPI(db Persistence, client *appstore.Client, pipeline *ingest.Pipeline, cfg *config.Config) *API {
+	a := &API{
+		db:       db,
+		client:   client,
+		pipeline: pipeline,
+		trends:   trends.NewCache(trends.DefaultCacheTTL),
+		limiter:  ratelimit.New(),
 	}
+	a.cfg.Store(cfg)
+	return a
+}
+
+// SetConfig switches the API to a new configuration snapshot, such as one with
+// a rotated admin key.
+func (a *API) SetConfig(cfg *config.Config) {
+	a.cfg.Store(cfg)
 }
 
 // Handler for fetching reviews from the past 48 hours
 func (a *API) ReviewsHandler(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
-	// extract app id from query parameters
-	appID := r.URL.Query().Get("app_id")
+	// extract app id from the path or query parameters
+	appID := appParam(r)
 
 	if appID == "" {
-		http.Error(w, "Missing app_id", http.StatusBadRequest)
+		fail(w, r, http.StatusBadRequest, CodeMissingParameter, "Missing app_id")
+		return
+	}
+	spanApp(r, appID)
+	if a.notModified(w, r, appID) {
 		return
 	}
 
 	reviews, err := a.db.GetRecentReviews(ctx, appID)
 	if err != nil {
-		http.Error(w, "Database error", http.StatusInternalServerError)
-		log.Println("Database error:", err)
+		storeError(w, r, err)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(reviews); err != nil {
-		http.Error(w, "Encoding error", http.StatusInternalServerError)
-		log.Println("Encoding error:", err)
+	if lang := r.URL.Query().Get("language"); lang != "" {
+		reviews = filterByLanguage(reviews, lang)
+	}
+
+	triage, err := a.db.GetTriage(ctx, appID)
+	if err != nil {
+		storeError(w, r, err)
 		return
 	}
+	reviews = filterByTriage(withTriage(reviews, triage), r.URL.Query())
+
+	respondList(w, r, a.restrict(r, reviews))
 }
 
+// ReviewsHandlerByAppID fetches an app's recent reviews from the App Store,
+// stores them and returns them. It fails with 502, or 503 while the App Store
+// circuit is open, if the fetch fails. Fetching inside the request can take
+// minutes while the App Store is retrying, so it only serves the deprecated
+// /api/reviews_by_app; the v1 fetch route queues a poll instead.
 func (a *API) ReviewsHandlerByAppID(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
-	// extract app id from query parameters
-	appID := r.URL.Query().Get("app_id")
+	// extract app id from the path or query parameters
+	appID := appParam(r)
 
 	if appID == "" {
-		http.Error(w, "Missing app_id", http.StatusBadRequest)
+		fail(w, r, http.StatusBadRequest, CodeMissingParameter, "Missing app_id")
 		return
 	}
 
 	// Fetch recent reviews and update data store
-	reviews, err := a.client.FetchRecentReviews(appID)
+	spanApp(r, appID)
+	ctx = logging.With(ctx, "app_id", appID)
+	fetch, err := a.client.FetchRecentReviews(ctx, appID)
+	if errors.Is(err, appstore.ErrCircuitOpen) {
+		if until := a.client.Circuit().OpenUntil; until != nil {
+			w.Header().Set("Retry-After", strconv.Itoa(ceilSeconds(time.Until(*until))))
+		}
+		fail(w, r, http.StatusServiceUnavailable, CodeAppStoreUnavailable, "App Store requests are paused after repeated failures")
+		return
+	}
 	if err != nil {
-		log.Printf("Error fetching reviews for app %s: %v", appID, err)
-	} else {
-		for _, review := range reviews {
-			_ = a.db.InsertReview(ctx, review, appID)
+		logger.ErrorContext(ctx, "fetching reviews failed", "error", err)
+		fail(w, r, http.StatusBadGateway, CodeAppStoreUnavailable, "Fetching reviews from the App Store failed")
+		return
+	}
+	if _, err := a.pipeline.Ingest(ctx, appID, fetch.Reviews); err != nil {
+		logger.ErrorContext(ctx, "storing reviews failed", "error", err)
+	}
+
+	respondList(w, r, a.restrict(r, fetch.Reviews))
+}
+
+// StatsHandler returns volume, rating distribution and a per-language breakdown
+// of an app's recent reviews.
+func (a *API) StatsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	appID := appParam(r)
+	if appID == "" {
+		fail(w, r, http.StatusBadRequest, CodeMissingParameter, "Missing app_id")
+		return
+	}
+	if a.notModified(w, r, appID) {
+		return
+	}
+
+	reviews, err := a.db.GetRecentReviews(ctx, appID)
+	if err != nil {
+		storeError(w, r, err)
+		return
+	}
+
+	if r.URL.Query().Get("exclude_spam") == "true" {
+		reviews = filterSpam(reviews, false)
+	}
+
+	respond(w, r, http.StatusOK, stats.Summarize(reviews))
+}
+
+// SpamHandler lists an app's recent reviews that are flagged as suspected spam.
+func (a *API) SpamHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	appID := appParam(r)
+	if appID == "" {
+		fail(w, r, http.StatusBadRequest, CodeMissingParameter, "Missing app_id")
+		return
+	}
+	if a.notModified(w, r, appID) {
+		return
+	}
+
+	reviews, err := a.db.GetRecentReviews(ctx, appID)
+	if err != nil {
+		storeError(w, r, err)
+		return
+	}
+
+	respondList(w, r, a.restrict(r, filterSpam(reviews, true)))
+}
+
+// spamOverride is the body accepted by SpamOverrideHandler.
+type spamOverride struct {
+	SuspectedSpam bool   `json:"suspected_spam"`
+	Reason        string `json:"reason"`
+}
+
+// SpamOverrideHandler sets or clears the spam flag on a review by hand.
+func (a *API) SpamOverrideHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	reviewID := r.PathValue("id")
+
+	var body spamOverride
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		fail(w, r, http.StatusBadRequest, CodeInvalidBody, "Invalid request body")
+		return
+	}
+
+	review, err := a.db.GetReview(ctx, reviewID)
+	if errors.Is(err, database.ErrNotFound) || err == nil && !allowsApp(r, review.AppID) {
+		fail(w, r, http.StatusNotFound, CodeNotFound, "Review not found")
+		return
+	}
+	if err != nil {
+		storeError(w, r, err)
+		return
+	}
+
+	reason := ""
+	if body.SuspectedSpam {
+		reason = body.Reason
+		if reason == "" {
+			reason = spam.ReasonManual
 		}
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(reviews); err != nil {
-		http.Error(w, "Encoding error", http.StatusInternalServerError)
-		log.Println("Encoding error:", err)
+	err = a.db.UpdateSpamFlag(ctx, reviewID, body.SuspectedSpam, reason)
+	if errors.Is(err, database.ErrNotFound) {
+		fail(w, r, http.StatusNotFound, CodeNotFound, "Review not found")
+		return
+	}
+	if err != nil {
+		storeError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// triagePatch is the body accepted by TriageHandler. Omitted fields are left
+// unchanged.
+type triagePatch struct {
+	Status   *string   `json:"status"`
+	Assignee *string   `json:"assignee"`
+	Tags     *[]string `json:"tags"`
+	Notes    *string   `json:"notes"`
+}
+
+// TriageHandler updates the triage status, assignee, tags or notes of a review
+// and returns its new triage state.
+func (a *API) TriageHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	reviewID := r.PathValue("id")
+
+	var patch triagePatch
+	if err := json.NewDecoder(r.Body).Decode(&patch); err != nil {
+		fail(w, r, http.StatusBadRequest, CodeInvalidBody, "Invalid request body")
+		return
+	}
+	if patch.Status != nil && !model.ValidTriageStatus(*patch.Status) {
+		fail(w, r, http.StatusBadRequest, CodeInvalidBody, "Invalid status")
+		return
+	}
+	if patch.Tags != nil && slices.ContainsFunc(*patch.Tags, func(tag string) bool { return !model.ValidTag(tag) }) {
+		fail(w, r, http.StatusBadRequest, CodeInvalidBody, "Invalid tag: tags may be neither empty nor contain ;")
+		return
+	}
+
+	review, err := a.db.GetReview(ctx, reviewID)
+	if errors.Is(err, database.ErrNotFound) || err == nil && !allowsApp(r, review.AppID) {
+		fail(w, r, http.StatusNotFound, CodeNotFound, "Review not found")
+		return
+	}
+	if err != nil {
+		storeError(w, r, err)
+		return
+	}
+
+	existing, err := a.db.GetTriage(ctx, review.AppID)
+	if err != nil {
+		storeError(w, r, err)
+		return
+	}
+	triage, ok := existing[reviewID]
+	if !ok {
+		triage = model.Triage{ReviewID: reviewID, AppID: review.AppID, Status: model.TriageNew}
+	}
+
+	if patch.Status != nil {
+		triage.Status = *patch.Status
+	}
+	if patch.Assignee != nil {
+		triage.Assignee = *patch.Assignee
+	}
+	if patch.Tags != nil {
+		triage.Tags = *patch.Tags
+	}
+	if patch.Notes != nil {
+		triage.Notes = *patch.Notes
+	}
+	triage.UpdatedAt = time.Now().UTC()
+
+	if err := a.db.UpdateTriage(ctx, triage); err != nil {
+		storeError(w, r, err)
 		return
 	}
+
+	respond(w, r, http.StatusOK, triage)
 }
 
-// RegisterHandlers registers API endpoints.
-func (a *API) RegisterHandlers(mux *http.ServeMux) {
+// TrendsHandler returns the top keywords and phrases for an app over a window,
+// scored against the window before it.
+func (a *API) TrendsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	query := r.URL.Query()
+
+	appID := appParam(r)
+	if appID == "" {
+		fail(w, r, http.StatusBadRequest, CodeMissingParameter, "Missing app_id")
+		return
+	}
+
+	windowHrs, err := intParam(query.Get("window_hrs"), 24)
+	if err != nil || windowHrs <= 0 {
+		fail(w, r, http.StatusBadRequest, CodeInvalidParameter, "Invalid window_hrs")
+		return
+	}
+	// the window is scored against the one before it, and both must be
+	// within the recency cutoff for their reviews to be stored
+	cutoff := a.cfg.Load().RecencyCutoff
+	if maxHrs := int(cutoff / 2 / time.Hour); cutoff > 0 && windowHrs > maxHrs {
+		fail(w, r, http.StatusBadRequest, CodeInvalidParameter, "window_hrs may be at most "+strconv.Itoa(maxHrs)+", half the recency cutoff")
+		return
+	}
+	limit, err := intParam(query.Get("limit"), 20)
+	if err != nil || limit <= 0 {
+		fail(w, r, http.StatusBadRequest, CodeInvalidParameter, "Invalid limit")
+		return
+	}
+
+	opts := trends.Options{
+		Window:   time.Duration(windowHrs) * time.Hour,
+		Limit:    limit,
+		MinCount: 2,
+	}
+	// reports are kept against the app's data version rather than dropped
+	// when reviews change, as they may change in another process
+	version, err := a.db.GetDataVersion(ctx, appID)
+	if err != nil {
+		storeError(w, r, err)
+		return
+	}
+	report, err := a.trends.Get(appID, version.Version, opts, func() (trends.Report, error) {
+		reviews, err := a.db.GetRecentReviews(ctx, appID)
+		if err != nil {
+			return trends.Report{}, err
+		}
+		return trends.Analyze(appID, reviews, time.Now(), opts), nil
+	})
+	if err != nil {
+		storeError(w, r, err)
+		return
+	}
+	// reports are cached, so they are versioned by when they were made
+	generated := report.GeneratedAt.Format(time.RFC3339Nano)
+	if conditional(w, r, validators{etag: etag(r, generated), modified: report.GeneratedAt}) {
+		return
+	}
+
+	respond(w, r, http.StatusOK, report)
+}
+
+// restrict strips the unredacted original text from reviews unless the request
+// was authorized with an admin API key.
+func (a *API) restrict(r *http.Request, reviews []model.Review) []model.Review {
+	if seesOriginal(r) {
+		return reviews
+	}
+	restricted := make([]model.Review, len(reviews))
+	for i, review := range reviews {
+		review.Original = nil
+		restricted[i] = review
+	}
+	return restricted
+}
+
+// seesOriginal reports whether the request was authorized with an admin API
+// key, which may see the unredacted original text of reviews.
+func seesOriginal(r *http.Request) bool {
+	key := requestKey(r)
+	return key != nil && key.Role == model.RoleAdmin
+}
+
+// withTriage attaches triage state to reviews, defaulting untriaged reviews to
+// the "new" status.
+func withTriage(reviews []model.Review, triage map[string]model.Triage) []model.Review {
+	for i, review := range reviews {
+		t, ok := triage[review.ID]
+		if !ok {
+			t = model.Triage{ReviewID: review.ID, AppID: review.AppID, Status: model.TriageNew}
+		}
+		reviews[i].Triage = &t
+	}
+	return reviews
+}
+
+// filterByTriage applies the status, assignee and tag query filters.
+func filterByTriage(reviews []model.Review, query url.Values) []model.Review {
+	status, assignee, tag := query.Get("status"), query.Get("assignee"), query.Get("tag")
+	if status == "" && assignee == "" && tag == "" {
+		return reviews
+	}
+
+	filtered := make([]model.Review, 0, len(reviews))
+	for _, review := range reviews {
+		t := review.Triage
+		if status != "" && t.Status != status {
+			continue
+		}
+		if assignee != "" && t.Assignee != assignee {
+			continue
+		}
+		if tag != "" && !slices.Contains(t.Tags, tag) {
+			continue
+		}
+		filtered = append(filtered, review)
+	}
+	return filtered
+}
+
+// filterByLanguage keeps only reviews detected as the given language.
+func filterByLanguage(reviews []model.Review, lang string) []model.Review {
+	filtered := make([]model.Review, 0, len(reviews))
+	for _, review := range reviews {
+		if review.Language == lang {
+			filtered = append(filtered, review)
+		}
+	}
+	return filtered
+}
+
+// filterSpam keeps only reviews whose spam flag matches flagged.
+func filterSpam(reviews []model.Review, flagged bool) []model.Review {
+	filtered := make([]model.Review, 0, len(reviews))
+	for _, review := range reviews {
+		if review.SuspectedSpam == flagged {
+			filtered = append(filtered, review)
+		}
+	}
+	return filtered
+}
+
+// intParam parses an optional integer query parameter.
+func intParam(value string, defaultValue int) (int, error) {
+	if value == "" {
+		return defaultValue, nil
+	}
+	return strconv.Atoi(value)
+}
+
+// Listen drops an app's cached trends whenever new reviews for it are ingested
+// in this process, until ctx is done. Reports are also kept against the app's
+// data version, which catches changes made by other processes.
+func (a *API) Listen(ctx context.Context, bus *events.Bus) {
+	sub, unsubscribe := bus.Subscribe(64)
+	go func() {
+		defer unsubscribe()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case event := <-sub:
+				if event.Type == events.ReviewsIngested {
+					a.trends.Invalidate(event.AppID)
+				}
+			}
+		}
+	}()
+}
+
+// RegisterHandlers registers API endpoints, each behind the API key role it
+// needs and a rate limit. Probes, metrics and the OpenAPI description are left
+// open.
+//
+// Endpoints live under /api/v1, which wraps responses in an Envelope and
+// fails with a Problem. The routes from before v1 are kept, with their bare
+// bodies and plain text errors, as deprecated aliases. Atom and RSS feeds of
+// an app's reviews are served under /feeds, for feed readers.
+//
+// It returns an error naming every v1 route that docs/api.yaml doesn't
+// describe; those routes are left unregistered.
+func (a *API) RegisterHandlers(mux *http.ServeMux) error {
+	mux.HandleFunc("/api/live", a.LiveHandler)
 	mux.HandleFunc("/api/ready", a.ReadyHandler)
-	mux.HandleFunc("/api/reviews", a.ReviewsHandler)
-	mux.HandleFunc("/api/reviews_by_app", a.ReviewsHandlerByAppID)
+	mux.HandleFunc("GET /api/openapi.json", a.OpenAPIHandler)
+	mux.Handle("GET /metrics", promhttp.Handler())
+
+	read := func(role string, h http.HandlerFunc) http.HandlerFunc {
+		return a.authorize(role, a.limit(budgetRead, h))
+	}
+	fetch := func(role string, h http.HandlerFunc) http.HandlerFunc {
+		return a.authorize(role, a.limit(budgetFetch, h))
+	}
+
+	getStatus := read(model.RoleViewer, a.StatusHandler)
+	getReviews := read(model.RoleViewer, a.ReviewsHandler)
+	getStats := read(model.RoleViewer, a.StatsHandler)
+	getSpam := read(model.RoleViewer, a.SpamHandler)
+	getTrends := read(model.RoleViewer, a.TrendsHandler)
+	getPolls := read(model.RoleViewer, a.PollsHandler)
+	getJob := read(model.RoleViewer, a.JobHandler)
+	getRateLimit := a.authorize(model.RoleViewer, a.RateLimitHandler)
+	exportReviews := read(model.RoleViewer, a.ExportHandler)
+	getFeed := feedPath(read(model.RoleFeed, a.FeedHandler))
+	compareApps := read(model.RoleViewer, a.CompareHandler)
+	listApps := read(model.RoleViewer, a.AppsHandler)
+	getApp := read(model.RoleViewer, a.AppHandler)
+	// anyone who may read an app may have it fetched, so it can be browsed
+	// before it's tracked; fetches spend the fetch budget
+	fetchReviews := fetch(model.RoleViewer, a.FetchHandler)
+	// the legacy fetch needs no more than its successor, so clients that
+	// never sent a key keep working while keys aren't required
+	legacyFetchReviews := fetch(model.RoleViewer, a.ReviewsHandlerByAppID)
+
+	// these change reviews or call out to the App Store
+	setSpam := read(model.RoleTriager, a.SpamOverrideHandler)
+	patchTriage := read(model.RoleTriager, a.TriageHandler)
+	poll := fetch(model.RoleTriager, a.PollAppHandler)
+
+	listKeys := read(model.RoleAdmin, unscoped(a.KeysHandler))
+	createKey := read(model.RoleAdmin, unscoped(a.CreateKeyHandler))
+	deleteKey := read(model.RoleAdmin, unscoped(a.DeleteKeyHandler))
+	importReviews := read(model.RoleAdmin, unscoped(a.ImportHandler))
+
+	var undescribed []error
+	route := func(path string, handlers methods) {
+		if err := v1(mux, path, handlers); err != nil {
+			undescribed = append(undescribed, err)
+		}
+	}
+
+	route("/status", methods{http.MethodGet: getStatus})
+	route("/apps", methods{http.MethodGet: listApps})
+	route("/apps/{app_id}", methods{http.MethodGet: getApp})
+	route("/apps/{app_id}/reviews", methods{http.MethodGet: getReviews})
+	route("/apps/{app_id}/fetch", methods{http.MethodPost: fetchReviews})
+	route("/apps/{app_id}/stats", methods{http.MethodGet: getStats})
+	route("/apps/{app_id}/spam", methods{http.MethodGet: getSpam})
+	route("/apps/{app_id}/trends", methods{http.MethodGet: getTrends})
+	route("/apps/{app_id}/polls", methods{http.MethodGet: getPolls})
+	route("/apps/{app_id}/poll", methods{http.MethodPost: poll})
+	route("/reviews/export", methods{http.MethodGet: exportReviews})
+	route("/compare", methods{http.MethodGet: compareApps})
+	route("/reviews/{id}", methods{http.MethodPatch: patchTriage})
+	route("/reviews/{id}/spam", methods{http.MethodPut: setSpam})
+	route("/jobs/{id}", methods{http.MethodGet: getJob})
+	route("/keys", methods{http.MethodGet: listKeys, http.MethodPost: createKey})
+	route("/keys/{id}", methods{http.MethodDelete: deleteKey})
+	route("/rate_limit", methods{http.MethodGet: getRateLimit})
+	route("/imports", methods{http.MethodPost: importReviews})
+	mux.HandleFunc("/api/v1/", v1NotFound)
+
+	mux.HandleFunc("/api/status", deprecated("/api/v1/status", getStatus))
+	mux.HandleFunc("/api/reviews", deprecated("/api/v1/apps/{app_id}/reviews", getReviews))
+	mux.HandleFunc("/api/stats", deprecated("/api/v1/apps/{app_id}/stats", getStats))
+	mux.HandleFunc("/api/spam", deprecated("/api/v1/apps/{app_id}/spam", getSpam))
+	mux.HandleFunc("/api/trends", deprecated("/api/v1/apps/{app_id}/trends", getTrends))
+	mux.HandleFunc("GET /api/apps/{app_id}/polls", deprecated("/api/v1/apps/{app_id}/polls", getPolls))
+	mux.HandleFunc("GET /api/jobs/{id}", deprecated("/api/v1/jobs/{id}", getJob))
+	mux.HandleFunc("GET /api/rate_limit", deprecated("/api/v1/rate_limit", getRateLimit))
+	mux.HandleFunc("/api/reviews_by_app", deprecated("/api/v1/apps/{app_id}/fetch", legacyFetchReviews))
+	mux.HandleFunc("GET /api/reviews/export", deprecated("/api/v1/reviews/export", exportReviews))
+	mux.HandleFunc("GET /api/compare", deprecated("/api/v1/compare", compareApps))
+	mux.HandleFunc("PUT /api/reviews/{id}/spam", deprecated("/api/v1/reviews/{id}/spam", setSpam))
+	mux.HandleFunc("PATCH /api/reviews/{id}", deprecated("/api/v1/reviews/{id}", patchTriage))
+	mux.HandleFunc("POST /api/apps/{app_id}/poll", deprecated("/api/v1/apps/{app_id}/poll", poll))
+	mux.HandleFunc("GET /api/keys", deprecated("/api/v1/keys", listKeys))
+	mux.HandleFunc("POST /api/keys", deprecated("/api/v1/keys", createKey))
+	mux.HandleFunc("DELETE /api/keys/{id}", deprecated("/api/v1/keys/{id}", deleteKey))
+
+	mux.HandleFunc("GET /feeds/apps/{file}", getFeed)
+	return errors.Join(undescribed...)
 }
@@ -1,33 +1,223 @@
 package api_test
 
 import (
+	"compress/gzip"
 	"context"
 	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"io"
+	"io/fs"
+	"iter"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/andybalholm/brotli"
+	"github.com/furqanmk/reviews-browser/config"
 	"github.com/furqanmk/reviews-browser/internal/api"
+	"github.com/furqanmk/reviews-browser/internal/auth"
+	"github.com/furqanmk/reviews-browser/internal/clients/appstore"
+	"github.com/furqanmk/reviews-browser/internal/compare"
+	"github.com/furqanmk/reviews-browser/internal/database"
+	"github.com/furqanmk/reviews-browser/internal/events"
+	"github.com/furqanmk/reviews-browser/internal/ingest"
+	"github.com/furqanmk/reviews-browser/internal/jobs"
+	"github.com/furqanmk/reviews-browser/internal/logging"
 	"github.com/furqanmk/reviews-browser/internal/model"
+	"github.com/furqanmk/reviews-browser/internal/stats"
+	"github.com/furqanmk/reviews-browser/internal/trends"
 	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
 )
 
+// adminKey is the admin key tests configure for the routes that need a key.
+const adminKey = "admin"
+
+// asAdmin sends req with the admin key.
+func asAdmin(req *http.Request) *http.Request {
+	req.Header.Set(api.APIKeyHeader, adminKey)
+	return req
+}
+
 // mockPersistence implements the Persistence interface for testing
 type mockPersistence struct {
-	reviews []model.Review
-	err     error
+	reviews  []model.Review
+	triage   map[string]model.Triage
+	apps     []model.App
+	polls    []model.PollRun
+	keys     []model.APIKey
+	pings    int
+	versions map[string]model.DataVersion
+	err      error
+}
+
+// bump records a change to an app's reviews, as the store does on writes.
+func (m *mockPersistence) bump(appID string) {
+	if m.versions == nil {
+		m.versions = make(map[string]model.DataVersion)
+	}
+	v := m.versions[appID]
+	m.versions[appID] = model.DataVersion{AppID: appID, Version: v.Version + 1, ModifiedAt: time.Now().UTC()}
+}
+
+func (m *mockPersistence) GetDataVersion(ctx context.Context, appID string) (model.DataVersion, error) {
+	v, ok := m.versions[appID]
+	if !ok {
+		v.AppID = appID
+	}
+	return v, m.err
 }
 
 func (m *mockPersistence) GetRecentReviews(ctx context.Context, appID string) ([]model.Review, error) {
 	return m.reviews, m.err
 }
 
+func (m *mockPersistence) Reviews(ctx context.Context, appIDs ...string) iter.Seq2[model.Review, error] {
+	return func(yield func(model.Review, error) bool) {
+		if m.err != nil {
+			yield(model.Review{}, m.err)
+			return
+		}
+		for _, review := range m.reviews {
+			if len(appIDs) > 0 && !slices.Contains(appIDs, review.AppID) {
+				continue
+			}
+			if !yield(review, nil) {
+				return
+			}
+		}
+	}
+}
+
+func (m *mockPersistence) UpdateSpamFlag(ctx context.Context, reviewID string, suspected bool, reason string) error {
+	for i := range m.reviews {
+		if m.reviews[i].ID == reviewID {
+			m.reviews[i].SuspectedSpam = suspected
+			m.reviews[i].SpamReason = reason
+			m.bump(m.reviews[i].AppID)
+			return m.err
+		}
+	}
+	return database.ErrNotFound
+}
+
+func (m *mockPersistence) GetReview(ctx context.Context, reviewID string) (model.Review, error) {
+	for _, review := range m.reviews {
+		if review.ID == reviewID {
+			return review, m.err
+		}
+	}
+	return model.Review{}, database.ErrNotFound
+}
+
+func (m *mockPersistence) GetTriage(ctx context.Context, appID string) (map[string]model.Triage, error) {
+	return m.triage, m.err
+}
+
+func (m *mockPersistence) UpdateTriage(ctx context.Context, triage model.Triage) error {
+	if m.triage == nil {
+		m.triage = make(map[string]model.Triage)
+	}
+	m.triage[triage.ReviewID] = triage
+	m.bump(triage.AppID)
+	return m.err
+}
+
+func (m *mockPersistence) GetApps(ctx context.Context) ([]model.App, error) {
+	return m.apps, m.err
+}
+
+func (m *mockPersistence) GetPollRuns(ctx context.Context, appID string) ([]model.PollRun, error) {
+	var runs []model.PollRun
+	for _, run := range m.polls {
+		if run.AppID == appID {
+			runs = append(runs, run)
+		}
+	}
+	return runs, m.err
+}
+
+func (m *mockPersistence) GetAPIKeys(ctx context.Context) ([]model.APIKey, error) {
+	return m.keys, m.err
+}
+
+func (m *mockPersistence) GetAPIKey(ctx context.Context, id string) (model.APIKey, error) {
+	for _, key := range m.keys {
+		if key.ID == id {
+			return key, m.err
+		}
+	}
+	return model.APIKey{}, database.ErrNotFound
+}
+
+func (m *mockPersistence) AddAPIKey(ctx context.Context, key model.APIKey) error {
+	m.keys = append(m.keys, key)
+	return m.err
+}
+
+func (m *mockPersistence) RemoveAPIKey(ctx context.Context, id string) error {
+	for i, key := range m.keys {
+		if key.ID == id {
+			m.keys = slices.Delete(m.keys, i, i+1)
+			return m.err
+		}
+	}
+	return database.ErrNotFound
+}
+
+func (m *mockPersistence) TouchAPIKey(ctx context.Context, id string, at time.Time) error {
+	for i := range m.keys {
+		if m.keys[i].ID == id {
+			m.keys[i].LastUsedAt = &at
+		}
+	}
+	return m.err
+}
+
+func (m *mockPersistence) CountReviews(ctx context.Context) (map[string]int, error) {
+	counts := make(map[string]int)
+	for _, review := range m.reviews {
+		counts[review.AppID]++
+	}
+	return counts, m.err
+}
+
+func (m *mockPersistence) Ping(ctx context.Context) error {
+	m.pings++
+	return m.err
+}
+
+func (m *mockPersistence) InsertReviews(ctx context.Context, appID string, reviews []model.Review) (int, error) {
+	inserted := 0
+	for _, review := range reviews {
+		if slices.ContainsFunc(m.reviews, func(existing model.Review) bool { return existing.ID == review.ID }) {
+			continue
+		}
+		review.AppID = appID
+		m.reviews = append(m.reviews, review)
+		inserted++
+	}
+	if inserted > 0 {
+		m.bump(appID)
+	}
+	return inserted, m.err
+}
+
 func TestReviewsHandler_Success(t *testing.T) {
 	mockReviews := []model.Review{
 		{ID: "1", Content: "Great app!"},
 		{ID: "2", Content: "Needs improvement."},
 	}
-	mockAPI := api.NewAPI(&mockPersistence{reviews: mockReviews})
+	mockAPI := api.NewAPI(&mockPersistence{reviews: mockReviews}, nil, nil, &config.Config{})
 
 	req := httptest.NewRequest(http.MethodGet, "/api/reviews?app_id=1234", nil)
 	w := httptest.NewRecorder()
@@ -52,7 +242,7 @@ func TestReviewsHandler_Success(t *testing.T) {
 }
 
 func TestReviewsHandler_MissingAppID(t *testing.T) {
-	mockAPI := api.NewAPI(&mockPersistence{})
+	mockAPI := api.NewAPI(&mockPersistence{}, nil, nil, &config.Config{})
 
 	req := httptest.NewRequest(http.MethodGet, "/api/reviews", nil)
 	w := httptest.NewRecorder()
@@ -66,7 +256,7 @@ func TestReviewsHandler_MissingAppID(t *testing.T) {
 }
 
 func TestReviewsHandler_DBError(t *testing.T) {
-	mockAPI := api.NewAPI(&mockPersistence{err: context.DeadlineExceeded})
+	mockAPI := api.NewAPI(&mockPersistence{err: context.DeadlineExceeded}, nil, nil, &config.Config{})
 
 	req := httptest.NewRequest(http.MethodGet, "/api/reviews?app_id=test-app", nil)
 	w := httptest.NewRecorder()
@@ -78,3 +268,1295 @@ func TestReviewsHandler_DBError(t *testing.T) {
 
 	require.Equal(t, http.StatusInternalServerError, resp.StatusCode)
 }
+
+func TestTrendsHandler_Success(t *testing.T) {
+	now := time.Now()
+	mockReviews := []model.Review{
+		{ID: "1", Content: "Dark mode please", CreatedAt: now.Add(-time.Hour)},
+		{ID: "2", Content: "Where is dark mode?", CreatedAt: now.Add(-2 * time.Hour)},
+	}
+	mockAPI := api.NewAPI(&mockPersistence{reviews: mockReviews}, nil, nil, &config.Config{})
+
+	req := httptest.NewRequest(http.MethodGet, "/api/trends?app_id=1234&window_hrs=12", nil)
+	w := httptest.NewRecorder()
+
+	mockAPI.TrendsHandler(w, req)
+
+	resp := w.Result()
+	defer resp.Body.Close()
+
+	var report trends.Report
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&report))
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, 12, report.WindowHrs)
+	require.NotEmpty(t, report.Bigrams)
+	require.Equal(t, "dark mode", report.Bigrams[0].Term)
+}
+
+func TestTrendsHandler_InvalidWindow(t *testing.T) {
+	mockAPI := api.NewAPI(&mockPersistence{}, nil, nil, &config.Config{RecencyCutoff: 48 * time.Hour})
+
+	for window, status := range map[string]int{"abc": http.StatusBadRequest, "25": http.StatusBadRequest, "24": http.StatusOK} {
+		req := httptest.NewRequest(http.MethodGet, "/api/trends?app_id=1234&window_hrs="+window, nil)
+		w := httptest.NewRecorder()
+
+		mockAPI.TrendsHandler(w, req)
+
+		require.Equal(t, status, w.Result().StatusCode, "window_hrs=%s", window)
+	}
+}
+
+func TestTrendsHandler_Versioned(t *testing.T) {
+	now := time.Now()
+	db := &mockPersistence{
+		reviews:  []model.Review{{ID: "1", Content: "Dark mode please", CreatedAt: now.Add(-time.Hour)}},
+		versions: map[string]model.DataVersion{"1234": {AppID: "1234", Version: 1}},
+	}
+	mockAPI := api.NewAPI(db, nil, nil, &config.Config{})
+	bigrams := func() []trends.Term {
+		w := httptest.NewRecorder()
+		mockAPI.TrendsHandler(w, httptest.NewRequest(http.MethodGet, "/api/trends?app_id=1234", nil))
+		var report trends.Report
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&report))
+		return report.Bigrams
+	}
+	require.Empty(t, bigrams())
+
+	// the report is reused until the app's reviews get a new version, however
+	// they changed
+	db.reviews = append(db.reviews, model.Review{ID: "2", Content: "Where is dark mode?", CreatedAt: now.Add(-time.Hour)})
+	require.Empty(t, bigrams())
+	db.versions["1234"] = model.DataVersion{AppID: "1234", Version: 2}
+	require.Equal(t, "dark mode", bigrams()[0].Term)
+}
+
+// unversioned stores reviews without bumping their data version, so only an
+// ingest event tells the API they changed.
+type unversioned struct{ *mockPersistence }
+
+func (u unversioned) InsertReviews(ctx context.Context, appID string, reviews []model.Review) (int, error) {
+	u.reviews = append(u.reviews, reviews...)
+	return len(reviews), nil
+}
+
+func TestListen(t *testing.T) {
+	now := time.Now()
+	db := &mockPersistence{reviews: []model.Review{{ID: "1", Content: "Dark mode please", CreatedAt: now.Add(-time.Hour)}}}
+	bus := events.NewBus()
+	pipeline := ingest.NewPipeline(unversioned{db}, bus)
+	mockAPI := api.NewAPI(db, nil, pipeline, &config.Config{})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	mockAPI.Listen(ctx, bus)
+
+	bigrams := func() []trends.Term {
+		w := httptest.NewRecorder()
+		mockAPI.TrendsHandler(w, httptest.NewRequest(http.MethodGet, "/api/trends?app_id=1234", nil))
+		var report trends.Report
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&report))
+		return report.Bigrams
+	}
+	require.Empty(t, bigrams())
+
+	// the cached report is dropped on ingest, though the version is unchanged
+	_, err := pipeline.Ingest(ctx, "1234", []model.Review{{ID: "2", Content: "Where is dark mode?", CreatedAt: now.Add(-time.Hour)}})
+	require.NoError(t, err)
+	require.Eventually(t, func() bool {
+		terms := bigrams()
+		return len(terms) > 0 && terms[0].Term == "dark mode"
+	}, time.Second, time.Millisecond)
+}
+
+func TestReviewsHandler_LanguageFilter(t *testing.T) {
+	mockReviews := []model.Review{
+		{ID: "1", Content: "Great app!", Language: "en"},
+		{ID: "2", Content: "¡Muy buena!", Language: "es"},
+	}
+	mockAPI := api.NewAPI(&mockPersistence{reviews: mockReviews}, nil, nil, &config.Config{})
+
+	req := httptest.NewRequest(http.MethodGet, "/api/reviews?app_id=1234&language=es", nil)
+	w := httptest.NewRecorder()
+
+	mockAPI.ReviewsHandler(w, req)
+
+	var actual []model.Review
+	require.NoError(t, json.NewDecoder(w.Result().Body).Decode(&actual))
+	require.Len(t, actual, 1)
+	require.Equal(t, "2", actual[0].ID)
+}
+
+func TestStatsHandler_Success(t *testing.T) {
+	mockReviews := []model.Review{
+		{ID: "1", Rating: 5, Language: "en"},
+		{ID: "2", Rating: 2, Language: "es"},
+		{ID: "3", Rating: 2, Language: "en"},
+	}
+	mockAPI := api.NewAPI(&mockPersistence{reviews: mockReviews}, nil, nil, &config.Config{})
+
+	req := httptest.NewRequest(http.MethodGet, "/api/stats?app_id=1234", nil)
+	w := httptest.NewRecorder()
+
+	mockAPI.StatsHandler(w, req)
+
+	var summary stats.Summary
+	require.NoError(t, json.NewDecoder(w.Result().Body).Decode(&summary))
+	require.Equal(t, http.StatusOK, w.Result().StatusCode)
+	require.Equal(t, 3, summary.Total)
+	require.Equal(t, 3.0, summary.AverageRating)
+	require.Equal(t, 2, summary.Ratings[2])
+	require.Equal(t, map[string]int{"en": 2, "es": 1}, summary.Languages)
+}
+
+func TestStatsHandler_ExcludeSpam(t *testing.T) {
+	mockReviews := []model.Review{
+		{ID: "1", Rating: 5},
+		{ID: "2", Rating: 5, SuspectedSpam: true, SpamReason: "near_duplicate"},
+	}
+	mockAPI := api.NewAPI(&mockPersistence{reviews: mockReviews}, nil, nil, &config.Config{})
+
+	req := httptest.NewRequest(http.MethodGet, "/api/stats?app_id=1234&exclude_spam=true", nil)
+	w := httptest.NewRecorder()
+
+	mockAPI.StatsHandler(w, req)
+
+	var summary stats.Summary
+	require.NoError(t, json.NewDecoder(w.Result().Body).Decode(&summary))
+	require.Equal(t, 1, summary.Total)
+}
+
+func TestSpamOverrideHandler(t *testing.T) {
+	db := &mockPersistence{reviews: []model.Review{
+		{ID: "1", SuspectedSpam: true, SpamReason: "near_duplicate"},
+	}}
+	mux := http.NewServeMux()
+	require.NoError(t, api.NewAPI(db, nil, nil, &config.Config{AdminAPIKey: adminKey}).RegisterHandlers(mux))
+
+	req := httptest.NewRequest(http.MethodPut, "/api/reviews/1/spam", strings.NewReader(`{"suspected_spam": false}`))
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, asAdmin(req))
+
+	require.Equal(t, http.StatusNoContent, w.Result().StatusCode)
+	require.False(t, db.reviews[0].SuspectedSpam)
+	require.Empty(t, db.reviews[0].SpamReason)
+
+	req = httptest.NewRequest(http.MethodPut, "/api/reviews/404/spam", strings.NewReader(`{"suspected_spam": true}`))
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, asAdmin(req))
+
+	require.Equal(t, http.StatusNotFound, w.Result().StatusCode)
+}
+
+func TestReviewsHandler_OriginalRestricted(t *testing.T) {
+	mockReviews := []model.Review{{
+		ID:         "1",
+		Content:    "Mail me at [EMAIL]",
+		Redactions: []string{"email"},
+		Original:   &model.OriginalText{Content: "Mail me at me@example.com"},
+	}}
+	mux := http.NewServeMux()
+	require.NoError(t, api.NewAPI(&mockPersistence{reviews: mockReviews}, nil, nil, &config.Config{AdminAPIKey: "secret"}).RegisterHandlers(mux))
+
+	for key, wantOriginal := range map[string]bool{"": false, "secret": true} {
+		req := httptest.NewRequest(http.MethodGet, "/api/reviews?app_id=1234", nil)
+		req.Header.Set("X-API-Key", key)
+		w := httptest.NewRecorder()
+
+		mux.ServeHTTP(w, req)
+
+		var actual []model.Review
+		require.NoError(t, json.NewDecoder(w.Result().Body).Decode(&actual))
+		require.Equal(t, []string{"email"}, actual[0].Redactions)
+		require.Equal(t, wantOriginal, actual[0].Original != nil, key)
+	}
+
+	// a key that doesn't check out is refused rather than ignored
+	req := httptest.NewRequest(http.MethodGet, "/api/reviews?app_id=1234", nil)
+	req.Header.Set("X-API-Key", "wrong")
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+	require.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func TestTriageHandler(t *testing.T) {
+	db := &mockPersistence{reviews: []model.Review{
+		{ID: "1", AppID: "1234", Rating: 1},
+		{ID: "2", AppID: "1234", Rating: 2},
+	}}
+	mux := http.NewServeMux()
+	require.NoError(t, api.NewAPI(db, nil, nil, &config.Config{AdminAPIKey: adminKey}).RegisterHandlers(mux))
+
+	req := httptest.NewRequest(http.MethodPatch, "/api/reviews/1", strings.NewReader(`{"status": "in-progress", "assignee": "sam", "tags": ["login"]}`))
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, asAdmin(req))
+	require.Equal(t, http.StatusOK, w.Result().StatusCode)
+
+	// a second patch only changes the fields it names
+	req = httptest.NewRequest(http.MethodPatch, "/api/reviews/1", strings.NewReader(`{"notes": "replied via support"}`))
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, asAdmin(req))
+	require.Equal(t, http.StatusOK, w.Result().StatusCode)
+
+	triage := db.triage["1"]
+	require.Equal(t, model.TriageInProgress, triage.Status)
+	require.Equal(t, "sam", triage.Assignee)
+	require.Equal(t, []string{"login"}, triage.Tags)
+	require.Equal(t, "replied via support", triage.Notes)
+
+	req = httptest.NewRequest(http.MethodGet, "/api/reviews?app_id=1234&status=new", nil)
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+
+	var actual []model.Review
+	require.NoError(t, json.NewDecoder(w.Result().Body).Decode(&actual))
+	require.Len(t, actual, 1)
+	require.Equal(t, "2", actual[0].ID)
+	require.Equal(t, model.TriageNew, actual[0].Triage.Status)
+}
+
+func TestTriageHandler_InvalidStatus(t *testing.T) {
+	db := &mockPersistence{reviews: []model.Review{{ID: "1", AppID: "1234"}}}
+	mux := http.NewServeMux()
+	require.NoError(t, api.NewAPI(db, nil, nil, &config.Config{AdminAPIKey: adminKey}).RegisterHandlers(mux))
+
+	req := httptest.NewRequest(http.MethodPatch, "/api/reviews/1", strings.NewReader(`{"status": "done"}`))
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, asAdmin(req))
+
+	require.Equal(t, http.StatusBadRequest, w.Result().StatusCode)
+	require.Empty(t, db.triage)
+
+	// tags are stored joined by ";"
+	for _, body := range []string{`{"tags": ["login;crash"]}`, `{"tags": [""]}`} {
+		req = httptest.NewRequest(http.MethodPatch, "/api/reviews/1", strings.NewReader(body))
+		w = httptest.NewRecorder()
+		mux.ServeHTTP(w, asAdmin(req))
+		require.Equal(t, http.StatusBadRequest, w.Result().StatusCode, body)
+	}
+	require.Empty(t, db.triage)
+}
+
+func TestInstrument(t *testing.T) {
+	db := &mockPersistence{reviews: []model.Review{{ID: "metrics-1"}}}
+	mux := http.NewServeMux()
+	require.NoError(t, api.NewAPI(db, nil, nil, &config.Config{AdminAPIKey: adminKey}).RegisterHandlers(mux))
+	handler := api.Instrument(mux)
+
+	for _, path := range []string{"/api/reviews/metrics-1/spam", "/api/reviews/metrics-404/spam"} {
+		req := httptest.NewRequest(http.MethodPut, path, strings.NewReader(`{"suspected_spam": true}`))
+		handler.ServeHTTP(httptest.NewRecorder(), asAdmin(req))
+	}
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/nowhere", nil))
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	body := w.Body.String()
+	// requests are labelled by pattern, not by path
+	require.Contains(t, body, `reviews_browser_http_requests_total{method="PUT",route="PUT /api/reviews/{id}/spam",status="204"} 1`)
+	require.Contains(t, body, `reviews_browser_http_requests_total{method="PUT",route="PUT /api/reviews/{id}/spam",status="404"} 1`)
+	require.Contains(t, body, `reviews_browser_http_requests_total{method="GET",route="unmatched",status="404"} 1`)
+	require.Contains(t, body, `reviews_browser_http_request_duration_seconds_count{method="PUT",route="PUT /api/reviews/{id}/spam"} 2`)
+}
+
+func TestRequestID(t *testing.T) {
+	var seen string
+	handler := api.RequestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = logging.RequestID(r.Context())
+	}))
+
+	// a caller's ID is kept
+	req := httptest.NewRequest(http.MethodGet, "/api/ready", nil)
+	req.Header.Set(api.RequestIDHeader, "abc-123")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	require.Equal(t, "abc-123", seen)
+	require.Equal(t, "abc-123", w.Header().Get(api.RequestIDHeader))
+
+	// a missing or unreasonable one is replaced
+	req = httptest.NewRequest(http.MethodGet, "/api/ready", nil)
+	req.Header.Set(api.RequestIDHeader, "evil\nid")
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	require.Len(t, seen, 16)
+	require.Equal(t, seen, w.Header().Get(api.RequestIDHeader))
+}
+
+func TestTrace(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	db := &mockPersistence{reviews: []model.Review{{ID: "1", AppID: "42"}}}
+	mux := http.NewServeMux()
+	require.NoError(t, api.NewAPI(db, nil, nil, &config.Config{}).RegisterHandlers(mux))
+	handler := api.Trace(mux)
+
+	// the caller's trace is continued
+	req := httptest.NewRequest(http.MethodGet, "/api/reviews?app_id=42", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 1)
+	require.Equal(t, "/api/reviews", spans[0].Name())
+	require.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", spans[0].SpanContext().TraceID().String())
+	require.Equal(t, "00f067aa0ba902b7", spans[0].Parent().SpanID().String())
+	require.Contains(t, spans[0].Attributes(), attribute.String("app_id", "42"))
+}
+
+func TestReadyHandler(t *testing.T) {
+	mux := http.NewServeMux()
+	db := &mockPersistence{}
+	require.NoError(t, api.NewAPI(db, appstore.NewClient(&config.Config{}), nil, &config.Config{}).RegisterHandlers(mux))
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/ready", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	var readiness api.Readiness
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&readiness))
+	require.True(t, readiness.Ready)
+	require.Equal(t, "ok", readiness.Checks["store"].Status)
+	require.Equal(t, "ok", readiness.Checks["appstore"].Status)
+
+	// probes in quick succession reuse the store check
+	mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/ready", nil))
+	require.Equal(t, 1, db.pings)
+
+	// an unreachable store makes the API unready, but it stays live
+	mux = http.NewServeMux()
+	require.NoError(t, api.NewAPI(&mockPersistence{err: fs.ErrPermission}, nil, nil, &config.Config{}).RegisterHandlers(mux))
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/ready", nil))
+	require.Equal(t, http.StatusServiceUnavailable, w.Code)
+	readiness = api.Readiness{}
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&readiness))
+	require.False(t, readiness.Ready)
+	require.Equal(t, "failed", readiness.Checks["store"].Status)
+	require.Contains(t, readiness.Checks["store"].Detail, "permission denied")
+
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/live", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestStatusHandler(t *testing.T) {
+	now := time.Now().Truncate(time.Second)
+	db := &mockPersistence{
+		apps: []model.App{
+			{ID: "1", PollEverySeconds: 60, LastFetched: now.Add(-30 * time.Second), LastSuccess: now.Add(-30 * time.Second)},
+			{ID: "2", PollEverySeconds: 60, LastFetched: now.Add(-time.Hour), LastError: "unexpected status 503", LastErrorAt: now.Add(-time.Hour)},
+		},
+		reviews: []model.Review{{ID: "a", AppID: "1"}, {ID: "b", AppID: "1"}},
+	}
+	mockAPI := api.NewAPI(db, nil, nil, &config.Config{})
+
+	w := httptest.NewRecorder()
+	mockAPI.StatusHandler(w, httptest.NewRequest(http.MethodGet, "/api/status", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var status api.Status
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&status))
+	require.Len(t, status.Apps, 2)
+
+	healthy := status.Apps[0]
+	require.Equal(t, "1", healthy.AppID)
+	require.Equal(t, 2, healthy.ReviewCount)
+	require.False(t, healthy.Overdue)
+	require.True(t, now.Add(30*time.Second).Equal(healthy.NextPoll))
+	require.NotNil(t, healthy.LastSuccess)
+	require.Empty(t, healthy.LastError)
+
+	stuck := status.Apps[1]
+	require.Equal(t, 0, stuck.ReviewCount)
+	require.True(t, stuck.Overdue)
+	require.Nil(t, stuck.LastSuccess)
+	require.Equal(t, "unexpected status 503", stuck.LastError)
+	require.NotNil(t, stuck.LastErrorAt)
+}
+
+func TestPollsHandler(t *testing.T) {
+	now := time.Now()
+	db := &mockPersistence{polls: []model.PollRun{
+		{AppID: "1", StartedAt: now, FinishedAt: now, Pages: 2, Seen: 20, New: 3, Duplicate: 17},
+		{AppID: "1", StartedAt: now.Add(-time.Minute), FinishedAt: now.Add(-time.Minute), ErrorClass: model.PollErrorHTTPStatus, Error: "unexpected status 503"},
+		{AppID: "1", StartedAt: now.Add(-2 * time.Minute), FinishedAt: now.Add(-2 * time.Minute), ErrorClass: model.PollErrorNetwork, Error: "connection refused"},
+		{AppID: "2", StartedAt: now, FinishedAt: now},
+	}}
+	mux := http.NewServeMux()
+	require.NoError(t, api.NewAPI(db, nil, nil, &config.Config{}).RegisterHandlers(mux))
+
+	get := func(target string) []model.PollRun {
+		t.Helper()
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, target, nil))
+		require.Equal(t, http.StatusOK, w.Code)
+		var runs []model.PollRun
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&runs))
+		return runs
+	}
+
+	runs := get("/api/apps/1/polls")
+	require.Len(t, runs, 3)
+	require.Equal(t, 2, runs[0].Pages)
+	require.Equal(t, 3, runs[0].New)
+
+	require.Len(t, get("/api/apps/1/polls?limit=1"), 1)
+
+	runs = get("/api/apps/1/polls?errors=true")
+	require.Len(t, runs, 2)
+	require.Equal(t, "unexpected status 503", runs[0].Error)
+
+	runs = get("/api/apps/1/polls?error_class=network")
+	require.Len(t, runs, 1)
+	require.Equal(t, "connection refused", runs[0].Error)
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/apps/1/polls?limit=0", nil))
+	require.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestPollAppHandler(t *testing.T) {
+	release := make(chan struct{})
+	queue := jobs.NewQueue(func(ctx context.Context, appID string) (model.PollRun, error) {
+		<-release
+		return model.PollRun{AppID: appID, Pages: 1, New: 2}, nil
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go queue.Run(ctx)
+
+	mux := http.NewServeMux()
+	handlers := api.NewAPI(&mockPersistence{apps: []model.App{{ID: "1"}}}, nil, nil, &config.Config{AdminAPIKey: adminKey})
+	handlers.SetJobs(queue)
+	require.NoError(t, handlers.RegisterHandlers(mux))
+
+	post := func(target string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, asAdmin(httptest.NewRequest(http.MethodPost, target, nil)))
+		return w
+	}
+
+	w := post("/api/apps/1/poll")
+	require.Equal(t, http.StatusAccepted, w.Code)
+	var job jobs.Job
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&job))
+	require.Equal(t, "/api/jobs/"+job.ID, w.Header().Get("Location"))
+	require.Equal(t, "1", job.AppID)
+
+	// the in-flight job is reused
+	w = post("/api/apps/1/poll")
+	require.Equal(t, http.StatusOK, w.Code)
+	var again jobs.Job
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&again))
+	require.Equal(t, job.ID, again.ID)
+
+	// so is it when asked to fetch
+	w = post("/api/v1/apps/1/fetch")
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Equal(t, "/api/v1/jobs/"+job.ID, w.Header().Get("Location"))
+
+	// only tracked apps are polled, but any app may be fetched
+	require.Equal(t, http.StatusNotFound, post("/api/apps/2/poll").Code)
+	require.Equal(t, http.StatusAccepted, post("/api/v1/apps/2/fetch").Code)
+
+	close(release)
+	require.Eventually(t, func() bool {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/jobs/"+job.ID, nil))
+		require.Equal(t, http.StatusOK, w.Code)
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&job))
+		return job.Status == jobs.StatusSucceeded
+	}, time.Second, time.Millisecond)
+	require.Equal(t, 2, job.Result.New)
+
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/jobs/unknown", nil))
+	require.Equal(t, http.StatusNotFound, w.Code)
+}
+
+// TestFrontendFlow follows the requests the frontend makes to show an app
+// with nothing stored yet, without an API key: read the stored reviews, queue
+// a fetch, wait on the job and read them again.
+func TestFrontendFlow(t *testing.T) {
+	db := &mockPersistence{}
+	queue := jobs.NewQueue(func(ctx context.Context, appID string) (model.PollRun, error) {
+		db.reviews = append(db.reviews, model.Review{ID: "r1", AppID: appID, CreatedAt: time.Now()})
+		return model.PollRun{AppID: appID, New: 1}, nil
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go queue.Run(ctx)
+
+	mux := http.NewServeMux()
+	handlers := api.NewAPI(db, nil, nil, &config.Config{RequireAPIKeys: false, RecencyCutoff: 48 * time.Hour})
+	handlers.SetJobs(queue)
+	require.NoError(t, handlers.RegisterHandlers(mux))
+	do := func(method, target string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(method, target, nil))
+		return w
+	}
+	type page struct {
+		Data []model.Review `json:"data"`
+	}
+
+	w := do(http.MethodGet, "/api/v1/apps/42/reviews?limit=500")
+	require.Equal(t, http.StatusOK, w.Code)
+	var stored page
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&stored))
+	require.Empty(t, stored.Data)
+
+	// the app isn't tracked, and no key is sent
+	w = do(http.MethodPost, "/api/v1/apps/42/fetch")
+	require.Equal(t, http.StatusAccepted, w.Code)
+	location := w.Header().Get("Location")
+	require.NotEmpty(t, location)
+
+	require.Eventually(t, func() bool {
+		w := do(http.MethodGet, location)
+		require.Equal(t, http.StatusOK, w.Code)
+		var job struct {
+			Data jobs.Job `json:"data"`
+		}
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&job))
+		return job.Data.Status == jobs.StatusSucceeded
+	}, time.Second, time.Millisecond)
+
+	w = do(http.MethodGet, "/api/v1/apps/42/reviews?limit=500")
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&stored))
+	require.Len(t, stored.Data, 1)
+}
+
+func TestAuthorize(t *testing.T) {
+	newKey := func(name, role string, apps ...string) (model.APIKey, string) {
+		id, secret, hash := auth.NewKey()
+		return model.APIKey{ID: id, Name: name, Role: role, Apps: apps, Hash: hash}, secret
+	}
+	viewer, viewerSecret := newKey("support", model.RoleViewer, "1")
+	triager, triagerSecret := newKey("triage", model.RoleTriager)
+	scopedAdmin, scopedAdminSecret := newKey("team lead", model.RoleAdmin, "1")
+	db := &mockPersistence{
+		keys: []model.APIKey{viewer, triager, scopedAdmin},
+		apps: []model.App{{ID: "1"}, {ID: "2"}},
+		reviews: []model.Review{{
+			ID: "r1", AppID: "1", Content: "mail me at ***",
+			Original: &model.OriginalText{Content: "mail me at me@example.com"},
+		}},
+	}
+	mux := http.NewServeMux()
+	require.NoError(t, api.NewAPI(db, nil, nil, &config.Config{RequireAPIKeys: true, AdminAPIKey: "bootstrap"}).RegisterHandlers(mux))
+
+	do := func(method, target, key, body string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		if key != "" {
+			req.Header.Set(api.APIKeyHeader, key)
+		}
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		return w
+	}
+
+	// probes stay open, everything else needs a valid key
+	require.Equal(t, http.StatusOK, do(http.MethodGet, "/api/live", "", "").Code)
+	require.Equal(t, http.StatusUnauthorized, do(http.MethodGet, "/api/reviews?app_id=1", "", "").Code)
+	require.Equal(t, http.StatusUnauthorized, do(http.MethodGet, "/api/reviews?app_id=1", viewerSecret+"x", "").Code)
+
+	// a viewer can read its own apps, without the original text
+	w := do(http.MethodGet, "/api/reviews?app_id=1", viewerSecret, "")
+	require.Equal(t, http.StatusOK, w.Code)
+	var reviews []model.Review
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&reviews))
+	require.Len(t, reviews, 1)
+	require.Nil(t, reviews[0].Original)
+	require.NotNil(t, db.keys[0].LastUsedAt)
+
+	require.Equal(t, http.StatusForbidden, do(http.MethodGet, "/api/reviews?app_id=2", viewerSecret, "").Code)
+	require.Equal(t, http.StatusForbidden, do(http.MethodPatch, "/api/reviews/r1", viewerSecret, `{"status":"resolved"}`).Code)
+
+	w = do(http.MethodGet, "/api/status", viewerSecret, "")
+	var status api.Status
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&status))
+	require.Len(t, status.Apps, 1)
+
+	// keys can be sent as bearer tokens too
+	req := httptest.NewRequest(http.MethodPatch, "/api/reviews/r1", strings.NewReader(`{"status":"resolved"}`))
+	req.Header.Set("Authorization", "Bearer "+triagerSecret)
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	// the configured admin key sees the original text and manages keys
+	w = do(http.MethodGet, "/api/reviews?app_id=1", "bootstrap", "")
+	reviews = nil
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&reviews))
+	require.NotNil(t, reviews[0].Original)
+
+	w = do(http.MethodPost, "/api/keys", "bootstrap", `{"name":"dashboard","role":"viewer","apps":["2"]}`)
+	require.Equal(t, http.StatusCreated, w.Code)
+	var created struct {
+		model.APIKey
+		Key string `json:"key"`
+	}
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&created))
+	require.Equal(t, []string{"2"}, created.Apps)
+	require.Equal(t, http.StatusOK, do(http.MethodGet, "/api/reviews?app_id=2", created.Key, "").Code)
+	require.NotContains(t, do(http.MethodGet, "/api/keys", "bootstrap", "").Body.String(), created.Key)
+
+	require.Equal(t, http.StatusNoContent, do(http.MethodDelete, "/api/keys/"+created.ID, "bootstrap", "").Code)
+	require.Equal(t, http.StatusUnauthorized, do(http.MethodGet, "/api/reviews?app_id=2", created.Key, "").Code)
+	require.Equal(t, http.StatusBadRequest, do(http.MethodPost, "/api/keys", "bootstrap", `{"name":"x","role":"owner"}`).Code)
+	require.Equal(t, http.StatusBadRequest, do(http.MethodPost, "/api/keys", "bootstrap", `{"name":"x","role":"feed","apps":["1","2"]}`).Code)
+	for _, apps := range []string{`["abc"]`, `[""]`, `["2","2"]`} {
+		w = do(http.MethodPost, "/api/keys", "bootstrap", `{"name":"x","role":"viewer","apps":`+apps+`}`)
+		require.Equal(t, http.StatusBadRequest, w.Code, apps)
+	}
+	require.Len(t, db.keys, 3)
+
+	// an admin limited to some apps can't mint keys for others
+	require.Equal(t, http.StatusForbidden, do(http.MethodPost, "/api/keys", scopedAdminSecret, `{"name":"x","role":"admin"}`).Code)
+}
+
+func TestRateLimit(t *testing.T) {
+	db := &mockPersistence{reviews: []model.Review{{ID: "r1", AppID: "1"}}}
+	mux := http.NewServeMux()
+	cfg := &config.Config{RequireAPIKeys: true, AdminAPIKey: "bootstrap", RateLimitReads: 2, RateLimitFetches: 1}
+	handlers := api.NewAPI(db, nil, nil, cfg)
+	require.NoError(t, handlers.RegisterHandlers(mux))
+
+	do := func(method, target, key, addr string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(method, target, nil)
+		req.RemoteAddr = addr
+		if key != "" {
+			req.Header.Set(api.APIKeyHeader, key)
+		}
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		return w
+	}
+
+	w := do(http.MethodGet, "/api/reviews?app_id=1", "bootstrap", "192.0.2.1:1234")
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Equal(t, "2", w.Header().Get("RateLimit-Limit"))
+	require.Equal(t, "1", w.Header().Get("RateLimit-Remaining"))
+	require.Equal(t, "2;w=60", w.Header().Get("RateLimit-Policy"))
+
+	// the budget follows the key, not the address
+	require.Equal(t, http.StatusOK, do(http.MethodGet, "/api/stats?app_id=1", "bootstrap", "192.0.2.2:1234").Code)
+	w = do(http.MethodGet, "/api/reviews?app_id=1", "bootstrap", "192.0.2.1:1234")
+	require.Equal(t, http.StatusTooManyRequests, w.Code)
+	require.Equal(t, "30", w.Header().Get("Retry-After"))
+	require.Equal(t, "0", w.Header().Get("RateLimit-Remaining"))
+
+	// usage can still be checked, and fetches have a budget of their own
+	w = do(http.MethodGet, "/api/rate_limit", "bootstrap", "192.0.2.1:1234")
+	require.Equal(t, http.StatusOK, w.Code)
+	var usage api.Usage
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&usage))
+	require.Equal(t, "key:admin_api_key", usage.Client)
+	require.Equal(t, 0, usage.Budgets["read"].Remaining)
+	require.Equal(t, api.BudgetUsage{Limit: 1, Remaining: 1, WindowSeconds: 60}, usage.Budgets["fetch"])
+
+	// failed authentication spends the address's read budget
+	require.Equal(t, http.StatusUnauthorized, do(http.MethodGet, "/api/reviews?app_id=1", "wrong", "192.0.2.3:1").Code)
+	require.Equal(t, http.StatusUnauthorized, do(http.MethodGet, "/api/reviews?app_id=1", "", "192.0.2.3:2").Code)
+	require.Equal(t, http.StatusTooManyRequests, do(http.MethodGet, "/api/reviews?app_id=1", "wrong", "192.0.2.3:3").Code)
+
+	// without keys, clients are told apart by address
+	cfg = &config.Config{RateLimitReads: 1}
+	handlers.SetConfig(cfg)
+	require.Equal(t, http.StatusOK, do(http.MethodGet, "/api/reviews?app_id=1", "", "192.0.2.4:1").Code)
+	require.Equal(t, http.StatusTooManyRequests, do(http.MethodGet, "/api/reviews?app_id=1", "", "192.0.2.4:2").Code)
+	require.Equal(t, http.StatusOK, do(http.MethodGet, "/api/reviews?app_id=1", "", "192.0.2.5:1").Code)
+
+	// a limit of 0 lifts it
+	handlers.SetConfig(&config.Config{})
+	w = do(http.MethodGet, "/api/reviews?app_id=1", "", "192.0.2.4:3")
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Empty(t, w.Header().Get("RateLimit-Limit"))
+}
+
+func TestV1(t *testing.T) {
+	db := &mockPersistence{
+		apps:    []model.App{{ID: "1"}},
+		reviews: []model.Review{{ID: "r1", AppID: "1"}, {ID: "r2", AppID: "1"}, {ID: "r3", AppID: "1"}},
+	}
+	feed := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("not a feed"))
+	}))
+	defer feed.Close()
+	client := appstore.NewClient(&config.Config{AppStoreReviewsURL: feed.URL + "/%s/%d", RecencyCutoff: time.Hour})
+	mux := http.NewServeMux()
+	require.NoError(t, api.NewAPI(db, client, nil, &config.Config{AdminAPIKey: adminKey}).RegisterHandlers(mux))
+
+	do := func(method, target string) *httptest.ResponseRecorder {
+		t.Helper()
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, asAdmin(httptest.NewRequest(method, target, nil)))
+		return w
+	}
+	problem := func(w *httptest.ResponseRecorder, status int, code string) api.Problem {
+		t.Helper()
+		require.Equal(t, status, w.Code)
+		require.Equal(t, "application/problem+json", w.Header().Get("Content-Type"))
+		var p api.Problem
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&p))
+		require.Equal(t, status, p.Status)
+		require.Equal(t, code, p.Code)
+		require.Equal(t, "urn:reviews-browser:problem:"+code, p.Type)
+		require.NotEmpty(t, p.Title)
+		return p
+	}
+
+	// lists are enveloped and paged
+	w := do(http.MethodGet, "/api/v1/apps/1/reviews?limit=2")
+	require.Equal(t, http.StatusOK, w.Code)
+	var page struct {
+		Data       []model.Review  `json:"data"`
+		Pagination *api.Pagination `json:"pagination"`
+		Meta       api.Meta        `json:"meta"`
+	}
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&page))
+	require.Len(t, page.Data, 2)
+	require.Equal(t, 3, page.Pagination.Total)
+	require.Equal(t, 2, *page.Pagination.NextOffset)
+
+	page.Data, page.Pagination = nil, nil
+	require.NoError(t, json.NewDecoder(do(http.MethodGet, "/api/v1/apps/1/reviews?limit=2&offset=2").Body).Decode(&page))
+	require.Equal(t, "r3", page.Data[0].ID)
+	require.Nil(t, page.Pagination.NextOffset)
+
+	problem(do(http.MethodGet, "/api/v1/apps/1/reviews?limit=0"), http.StatusBadRequest, api.CodeInvalidParameter)
+
+	// single resources are enveloped without pagination
+	w = do(http.MethodGet, "/api/v1/apps/1/stats")
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Contains(t, w.Body.String(), `"data":{`)
+	require.NotContains(t, w.Body.String(), "pagination")
+
+	// methods are enforced, and unknown routes are problems too
+	w = do(http.MethodDelete, "/api/v1/apps/1/reviews")
+	problem(w, http.StatusMethodNotAllowed, api.CodeMethodNotAllowed)
+	require.Equal(t, "GET, HEAD", w.Header().Get("Allow"))
+	require.Equal(t, "POST", do(http.MethodGet, "/api/v1/apps/1/poll").Header().Get("Allow"))
+	p := problem(do(http.MethodGet, "/api/v1/nope"), http.StatusNotFound, api.CodeNotFound)
+	require.Equal(t, "/api/v1/nope", p.Instance)
+
+	db.err = context.DeadlineExceeded
+	problem(do(http.MethodGet, "/api/v1/apps/1/reviews"), http.StatusInternalServerError, api.CodeStoreError)
+	db.err = nil
+
+	// fetches are queued as polls, so they need the queue
+	problem(do(http.MethodPost, "/api/v1/apps/1/fetch"), http.StatusServiceUnavailable, api.CodePollingUnavailable)
+
+	// the legacy fetch still fetches inside the request, and a failed fetch
+	// is an error rather than an empty success
+	w = do(http.MethodPost, "/api/reviews_by_app?app_id=1")
+	require.Equal(t, http.StatusBadGateway, w.Code)
+	require.Equal(t, "true", w.Header().Get("Deprecation"))
+
+	// and, like its successor, needs no key while keys aren't required
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/reviews_by_app?app_id=1", nil))
+	require.Equal(t, http.StatusBadGateway, w.Code)
+
+	// legacy routes answer as before, marked deprecated
+	w = do(http.MethodGet, "/api/reviews?app_id=1")
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Equal(t, "true", w.Header().Get("Deprecation"))
+	require.Equal(t, `</api/v1/apps/1/reviews>; rel="successor-version"`, w.Header().Get("Link"))
+	var reviews []model.Review
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&reviews))
+	require.Len(t, reviews, 3)
+
+	w = do(http.MethodGet, "/api/reviews")
+	require.Equal(t, http.StatusBadRequest, w.Code)
+	require.Equal(t, "Missing app_id\n", w.Body.String())
+	require.Empty(t, w.Header().Get("Link"))
+}
+
+func TestV1_Auth(t *testing.T) {
+	mux := http.NewServeMux()
+	require.NoError(t, api.NewAPI(&mockPersistence{}, nil, nil, &config.Config{RequireAPIKeys: true}).RegisterHandlers(mux))
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/status", nil))
+	require.Equal(t, http.StatusUnauthorized, w.Code)
+	require.NotEmpty(t, w.Header().Get("WWW-Authenticate"))
+	var p api.Problem
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&p))
+	require.Equal(t, api.CodeUnauthorized, p.Code)
+}
+
+func TestAuthorize_KeysOptional(t *testing.T) {
+	db := &mockPersistence{apps: []model.App{{ID: "1"}}, reviews: []model.Review{{ID: "r1", AppID: "1"}}}
+	mux := http.NewServeMux()
+	require.NoError(t, api.NewAPI(db, nil, nil, &config.Config{AdminAPIKey: adminKey}).RegisterHandlers(mux))
+
+	do := func(req *http.Request) int {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	// without a key a request may only do what a viewer may
+	require.Equal(t, http.StatusOK, do(httptest.NewRequest(http.MethodGet, "/api/v1/apps/1/reviews", nil)))
+	require.Equal(t, http.StatusUnauthorized, do(httptest.NewRequest(http.MethodPatch, "/api/v1/reviews/r1", strings.NewReader(`{"status": "resolved"}`))))
+	require.Equal(t, http.StatusUnauthorized, do(httptest.NewRequest(http.MethodGet, "/api/v1/keys", nil)))
+	require.Equal(t, http.StatusUnauthorized, do(httptest.NewRequest(http.MethodPost, "/api/v1/imports", strings.NewReader("id,content\n"))))
+	require.Empty(t, db.triage)
+
+	// a key that is sent must check out
+	bad := httptest.NewRequest(http.MethodGet, "/api/v1/apps/1/reviews", nil)
+	bad.Header.Set(api.APIKeyHeader, "wrong")
+	require.Equal(t, http.StatusUnauthorized, do(bad))
+
+	require.Equal(t, http.StatusOK, do(asAdmin(httptest.NewRequest(http.MethodPatch, "/api/v1/reviews/r1", strings.NewReader(`{"status": "resolved"}`)))))
+}
+
+func TestConditionalRequests(t *testing.T) {
+	modified := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	db := &mockPersistence{
+		reviews:  []model.Review{{ID: "r1", AppID: "1", Rating: 4, CreatedAt: time.Now()}},
+		versions: map[string]model.DataVersion{"1": {AppID: "1", Version: 3, ModifiedAt: modified}},
+	}
+	mux := http.NewServeMux()
+	require.NoError(t, api.NewAPI(db, nil, nil, &config.Config{RecencyCutoff: 48 * time.Hour, AdminAPIKey: adminKey}).RegisterHandlers(mux))
+	get := func(target string, header http.Header) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		req.Header = header
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		return w
+	}
+
+	w := get("/api/v1/apps/1/reviews", http.Header{})
+	require.Equal(t, http.StatusOK, w.Code)
+	tag := w.Header().Get("ETag")
+	require.Regexp(t, `^"[0-9a-f]{32}"$`, tag)
+	require.Equal(t, "Wed, 01 May 2024 12:00:00 GMT", w.Header().Get("Last-Modified"))
+	require.Equal(t, "private, no-cache", w.Header().Get("Cache-Control"))
+
+	w = get("/api/v1/apps/1/reviews", http.Header{"If-None-Match": {`"other", ` + tag}})
+	require.Equal(t, http.StatusNotModified, w.Code)
+	require.Empty(t, w.Body.String())
+	require.Equal(t, tag, w.Header().Get("ETag"))
+
+	// the tag depends on the query, and If-None-Match wins over If-Modified-Since
+	w = get("/api/v1/apps/1/reviews?limit=10", http.Header{"If-None-Match": {tag}, "If-Modified-Since": {"Thu, 02 May 2024 00:00:00 GMT"}})
+	require.Equal(t, http.StatusOK, w.Code)
+
+	w = get("/api/v1/apps/1/stats", http.Header{"If-Modified-Since": {"Wed, 01 May 2024 12:00:00 GMT"}})
+	require.Equal(t, http.StatusNotModified, w.Code)
+	w = get("/api/v1/apps/1/stats", http.Header{"If-Modified-Since": {"Wed, 01 May 2024 11:59:59 GMT"}})
+	require.Equal(t, http.StatusOK, w.Code)
+
+	// a change to the app's reviews gives them a new tag
+	req := httptest.NewRequest(http.MethodPatch, "/api/v1/reviews/r1", strings.NewReader(`{"status": "resolved"}`))
+	mux.ServeHTTP(httptest.NewRecorder(), asAdmin(req))
+	w = get("/api/v1/apps/1/reviews", http.Header{"If-None-Match": {tag}})
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NotEqual(t, tag, w.Header().Get("ETag"))
+
+	// legacy routes have their own tags
+	w = get("/api/reviews?app_id=1", http.Header{})
+	require.NotEmpty(t, w.Header().Get("ETag"))
+	w = get("/api/reviews?app_id=1", http.Header{"If-None-Match": {w.Header().Get("ETag")}})
+	require.Equal(t, http.StatusNotModified, w.Code)
+
+	// without a current version there is nothing to validate against
+	w = get("/api/v1/apps/2/reviews", http.Header{"If-None-Match": {"*"}})
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Empty(t, w.Header().Get("ETag"))
+	db.versions["1"] = model.DataVersion{AppID: "1", Version: 5, Oldest: time.Now().Add(-49 * time.Hour)}
+	w = get("/api/v1/apps/1/reviews", http.Header{})
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Empty(t, w.Header().Get("ETag"))
+}
+
+func TestCompress(t *testing.T) {
+	mux := http.NewServeMux()
+	require.NoError(t, api.NewAPI(&mockPersistence{
+		reviews:  []model.Review{{ID: "r1", AppID: "1", Rating: 4, Content: strings.Repeat("great app ", 50)}},
+		versions: map[string]model.DataVersion{"1": {AppID: "1", Version: 1}},
+	}, nil, nil, &config.Config{}).RegisterHandlers(mux))
+	mux.HandleFunc("GET /encoded", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		w.Header().Set("Content-Encoding", "identity")
+		io.WriteString(w, "as is")
+	})
+	handler := api.Compress(mux)
+	get := func(target, accept string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		req.Header.Set("Accept-Encoding", accept)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w
+	}
+	plain := get("/api/v1/apps/1/reviews", "")
+	require.Empty(t, plain.Header().Get("Content-Encoding"))
+	require.Equal(t, "Accept-Encoding", plain.Header().Get("Vary"))
+
+	for accept, want := range map[string]string{
+		"gzip, deflate, br":      "br",
+		"gzip;q=1, br;q=0.5":     "gzip",
+		"x-gzip":                 "gzip",
+		"*":                      "br",
+		"br;q=0, *;q=0.1":        "gzip",
+		"deflate, identity":      "",
+		"gzip;q=0, br;q=0":       "",
+		"GZIP":                   "gzip",
+		"br;q=0.8, gzip;q=0.800": "br",
+	} {
+		w := get("/api/v1/apps/1/reviews", accept)
+		require.Equal(t, http.StatusOK, w.Code)
+		require.Equal(t, want, w.Header().Get("Content-Encoding"), accept)
+
+		var body io.Reader = w.Body
+		switch want {
+		case "br":
+			body = brotli.NewReader(w.Body)
+		case "gzip":
+			zr, err := gzip.NewReader(w.Body)
+			require.NoError(t, err)
+			body = zr
+		}
+		decoded, err := io.ReadAll(body)
+		require.NoError(t, err)
+		require.Equal(t, plain.Body.String(), string(decoded), accept)
+		if want != "" {
+			require.Less(t, w.Body.Len(), plain.Body.Len())
+			// each coding is a different representation, with its own tag
+			require.NotEqual(t, plain.Header().Get("ETag"), w.Header().Get("ETag"))
+		}
+	}
+
+	w := get("/encoded", "br")
+	require.Equal(t, "identity", w.Header().Get("Content-Encoding"))
+	require.Equal(t, "as is", w.Body.String())
+}
+
+func TestExportHandler(t *testing.T) {
+	id, secret, hash := auth.NewKey()
+	viewer := model.APIKey{ID: id, Name: "support", Role: model.RoleViewer, Apps: []string{"1"}, Hash: hash}
+	db := &mockPersistence{
+		keys: []model.APIKey{viewer},
+		reviews: []model.Review{
+			{ID: "r1", AppID: "1", Title: "=1+1", Rating: 5, Language: "en", Original: &model.OriginalText{Title: "=1+1"}},
+			{ID: "r2", AppID: "2", Title: "Meh", Rating: 2, Language: "de"},
+			{ID: "r3", AppID: "1", Title: "Spam", Rating: 1, Language: "en", SuspectedSpam: true},
+		},
+	}
+	mux := http.NewServeMux()
+	require.NoError(t, api.NewAPI(db, nil, nil, &config.Config{AdminAPIKey: "admin"}).RegisterHandlers(mux))
+	get := func(target, key string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		if key != "" {
+			req.Header.Set(api.APIKeyHeader, key)
+		}
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		return w
+	}
+
+	w := get("/api/v1/reviews/export?app_id=1&app_id=2&columns=id,app_id,rating&exclude_spam=true", "admin")
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Equal(t, "text/csv; charset=utf-8", w.Header().Get("Content-Type"))
+	require.Regexp(t, `^attachment; filename="reviews-\d{8}-\d{6}\.csv"$`, w.Header().Get("Content-Disposition"))
+	require.Equal(t, "id,app_id,rating\nr1,1,5\nr2,2,2\n", w.Body.String())
+
+	w = get("/api/v1/reviews/export?format=excel&columns=id,title,original_title&language=en", "admin")
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Equal(t, "\uFEFFid,title,original_title\r\nr1,'=1+1,'=1+1\r\nr3,Spam,\r\n", w.Body.String())
+
+	w = get("/api/v1/reviews/export?format=ndjson&columns=id", "admin")
+	require.Equal(t, "application/x-ndjson", w.Header().Get("Content-Type"))
+	require.Equal(t, "{\"id\":\"r1\"}\n{\"id\":\"r2\"}\n{\"id\":\"r3\"}\n", w.Body.String())
+
+	// a scoped key exports its own apps, and may not name others or see the
+	// original text
+	w = get("/api/v1/reviews/export?format=json&columns=id", secret)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.JSONEq(t, `[{"id": "r1"}, {"id": "r3"}]`, w.Body.String())
+	require.Equal(t, http.StatusForbidden, get("/api/v1/reviews/export?app_id=2", secret).Code)
+	require.Equal(t, http.StatusBadRequest, get("/api/v1/reviews/export?columns=original_title", secret).Code)
+
+	w = get("/api/v1/reviews/export?columns=bogus", "admin")
+	require.Equal(t, http.StatusBadRequest, w.Code)
+	require.Empty(t, w.Header().Get("Content-Disposition"))
+	require.Equal(t, http.StatusBadRequest, get("/api/v1/reviews/export?format=json&bom=true", "admin").Code)
+
+	// a failure before anything is written is reported as usual
+	db.err = errors.New("disk gone")
+	w = get("/api/reviews/export?app_id=1", "admin")
+	require.Equal(t, http.StatusInternalServerError, w.Code)
+	require.Empty(t, w.Header().Get("Content-Disposition"))
+	require.NotEmpty(t, w.Header().Get("Deprecation"))
+}
+
+func TestImportHandler(t *testing.T) {
+	id, secret, hash := auth.NewKey()
+	db := &mockPersistence{
+		keys:    []model.APIKey{{ID: id, Name: "lead", Role: model.RoleAdmin, Apps: []string{"1"}, Hash: hash}},
+		reviews: []model.Review{{ID: "r1", AppID: "1", Rating: 3, CreatedAt: time.Now()}},
+	}
+	mux := http.NewServeMux()
+	require.NoError(t, api.NewAPI(db, nil, ingest.NewPipeline(db, nil), &config.Config{AdminAPIKey: "admin", RecencyCutoff: 48 * time.Hour}).RegisterHandlers(mux))
+	post := func(target, key, contentType, body string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodPost, target, strings.NewReader(body))
+		req.Header.Set(api.APIKeyHeader, key)
+		req.Header.Set("Content-Type", contentType)
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		return w
+	}
+	created := time.Now().UTC().Format(time.RFC3339)
+	file := "id,stars,date,content\n" +
+		"r1,3," + created + ",Seen before\n" +
+		"r2,5," + created + ",New\n" +
+		"r3,9," + created + ",Bad rating\n"
+	var report struct {
+		Data struct {
+			DryRun   bool `json:"dry_run"`
+			Valid    int  `json:"valid"`
+			New      int  `json:"new"`
+			Problems []struct {
+				Line   int    `json:"line"`
+				Reason string `json:"reason"`
+			} `json:"problems"`
+		} `json:"data"`
+	}
+
+	w := post("/api/v1/imports?app_id=1&map=rating=stars,created_at=date&dry_run=true", "admin", "text/csv", file)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &report))
+	require.True(t, report.Data.DryRun)
+	require.Equal(t, 2, report.Data.Valid)
+	require.Zero(t, report.Data.New)
+	require.Len(t, report.Data.Problems, 1)
+	require.Equal(t, 4, report.Data.Problems[0].Line)
+	require.Len(t, db.reviews, 1)
+
+	// reviews already stored are skipped
+	w = post("/api/v1/imports?app_id=1&map=rating=stars,created_at=date", "admin", "text/csv", file)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &report))
+	require.Equal(t, 1, report.Data.New)
+	require.Len(t, db.reviews, 2)
+	require.Equal(t, "1", db.reviews[1].AppID)
+
+	w = post("/api/v1/imports", "admin", "application/x-ndjson", `{"id": "r9", "app_id": "2", "rating": 4, "created_at": "`+created+`"}`+"\n")
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &report))
+	require.Equal(t, 1, report.Data.New)
+
+	require.Equal(t, http.StatusBadRequest, post("/api/v1/imports?app_id=1", "admin", "text/csv", file).Code, "rating has no column")
+	require.Equal(t, http.StatusBadRequest, post("/api/v1/imports?map=stars", "admin", "text/csv", file).Code)
+	require.Equal(t, http.StatusForbidden, post("/api/v1/imports?app_id=1", secret, "text/csv", file).Code)
+}
+
+func TestFeedHandler(t *testing.T) {
+	id, secret, hash := auth.NewKey()
+	feedID, feedSecret, feedHash := auth.NewKey()
+	created := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	db := &mockPersistence{
+		keys: []model.APIKey{
+			{ID: id, Name: "support", Role: model.RoleViewer, Apps: []string{"1"}, Hash: hash},
+			{ID: feedID, Name: "chat", Role: model.RoleFeed, Apps: []string{"1"}, Hash: feedHash},
+		},
+		reviews: []model.Review{
+			{ID: "r3", AppID: "1", Author: "Ann", Title: "Crashes", Content: "Crashes <always> & forever", Rating: 1, CreatedAt: created},
+			{ID: "r2", AppID: "1", Title: "Buy now", Content: "spam", Rating: 1, CreatedAt: created.Add(-time.Hour), SuspectedSpam: true},
+			{ID: "r1", AppID: "1", Author: "Bob", Title: "Love it", Content: "Great", Rating: 5, CreatedAt: created.Add(-2 * time.Hour)},
+		},
+		versions: map[string]model.DataVersion{"1": {AppID: "1", Version: 1, ModifiedAt: created}},
+	}
+	mux := http.NewServeMux()
+	require.NoError(t, api.NewAPI(db, nil, nil, &config.Config{RequireAPIKeys: true, RecencyCutoff: 48 * time.Hour}).RegisterHandlers(mux))
+	get := func(target string, header http.Header) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		req.Header = header
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		return w
+	}
+
+	w := get("/feeds/apps/1.atom?key="+url.QueryEscape(feedSecret), http.Header{})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	require.Equal(t, "application/atom+xml; charset=utf-8", w.Header().Get("Content-Type"))
+	var atom struct {
+		XMLName xml.Name `xml:"http://www.w3.org/2005/Atom feed"`
+		Updated string   `xml:"updated"`
+		Links   []struct {
+			Rel  string `xml:"rel,attr"`
+			Href string `xml:"href,attr"`
+		} `xml:"link"`
+		Entries []struct {
+			ID      string `xml:"id"`
+			Title   string `xml:"title"`
+			Author  string `xml:"author>name"`
+			Content string `xml:"content"`
+		} `xml:"entry"`
+	}
+	require.NoError(t, xml.Unmarshal(w.Body.Bytes(), &atom))
+	require.Equal(t, "2024-05-01T12:00:00Z", atom.Updated)
+	require.Equal(t, "http://example.com/feeds/apps/1.atom", atom.Links[0].Href, "the key is left out of the self link")
+	require.Len(t, atom.Entries, 2, "suspected spam is left out")
+	require.Equal(t, "urn:reviews-browser:review:1:r3", atom.Entries[0].ID)
+	require.Equal(t, "★☆☆☆☆ Crashes", atom.Entries[0].Title)
+	require.Equal(t, "Ann", atom.Entries[0].Author)
+	require.Equal(t, "Crashes <always> & forever", atom.Entries[0].Content)
+
+	// max_rating narrows the feed to poor reviews
+	header := http.Header{}
+	header.Set(api.APIKeyHeader, secret)
+	w = get("/feeds/apps/1.rss?max_rating=2", header)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Equal(t, "application/rss+xml; charset=utf-8", w.Header().Get("Content-Type"))
+	var rss struct {
+		Version string `xml:"version,attr"`
+		Channel struct {
+			Title string `xml:"title"`
+			Items []struct {
+				Title   string `xml:"title"`
+				Creator string `xml:"http://purl.org/dc/elements/1.1/ creator"`
+				GUID    string `xml:"guid"`
+				PubDate string `xml:"pubDate"`
+			} `xml:"item"`
+		} `xml:"channel"`
+	}
+	require.NoError(t, xml.Unmarshal(w.Body.Bytes(), &rss))
+	require.Equal(t, "2.0", rss.Version)
+	require.Equal(t, "Reviews of app 1 rated 2 stars or less", rss.Channel.Title)
+	require.Len(t, rss.Channel.Items, 1)
+	require.Equal(t, "Ann", rss.Channel.Items[0].Creator)
+	require.Equal(t, "Wed, 01 May 2024 12:00:00 +0000", rss.Channel.Items[0].PubDate)
+
+	// feeds honour conditional requests
+	tag := w.Header().Get("ETag")
+	require.NotEmpty(t, tag)
+	header.Set("If-None-Match", tag)
+	w = get("/feeds/apps/1.rss?max_rating=2", header)
+	require.Equal(t, http.StatusNotModified, w.Code)
+	header.Del("If-None-Match")
+
+	require.Equal(t, http.StatusBadRequest, get("/feeds/apps/1.atom?max_rating=0", header).Code)
+	require.Equal(t, http.StatusNotFound, get("/feeds/apps/1.json", header).Code)
+	require.Equal(t, http.StatusUnauthorized, get("/feeds/apps/1.atom", http.Header{}).Code)
+	require.Equal(t, http.StatusForbidden, get("/feeds/apps/2.atom", header).Code)
+
+	// only feed keys may be put in feed URLs, and they read nothing else
+	require.Equal(t, http.StatusForbidden, get("/feeds/apps/1.atom?key="+url.QueryEscape(secret), http.Header{}).Code)
+	require.Equal(t, http.StatusForbidden, get("/feeds/apps/2.atom?key="+url.QueryEscape(feedSecret), http.Header{}).Code)
+	feedHeader := http.Header{}
+	feedHeader.Set(api.APIKeyHeader, feedSecret)
+	require.Equal(t, http.StatusOK, get("/feeds/apps/1.rss", feedHeader).Code)
+	require.Equal(t, http.StatusForbidden, get("/api/v1/apps/1/reviews", feedHeader).Code)
+}
+
+func TestCompareHandler(t *testing.T) {
+	id, secret, hash := auth.NewKey()
+	now := time.Now()
+	db := &mockPersistence{
+		keys: []model.APIKey{{ID: id, Name: "team", Role: model.RoleViewer, Apps: []string{"1"}, Hash: hash}},
+		reviews: []model.Review{
+			{ID: "r1", AppID: "1", Rating: 5, Content: "Dark mode", CreatedAt: now.Add(-time.Hour)},
+			{ID: "r2", AppID: "2", Rating: 2, Content: "Login loop", CreatedAt: now.Add(-time.Hour)},
+			{ID: "r3", AppID: "2", Rating: 4, Content: "Login loop fixed", CreatedAt: now.Add(-2 * time.Hour)},
+			{ID: "r4", AppID: "2", Rating: 1, Content: "Old", CreatedAt: now.Add(-30 * time.Hour)},
+		},
+	}
+	mux := http.NewServeMux()
+	require.NoError(t, api.NewAPI(db, nil, nil, &config.Config{AdminAPIKey: "admin", RecencyCutoff: 48 * time.Hour}).RegisterHandlers(mux))
+	get := func(target, key string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		req.Header.Set(api.APIKeyHeader, key)
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		return w
+	}
+
+	w := get("/api/v1/compare?app_id=2&app_id=1&app_id=2&window_hrs=24", "admin")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var body struct {
+		Data compare.Report `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	require.Equal(t, 24, body.Data.WindowHrs)
+	require.Len(t, body.Data.Apps, 2, "repeated apps are compared once")
+	require.Equal(t, "2", body.Data.Apps[0].AppID)
+	require.Equal(t, 2, body.Data.Apps[0].Total)
+	require.Equal(t, 3.0, body.Data.Apps[0].AverageRating)
+	require.Equal(t, compare.Sentiment{Positive: 1, Negative: 1}, body.Data.Apps[0].Sentiment)
+	require.Equal(t, "login loop", body.Data.Apps[0].Bigrams[0].Term)
+	require.Equal(t, 1, body.Data.Apps[1].Total)
+
+	// the window defaults to the recency cutoff
+	w = get("/api/compare?app_id=2", "admin")
+	require.Equal(t, http.StatusOK, w.Code)
+	var report compare.Report
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &report))
+	require.Equal(t, 48, report.WindowHrs)
+	require.Equal(t, 3, report.Apps[0].Total)
+
+	require.Equal(t, http.StatusBadRequest, get("/api/v1/compare", "admin").Code)
+	require.Equal(t, http.StatusBadRequest, get("/api/compare?app_id=1&window_hrs=0", "admin").Code)
+	require.Equal(t, http.StatusBadRequest, get("/api/compare?app_id=1&app_id=2&app_id=3&app_id=4&app_id=5&app_id=6&app_id=7&app_id=8&app_id=9&app_id=10&app_id=11", "admin").Code)
+	require.Equal(t, http.StatusOK, get("/api/v1/compare?app_id=1", secret).Code)
+	require.Equal(t, http.StatusForbidden, get("/api/v1/compare?app_id=1&app_id=2", secret).Code)
+}
+
+func TestAppsHandler(t *testing.T) {
+	id, secret, hash := auth.NewKey()
+	refreshed := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	db := &mockPersistence{
+		keys: []model.APIKey{{ID: id, Name: "team", Role: model.RoleViewer, Apps: []string{"1"}, Hash: hash}},
+		apps: []model.App{
+			{ID: "1", PollEverySeconds: 60, Metadata: model.AppMetadata{Name: "Example", Developer: "Example Inc.", Version: "2.1", AverageRating: 4.6, RatingCount: 1200, RefreshedAt: refreshed}},
+			{ID: "2", PollEverySeconds: 300},
+		},
+	}
+	mux := http.NewServeMux()
+	require.NoError(t, api.NewAPI(db, nil, nil, &config.Config{AdminAPIKey: "admin"}).RegisterHandlers(mux))
+	get := func(target, key string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		req.Header.Set(api.APIKeyHeader, key)
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		return w
+	}
+
+	w := get("/api/v1/apps", "admin")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var list struct {
+		Data []api.AppInfo `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &list))
+	require.Equal(t, []api.AppInfo{
+		{AppID: "1", PollEverySeconds: 60, Metadata: &db.apps[0].Metadata},
+		{AppID: "2", PollEverySeconds: 300},
+	}, list.Data, "apps not looked up yet have no metadata")
+
+	require.NoError(t, json.Unmarshal(get("/api/v1/apps", secret).Body.Bytes(), &list))
+	require.Len(t, list.Data, 1, "scoped keys only see their apps")
+
+	w = get("/api/v1/apps/1", secret)
+	require.Equal(t, http.StatusOK, w.Code)
+	var one struct {
+		Data api.AppInfo `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &one))
+	require.Equal(t, "Example", one.Data.Metadata.Name)
+	require.Equal(t, 4.6, one.Data.Metadata.AverageRating)
+
+	require.Equal(t, http.StatusForbidden, get("/api/v1/apps/2", secret).Code)
+	require.Equal(t, http.StatusNotFound, get("/api/v1/apps/3", "admin").Code)
+}
@@ -0,0 +1,60 @@
+package api
+
+import (
+	"net/http"
+	"slices"
+
+	"github.com/furqanmk/reviews-browser/internal/model"
+)
+
+// AppInfo describes a tracked app: how often it is polled and how the App
+// Store lists it. Metadata is left out until the app is first looked up.
+type AppInfo struct {
+	AppID            string             `json:"app_id"`
+	PollEverySeconds int                `json:"poll_every_seconds"`
+	Metadata         *model.AppMetadata `json:"metadata,omitempty"`
+}
+
+func appInfo(app model.App) AppInfo {
+	info := AppInfo{AppID: app.ID, PollEverySeconds: app.PollEverySeconds}
+	if !app.Metadata.RefreshedAt.IsZero() {
+		info.Metadata = &app.Metadata
+	}
+	return info
+}
+
+// AppsHandler lists the tracked apps the API key may see, with their cached
+// App Store details.
+func (a *API) AppsHandler(w http.ResponseWriter, r *http.Request) {
+	apps, err := a.db.GetApps(r.Context())
+	if err != nil {
+		storeError(w, r, err)
+		return
+	}
+
+	infos := make([]AppInfo, 0, len(apps))
+	for _, app := range apps {
+		if allowsApp(r, app.ID) {
+			infos = append(infos, appInfo(app))
+		}
+	}
+	respondList(w, r, infos)
+}
+
+// AppHandler describes one tracked app, with its cached App Store details.
+func (a *API) AppHandler(w http.ResponseWriter, r *http.Request) {
+	appID := r.PathValue("app_id")
+	spanApp(r, appID)
+
+	apps, err := a.db.GetApps(r.Context())
+	if err != nil {
+		storeError(w, r, err)
+		return
+	}
+	i := slices.IndexFunc(apps, func(app model.App) bool { return app.ID == appID })
+	if i < 0 {
+		fail(w, r, http.StatusNotFound, CodeNotFound, "App not tracked")
+		return
+	}
+	respond(w, r, http.StatusOK, appInfo(apps[i]))
+}
@@ -0,0 +1,148 @@
+package api
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/furqanmk/reviews-browser/internal/auth"
+	"github.com/furqanmk/reviews-browser/internal/database"
+	"github.com/furqanmk/reviews-browser/internal/logging"
+	"github.com/furqanmk/reviews-browser/internal/model"
+)
+
+// APIKeyHeader carries a request's API key. "Authorization: Bearer <key>" is
+// accepted too.
+const APIKeyHeader = "X-API-Key"
+
+// touchEvery is how stale a key's last use may get before it's rewritten, so
+// busy keys don't rewrite the keys table on every request.
+const touchEvery = time.Minute
+
+type keyContextKey struct{}
+
+// keyInURLContextKey marks a request whose key was given in its URL.
+type keyInURLContextKey struct{}
+
+// authorize only lets a request through to next if it carries an API key with
+// at least the given role, allowed to see the app named by its app_id query
+// parameter or path wildcard. When API keys aren't required, requests without
+// one are let through to routes a viewer may use, but never to the triager and
+// admin routes that change data or call out to the App Store. A key that
+// doesn't check out is always refused.
+func (a *API) authorize(role string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		key, presented, err := a.authenticate(r)
+		if err != nil {
+			storeError(w, r, err)
+			return
+		}
+		if key == nil {
+			if !presented && !a.cfg.Load().RequireAPIKeys && auth.Allows(model.RoleViewer, role) {
+				next(w, r)
+				return
+			}
+			// failed attempts spend the address's read budget, so keys can't
+			// be guessed at full speed
+			if !a.allow(w, r, budgetRead, "ip:"+clientIP(r)) {
+				return
+			}
+			w.Header().Set("WWW-Authenticate", `Bearer realm="reviews-browser"`)
+			fail(w, r, http.StatusUnauthorized, CodeUnauthorized, "Missing or invalid API key")
+			return
+		}
+
+		if !auth.Allows(key.Role, role) {
+			fail(w, r, http.StatusForbidden, CodeForbidden, "API key lacks the "+role+" role")
+			return
+		}
+		// URLs end up in logs and reader configurations, so only keys that can
+		// do no more than read a feed may be put in them
+		if inURL, _ := ctx.Value(keyInURLContextKey{}).(bool); inURL && key.Role != model.RoleFeed {
+			fail(w, r, http.StatusForbidden, CodeForbidden, "Only feed keys may be given in a URL")
+			return
+		}
+		for _, appID := range []string{r.URL.Query().Get("app_id"), r.PathValue("app_id")} {
+			if appID != "" && !key.AllowsApp(appID) {
+				fail(w, r, http.StatusForbidden, CodeForbidden, "API key may not access this app")
+				return
+			}
+		}
+
+		ctx = context.WithValue(ctx, keyContextKey{}, key)
+		ctx = logging.With(ctx, "api_key", key.ID)
+		next(w, r.WithContext(ctx))
+	}
+}
+
+// unscoped only lets a request through to next if its API key, if any, may
+// see every app. Key management needs it, so that a key scoped to some apps
+// can't mint one for others.
+func unscoped(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if key := requestKey(r); key != nil && len(key.Apps) > 0 {
+			fail(w, r, http.StatusForbidden, CodeForbidden, "API key is limited to some apps")
+			return
+		}
+		next(w, r)
+	}
+}
+
+// authenticate finds the API key a request carries. It also reports whether
+// the request presented a key at all, valid or not.
+func (a *API) authenticate(r *http.Request) (_ *model.APIKey, presented bool, _ error) {
+	ctx := r.Context()
+	presented = true
+	secret := r.Header.Get(APIKeyHeader)
+	if secret == "" {
+		secret, presented = strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !presented {
+			return nil, false, nil
+		}
+	}
+
+	if adminKey := a.cfg.Load().AdminAPIKey; adminKey != "" &&
+		subtle.ConstantTimeCompare([]byte(secret), []byte(adminKey)) == 1 {
+		return &model.APIKey{ID: "admin_api_key", Name: "admin_api_key", Role: model.RoleAdmin}, true, nil
+	}
+
+	id := auth.KeyID(secret)
+	if id == "" {
+		return nil, true, nil
+	}
+	key, err := a.db.GetAPIKey(ctx, id)
+	if errors.Is(err, database.ErrNotFound) {
+		return nil, true, nil
+	}
+	if err != nil {
+		return nil, true, err
+	}
+	if !auth.Matches(secret, key.Hash) {
+		return nil, true, nil
+	}
+
+	if now := time.Now(); key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) >= touchEvery {
+		if err := a.db.TouchAPIKey(ctx, key.ID, now); err != nil {
+			logger.WarnContext(ctx, "recording key use failed", "api_key", key.ID, "error", err)
+		}
+	}
+	return &key, true, nil
+}
+
+// requestKey returns the API key a request was authorized with, or nil if it
+// had none.
+func requestKey(r *http.Request) *model.APIKey {
+	key, _ := r.Context().Value(keyContextKey{}).(*model.APIKey)
+	return key
+}
+
+// allowsApp reports whether the request may see an app's data. Requests let
+// through without a key may see every app.
+func allowsApp(r *http.Request, appID string) bool {
+	key := requestKey(r)
+	return key == nil || key.AllowsApp(appID)
+}
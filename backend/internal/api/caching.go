@@ -0,0 +1,94 @@
+package api
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// validators identify a response for conditional requests.
+type validators struct {
+	etag     string
+	modified time.Time
+}
+
+// notModified reads the data version of an app's reviews and sets validators
+// for a response built from them. It reports whether it has responded: with
+// 304 if the client already has the response, or with an error. A version
+// that can no longer vouch for what the store returns gets no validators.
+func (a *API) notModified(w http.ResponseWriter, r *http.Request, appID string) bool {
+	version, err := a.db.GetDataVersion(r.Context(), appID)
+	if err != nil {
+		storeError(w, r, err)
+		return true
+	}
+	cutoff := a.cfg.Load().RecencyCutoff
+	if !version.Current(cutoff, time.Now()) {
+		return false
+	}
+	return conditional(w, r, validators{
+		etag:     etag(r, strconv.FormatInt(version.Version, 10), cutoff.String()),
+		modified: version.ModifiedAt,
+	})
+}
+
+// etag makes a strong ETag for the response to r from parts, which describe
+// the data it is built from. Everything else that shapes the response goes in
+// too: the path and query, whether the caller may see original text, and the
+// content coding, so each encoding of a response has its own tag.
+func etag(r *http.Request, parts ...string) string {
+	h := sha256.New()
+	for _, part := range append([]string{r.URL.Path, r.URL.Query().Encode(), strconv.FormatBool(seesOriginal(r)), contentCoding(r)}, parts...) {
+		h.Write([]byte(part))
+		h.Write([]byte{0})
+	}
+	return `"` + hex.EncodeToString(h.Sum(nil)[:16]) + `"`
+}
+
+// conditional sets a response's validators and reports whether the request's
+// preconditions show the client already has it, in which case it has been
+// answered with 304 Not Modified. If-None-Match takes precedence over
+// If-Modified-Since.
+func conditional(w http.ResponseWriter, r *http.Request, v validators) bool {
+	header := w.Header()
+	header.Set("ETag", v.etag)
+	// callers authenticate, so only their own caches may keep responses, and
+	// must check back before reusing one
+	header.Set("Cache-Control", "private, no-cache")
+	if !v.modified.IsZero() {
+		header.Set("Last-Modified", v.modified.UTC().Format(http.TimeFormat))
+	}
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return false
+	}
+
+	if match := r.Header.Get("If-None-Match"); match != "" {
+		if !etagMatches(match, v.etag) {
+			return false
+		}
+	} else if since, err := http.ParseTime(r.Header.Get("If-Modified-Since")); err != nil ||
+		v.modified.IsZero() || v.modified.Truncate(time.Second).After(since) {
+		return false
+	}
+
+	httpNotModified.WithLabelValues(r.Pattern).Inc()
+	w.WriteHeader(http.StatusNotModified)
+	return true
+}
+
+// etagMatches compares an If-None-Match header against an ETag, weakly as
+// RFC 9110 has it, so a W/ prefix is ignored.
+func etagMatches(match, etag string) bool {
+	if strings.TrimSpace(match) == "*" {
+		return true
+	}
+	for _, candidate := range strings.Split(match, ",") {
+		if strings.TrimPrefix(strings.TrimSpace(candidate), "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
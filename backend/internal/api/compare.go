@@ -0,0 +1,68 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/furqanmk/reviews-browser/internal/compare"
+)
+
+// maxCompared is the most apps one comparison may name.
+const maxCompared = 10
+
+// CompareHandler puts the recent reviews of the apps named by repeated app_id
+// parameters side by side: volume, average rating, rating distribution,
+// sentiment and top terms over the last window_hrs hours, which default to the
+// whole recency cutoff. The store is read once for all of them.
+func (a *API) CompareHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	query := r.URL.Query()
+
+	var appIDs []string
+	for _, appID := range query["app_id"] {
+		if appID != "" && !slices.Contains(appIDs, appID) {
+			appIDs = append(appIDs, appID)
+		}
+	}
+	if len(appIDs) == 0 {
+		fail(w, r, http.StatusBadRequest, CodeMissingParameter, "Missing app_id")
+		return
+	}
+	if len(appIDs) > maxCompared {
+		fail(w, r, http.StatusBadRequest, CodeInvalidParameter, fmt.Sprintf("At most %d apps can be compared", maxCompared))
+		return
+	}
+	for _, appID := range appIDs {
+		if !allowsApp(r, appID) {
+			fail(w, r, http.StatusForbidden, CodeForbidden, "API key may not access app "+appID)
+			return
+		}
+	}
+
+	cutoff := a.cfg.Load().RecencyCutoff
+	windowHrs, err := intParam(query.Get("window_hrs"), max(int(cutoff/time.Hour), 1))
+	if err != nil || windowHrs <= 0 {
+		fail(w, r, http.StatusBadRequest, CodeInvalidParameter, "Invalid window_hrs")
+		return
+	}
+	limit, err := intParam(query.Get("limit"), 20)
+	if err != nil || limit <= 0 {
+		fail(w, r, http.StatusBadRequest, CodeInvalidParameter, "Invalid limit")
+		return
+	}
+
+	report, err := compare.Compare(ctx, a.db, appIDs, time.Now().UTC(), compare.Options{
+		Window:      time.Duration(windowHrs) * time.Hour,
+		Limit:       limit,
+		MinCount:    2,
+		ExcludeSpam: query.Get("exclude_spam") == "true",
+	})
+	if err != nil {
+		storeError(w, r, err)
+		return
+	}
+
+	respond(w, r, http.StatusOK, report)
+}
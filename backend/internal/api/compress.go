@@ -0,0 +1,166 @@
+package api
+
+import (
+	"compress/gzip"
+	"context"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/andybalholm/brotli"
+)
+
+// Content codings Compress can apply.
+const (
+	codingBrotli = "br"
+	codingGzip   = "gzip"
+)
+
+// brotliLevel trades some ratio for speed, as responses are compressed as
+// they are served.
+const brotliLevel = 4
+
+// encoder is a compressing writer that can be reused for another response.
+type encoder interface {
+	io.WriteCloser
+	Flush() error
+	Reset(w io.Writer)
+}
+
+var encoders = map[string]*sync.Pool{
+	codingBrotli: {New: func() any { return brotli.NewWriterLevel(io.Discard, brotliLevel) }},
+	codingGzip:   {New: func() any { return gzip.NewWriter(io.Discard) }},
+}
+
+type codingContextKey struct{}
+
+// Compress compresses text and JSON responses with brotli or gzip, whichever
+// the client's Accept-Encoding prefers, and brotli when it likes both as much.
+// Responses a handler has already encoded, such as /metrics, are left alone.
+// The chosen coding is carried by the request context, so handlers can give
+// each encoding of a response its own ETag.
+func Compress(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Accept-Encoding")
+		coding := negotiate(r.Header.Get("Accept-Encoding"))
+		if coding == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		cw := &compressWriter{ResponseWriter: w, coding: coding}
+		defer cw.close()
+		next.ServeHTTP(cw, r.WithContext(context.WithValue(r.Context(), codingContextKey{}, coding)))
+	})
+}
+
+// contentCoding returns the coding Compress will apply to the response to r,
+// or "" for none.
+func contentCoding(r *http.Request) string {
+	coding, _ := r.Context().Value(codingContextKey{}).(string)
+	return coding
+}
+
+// negotiate picks the content coding to use from an Accept-Encoding header,
+// or "" if the client accepts neither brotli nor gzip.
+func negotiate(accept string) string {
+	q := map[string]float64{}
+	for _, part := range strings.Split(accept, ",") {
+		coding, params, _ := strings.Cut(part, ";")
+		coding = strings.ToLower(strings.TrimSpace(coding))
+		if coding == "x-gzip" {
+			coding = codingGzip
+		}
+		weight := 1.0
+		if name, value, ok := strings.Cut(strings.TrimSpace(params), "="); ok && strings.TrimSpace(name) == "q" {
+			if v, err := strconv.ParseFloat(strings.TrimSpace(value), 64); err == nil {
+				weight = v
+			}
+		}
+		q[coding] = weight
+	}
+
+	best, bestQ := "", 0.0
+	for _, coding := range []string{codingBrotli, codingGzip} {
+		weight, ok := q[coding]
+		if !ok {
+			weight = q["*"]
+		}
+		if weight > bestQ {
+			best, bestQ = coding, weight
+		}
+	}
+	return best
+}
+
+// compressWriter encodes a response's body once its headers show it is worth
+// compressing.
+type compressWriter struct {
+	http.ResponseWriter
+	coding  string
+	decided bool
+	enc     encoder
+}
+
+func (w *compressWriter) WriteHeader(status int) {
+	if !w.decided {
+		w.decided = true
+		if compressible(status, w.Header()) {
+			w.Header().Set("Content-Encoding", w.coding)
+			w.Header().Del("Content-Length")
+			w.enc = encoders[w.coding].Get().(encoder)
+			w.enc.Reset(w.ResponseWriter)
+		}
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *compressWriter) Write(b []byte) (int, error) {
+	if !w.decided {
+		w.WriteHeader(http.StatusOK)
+	}
+	if w.enc != nil {
+		return w.enc.Write(b)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Flush sends what has been compressed so far, for handlers that stream.
+func (w *compressWriter) Flush() {
+	if w.enc != nil {
+		w.enc.Flush()
+	}
+	http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+func (w *compressWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// close finishes the compressed body and returns the encoder to its pool.
+func (w *compressWriter) close() {
+	if w.enc == nil {
+		return
+	}
+	if err := w.enc.Close(); err != nil {
+		logger.Debug("finishing compressed response failed", "coding", w.coding, "error", err)
+	}
+	w.enc.Reset(io.Discard)
+	encoders[w.coding].Put(w.enc)
+	w.enc = nil
+}
+
+// compressible reports whether a response is text, JSON, NDJSON or XML with a body,
+// and not already encoded.
+func compressible(status int, header http.Header) bool {
+	if status < http.StatusOK || status == http.StatusNoContent || status == http.StatusNotModified {
+		return false
+	}
+	if header.Get("Content-Encoding") != "" {
+		return false
+	}
+	mt := strings.TrimSpace(strings.ToLower(strings.Split(header.Get("Content-Type"), ";")[0]))
+	return strings.HasPrefix(mt, "text/") || mt == "application/json" || mt == "application/x-ndjson" || strings.HasSuffix(mt, "+json") || strings.HasSuffix(mt, "+xml")
+}
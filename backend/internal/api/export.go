@@ -0,0 +1,71 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/furqanmk/reviews-browser/internal/export"
+)
+
+// ExportHandler streams the recent reviews of the apps named by repeated
+// app_id parameters, or of every app the key may see if there are none, as
+// CSV, Excel-friendly CSV, NDJSON or JSON. The review filters apply, and
+// columns picks the fields. Reviews are written as they are read from the
+// store, so once the first is out a failure can only cut the export short.
+func (a *API) ExportHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	query := r.URL.Query()
+
+	appIDs := query["app_id"]
+	for _, appID := range appIDs {
+		if !allowsApp(r, appID) {
+			fail(w, r, http.StatusForbidden, CodeForbidden, "API key may not access app "+appID)
+			return
+		}
+	}
+	if key := requestKey(r); len(appIDs) == 0 && key != nil {
+		appIDs = key.Apps
+	}
+
+	format := query.Get("format")
+	if format == "" {
+		format = export.FormatCSV
+	}
+	columns, err := export.ParseColumns(query.Get("columns"), seesOriginal(r))
+	if err != nil {
+		fail(w, r, http.StatusBadRequest, CodeInvalidParameter, "Invalid columns: "+err.Error())
+		return
+	}
+	opts := export.Options{Format: format, Columns: columns, BOM: query.Get("bom") == "true"}
+	out, err := export.NewWriter(w, opts)
+	if err != nil {
+		fail(w, r, http.StatusBadRequest, CodeInvalidParameter, "Invalid export: "+err.Error())
+		return
+	}
+
+	filter := export.Filter{
+		Language:    query.Get("language"),
+		Status:      query.Get("status"),
+		Assignee:    query.Get("assignee"),
+		Tag:         query.Get("tag"),
+		ExcludeSpam: query.Get("exclude_spam") == "true",
+	}
+	w.Header().Set("Content-Type", opts.ContentType())
+	w.Header().Set("Content-Disposition",
+		fmt.Sprintf(`attachment; filename="reviews-%s.%s"`, time.Now().UTC().Format("20060102-150405"), opts.Extension()))
+
+	err = export.Export(ctx, a.db, out, appIDs, filter)
+	if err == nil {
+		err = out.Close()
+	}
+	switch {
+	case err == nil:
+		logger.InfoContext(ctx, "exported reviews", "format", format, "apps", appIDs, "reviews", out.Count())
+	case out.Count() == 0:
+		w.Header().Del("Content-Disposition")
+		storeError(w, r, err)
+	default:
+		logger.ErrorContext(ctx, "export cut short", "format", format, "reviews", out.Count(), "error", err)
+	}
+}
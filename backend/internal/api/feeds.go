@@ -0,0 +1,292 @@
+package api
+
+import (
+	"context"
+	"encoding/xml"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/furqanmk/reviews-browser/internal/model"
+)
+
+// feedEntries is how many of an app's latest reviews a feed carries.
+const feedEntries = 50
+
+// Feed formats, named by the extension of a feed's path.
+const (
+	feedAtom = "atom"
+	feedRSS  = "rss"
+)
+
+// feedPath serves feed paths such as /feeds/apps/123.atom, splitting the file
+// name into the app_id and format path values that authorize and FeedHandler
+// read, and answering 404 for other names. Feed readers seldom let headers be
+// set, so a feed key may come as the key query parameter instead. It is taken
+// out of the URL before next sees it, so nothing downstream echoes it.
+func feedPath(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		appID, format, _ := strings.Cut(r.PathValue("file"), ".")
+		if appID == "" || (format != feedAtom && format != feedRSS) {
+			http.NotFound(w, r)
+			return
+		}
+		r.SetPathValue("app_id", appID)
+		r.SetPathValue("format", format)
+		query := r.URL.Query()
+		if key := query.Get("key"); key != "" {
+			query.Del("key")
+			r.URL.RawQuery = query.Encode()
+			if r.Header.Get(APIKeyHeader) == "" {
+				r.Header.Set(APIKeyHeader, key)
+				r = r.WithContext(context.WithValue(r.Context(), keyInURLContextKey{}, true))
+			}
+		}
+		next(w, r)
+	}
+}
+
+// FeedHandler publishes an app's latest stored reviews, newest first, as an
+// Atom or RSS 2.0 feed. max_rating leaves out reviews rated above it, so a team
+// can subscribe to just the poor ones. Suspected spam is left out.
+func (a *API) FeedHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	appID := appParam(r)
+
+	maxRating := 5
+	if s := r.URL.Query().Get("max_rating"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 || n > 5 {
+			fail(w, r, http.StatusBadRequest, CodeInvalidParameter, "max_rating must be a whole number from 1 to 5")
+			return
+		}
+		maxRating = n
+	}
+	spanApp(r, appID)
+	if a.notModified(w, r, appID) {
+		return
+	}
+
+	reviews, err := a.db.GetRecentReviews(ctx, appID)
+	if err != nil {
+		storeError(w, r, err)
+		return
+	}
+	entries := make([]model.Review, 0, feedEntries)
+	for _, review := range reviews {
+		if review.Rating > maxRating || review.SuspectedSpam {
+			continue
+		}
+		entries = append(entries, review)
+		if len(entries) == feedEntries {
+			break
+		}
+	}
+
+	f := feed{
+		id:      "urn:reviews-browser:feed:apps:" + appID,
+		title:   "Reviews of app " + appID,
+		link:    appStoreURL(appID),
+		self:    feedURL(r),
+		entries: entries,
+	}
+	if maxRating < 5 {
+		f.id += ":max-rating:" + strconv.Itoa(maxRating)
+		f.title += " rated " + strconv.Itoa(maxRating) + " stars or less"
+	}
+	// the newest stored review dates the feed, whatever its rating, so an
+	// unchanged store always gives the same document
+	if len(reviews) > 0 {
+		f.updated = reviews[0].CreatedAt
+	}
+
+	var doc any
+	contentType := "application/atom+xml; charset=utf-8"
+	if r.PathValue("format") == feedRSS {
+		doc, contentType = f.rss(), "application/rss+xml; charset=utf-8"
+	} else {
+		doc = f.atom()
+	}
+	w.Header().Set("Content-Type", contentType)
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	_, err = io.WriteString(w, xml.Header)
+	if err == nil {
+		err = enc.Encode(doc)
+	}
+	if err != nil {
+		logger.WarnContext(ctx, "writing feed failed", "app_id", appID, "error", err)
+	}
+}
+
+// feed is what the Atom and RSS documents are built from.
+type feed struct {
+	id, title, link, self string
+	updated               time.Time
+	entries               []model.Review
+}
+
+// feedURL is the absolute URL a feed was requested at. feedPath has already
+// taken any key out of it.
+func feedURL(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	u := url.URL{Scheme: scheme, Host: r.Host, Path: r.URL.Path, RawQuery: r.URL.RawQuery}
+	return u.String()
+}
+
+// appStoreURL is an app's page on the App Store.
+func appStoreURL(appID string) string {
+	return "https://apps.apple.com/app/id" + url.PathEscape(appID)
+}
+
+// entryTitle leads a review's title with its rating in stars, which is what
+// most readers show of an entry.
+func entryTitle(review model.Review) string {
+	rating := min(max(review.Rating, 0), 5)
+	return strings.Repeat("★", rating) + strings.Repeat("☆", 5-rating) + " " + review.Title
+}
+
+func entryAuthor(review model.Review) string {
+	if review.Author == "" {
+		return "Anonymous"
+	}
+	return review.Author
+}
+
+func entryID(review model.Review) string {
+	return "urn:reviews-browser:review:" + review.AppID + ":" + review.ID
+}
+
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	ID      string      `xml:"id"`
+	Title   string      `xml:"title"`
+	Updated string      `xml:"updated"`
+	Links   []atomLink  `xml:"link"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Rel  string `xml:"rel,attr,omitempty"`
+	Href string `xml:"href,attr"`
+}
+
+type atomEntry struct {
+	ID        string     `xml:"id"`
+	Title     string     `xml:"title"`
+	Updated   string     `xml:"updated"`
+	Published string     `xml:"published"`
+	Author    atomPerson `xml:"author"`
+	Link      atomLink   `xml:"link"`
+	Category  atomTerm   `xml:"category"`
+	Content   atomText   `xml:"content"`
+}
+
+type atomPerson struct {
+	Name string `xml:"name"`
+}
+
+type atomTerm struct {
+	Term string `xml:"term,attr"`
+}
+
+type atomText struct {
+	Type string `xml:"type,attr"`
+	Text string `xml:",chardata"`
+}
+
+func (f feed) atom() atomFeed {
+	doc := atomFeed{
+		ID:      f.id,
+		Title:   f.title,
+		Updated: f.updated.UTC().Format(time.RFC3339),
+		Links:   []atomLink{{Rel: "self", Href: f.self}, {Rel: "alternate", Href: f.link}},
+		Entries: make([]atomEntry, len(f.entries)),
+	}
+	for i, review := range f.entries {
+		created := review.CreatedAt.UTC().Format(time.RFC3339)
+		doc.Entries[i] = atomEntry{
+			ID:        entryID(review),
+			Title:     entryTitle(review),
+			Updated:   created,
+			Published: created,
+			Author:    atomPerson{Name: entryAuthor(review)},
+			Link:      atomLink{Rel: "alternate", Href: f.link},
+			Category:  atomTerm{Term: "rating:" + strconv.Itoa(review.Rating)},
+			Content:   atomText{Type: "text", Text: review.Content},
+		}
+	}
+	return doc
+}
+
+type rssFeed struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	DC      string     `xml:"xmlns:dc,attr"`
+	Atom    string     `xml:"xmlns:atom,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	Description   string    `xml:"description"`
+	LastBuildDate string    `xml:"lastBuildDate"`
+	Self          rssLink   `xml:"atom:link"`
+	Items         []rssItem `xml:"item"`
+}
+
+type rssLink struct {
+	Rel  string `xml:"rel,attr"`
+	Type string `xml:"type,attr"`
+	Href string `xml:"href,attr"`
+}
+
+type rssItem struct {
+	Title       string  `xml:"title"`
+	Link        string  `xml:"link"`
+	Description string  `xml:"description"`
+	Creator     string  `xml:"dc:creator"`
+	Category    string  `xml:"category"`
+	GUID        rssGUID `xml:"guid"`
+	PubDate     string  `xml:"pubDate"`
+}
+
+type rssGUID struct {
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+	ID          string `xml:",chardata"`
+}
+
+func (f feed) rss() rssFeed {
+	doc := rssFeed{
+		Version: "2.0",
+		DC:      "http://purl.org/dc/elements/1.1/",
+		Atom:    "http://www.w3.org/2005/Atom",
+		Channel: rssChannel{
+			Title:         f.title,
+			Link:          f.link,
+			Description:   f.title + ", newest first",
+			LastBuildDate: f.updated.UTC().Format(time.RFC1123Z),
+			Self:          rssLink{Rel: "self", Type: "application/rss+xml", Href: f.self},
+			Items:         make([]rssItem, len(f.entries)),
+		},
+	}
+	for i, review := range f.entries {
+		doc.Channel.Items[i] = rssItem{
+			Title:       entryTitle(review),
+			Link:        f.link,
+			Description: review.Content,
+			Creator:     entryAuthor(review),
+			Category:    "rating:" + strconv.Itoa(review.Rating),
+			GUID:        rssGUID{ID: entryID(review)},
+			PubDate:     review.CreatedAt.UTC().Format(time.RFC1123Z),
+		}
+	}
+	return doc
+}
@@ -0,0 +1,205 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/furqanmk/reviews-browser/internal/clients/appstore"
+	"github.com/furqanmk/reviews-browser/internal/model"
+)
+
+// Check outcomes reported by ReadyHandler.
+const (
+	checkOK       = "ok"
+	checkFailed   = "failed"
+	checkDegraded = "degraded"
+)
+
+// Readiness is the body of a readiness response. The API is ready unless a
+// check failed; a degraded check is reported but still ready.
+type Readiness struct {
+	Ready  bool             `json:"ready"`
+	Checks map[string]Check `json:"checks"`
+}
+
+// Check is the outcome of one readiness check.
+type Check struct {
+	Status string `json:"status"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// storeCheckTTL is how long ReadyHandler reuses the result of pinging the
+// store, so frequent probes don't open every table each time.
+const storeCheckTTL = 10 * time.Second
+
+// storeCheck caches the outcome of the last store ping.
+type storeCheck struct {
+	mu  sync.Mutex
+	at  time.Time
+	err error
+}
+
+// ping returns the outcome of the last ping, pinging again once it is older
+// than storeCheckTTL.
+func (c *storeCheck) ping(ctx context.Context, db Persistence) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.at.IsZero() || time.Since(c.at) >= storeCheckTTL {
+		c.err, c.at = db.Ping(ctx), time.Now()
+	}
+	return c.err
+}
+
+// Status is the body of a status response.
+type Status struct {
+	Apps     []AppStatus             `json:"apps"`
+	AppStore *appstore.CircuitStatus `json:"appstore,omitempty"`
+}
+
+// AppStatus describes how polling a tracked app is going. An app is overdue
+// when a whole poll interval has passed since its next poll was due, which
+// means the poller is stuck or not running.
+type AppStatus struct {
+	AppID            string     `json:"app_id"`
+	Name             string     `json:"name,omitempty"`
+	PollEverySeconds int        `json:"poll_every_seconds"`
+	LastFetched      *time.Time `json:"last_fetched,omitempty"`
+	LastSuccess      *time.Time `json:"last_success,omitempty"`
+	LastError        string     `json:"last_error,omitempty"`
+	LastErrorAt      *time.Time `json:"last_error_at,omitempty"`
+	NextPoll         time.Time  `json:"next_poll"`
+	Overdue          bool       `json:"overdue"`
+	ReviewCount      int        `json:"review_count"`
+}
+
+// LiveHandler reports that the process is up and serving. It checks nothing
+// else, so a failing store never gets the process restarted.
+func (a *API) LiveHandler(w http.ResponseWriter, r *http.Request) {
+	if _, err := w.Write([]byte("API is live")); err != nil {
+		logger.ErrorContext(r.Context(), "write error", "error", err)
+	}
+}
+
+// ReadyHandler reports whether the API can serve requests: the store's tables
+// must be reachable and writable. The result is reused for storeCheckTTL, and
+// the tables aren't read, so probes stay cheap; `db check` looks at what they
+// hold. An open App Store circuit only degrades readiness, since stored
+// reviews can still be served. It responds 503 when not ready.
+func (a *API) ReadyHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	readiness := Readiness{Ready: true, Checks: make(map[string]Check)}
+
+	if err := a.storeCheck.ping(ctx, a.db); err != nil {
+		readiness.Ready = false
+		readiness.Checks["store"] = Check{Status: checkFailed, Detail: err.Error()}
+	} else {
+		readiness.Checks["store"] = Check{Status: checkOK}
+	}
+
+	if a.client != nil {
+		check := Check{Status: checkOK}
+		if circuit := a.client.Circuit(); circuit.State != appstore.CircuitClosed {
+			check = Check{Status: checkDegraded, Detail: "circuit " + circuit.State + ": " + circuit.LastError}
+		}
+		readiness.Checks["appstore"] = check
+	}
+
+	status := http.StatusOK
+	if !readiness.Ready {
+		status = http.StatusServiceUnavailable
+		logger.WarnContext(ctx, "not ready", "checks", readiness.Checks)
+	}
+	writeJSON(w, r, status, readiness)
+}
+
+// StatusHandler reports each tracked app's polling history and stored review
+// count, and the App Store circuit. Only apps the API key may see are listed.
+func (a *API) StatusHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	apps, err := a.db.GetApps(ctx)
+	if err != nil {
+		storeError(w, r, err)
+		return
+	}
+	counts, err := a.db.CountReviews(ctx)
+	if err != nil {
+		storeError(w, r, err)
+		return
+	}
+
+	now := time.Now()
+	status := Status{Apps: make([]AppStatus, 0, len(apps))}
+	for _, app := range apps {
+		if !allowsApp(r, app.ID) {
+			continue
+		}
+		every := time.Duration(app.PollEverySeconds) * time.Second
+		nextPoll := app.LastFetched.Add(every)
+		status.Apps = append(status.Apps, AppStatus{
+			AppID:            app.ID,
+			Name:             app.Metadata.Name,
+			PollEverySeconds: app.PollEverySeconds,
+			LastFetched:      optionalTime(app.LastFetched),
+			LastSuccess:      optionalTime(app.LastSuccess),
+			LastError:        app.LastError,
+			LastErrorAt:      optionalTime(app.LastErrorAt),
+			NextPoll:         nextPoll,
+			Overdue:          now.After(nextPoll.Add(every)),
+			ReviewCount:      counts[app.ID],
+		})
+	}
+	if a.client != nil {
+		circuit := a.client.Circuit()
+		status.AppStore = &circuit
+	}
+
+	respond(w, r, http.StatusOK, status)
+}
+
+// PollsHandler lists an app's poll runs, newest first. With errors=true only
+// failed runs are listed, and with error_class only those of that class.
+func (a *API) PollsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	query := r.URL.Query()
+	appID := r.PathValue("app_id")
+	spanApp(r, appID)
+
+	onlyErrors := query.Get("errors") == "true"
+	errorClass := query.Get("error_class")
+
+	runs, err := a.db.GetPollRuns(ctx, appID)
+	if err != nil {
+		storeError(w, r, err)
+		return
+	}
+
+	filtered := make([]model.PollRun, 0, len(runs))
+	for _, run := range runs {
+		if onlyErrors && run.Succeeded() || errorClass != "" && run.ErrorClass != errorClass {
+			continue
+		}
+		filtered = append(filtered, run)
+	}
+
+	// legacy clients get the first limit runs, v1 ones a page of them
+	if !isV1(r) {
+		limit, err := intParam(query.Get("limit"), defaultPageSize)
+		if err != nil || limit <= 0 {
+			fail(w, r, http.StatusBadRequest, CodeInvalidParameter, "Invalid limit")
+			return
+		}
+		filtered = filtered[:min(limit, len(filtered))]
+	}
+	respondList(w, r, filtered)
+}
+
+// optionalTime returns nil for the zero time, so it's left out of responses.
+func optionalTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
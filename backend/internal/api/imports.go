@@ -0,0 +1,48 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/furqanmk/reviews-browser/internal/importer"
+)
+
+// ImportHandler imports reviews from a CSV or NDJSON file sent as the request
+// body, through the ingestion pipeline, and responds with the import report.
+// The format is taken from format, or else the Content-Type. app_id names the
+// app of rows without one, map maps fields to the file's columns, and with
+// dry_run the file is only validated. The body is read as it is imported, so
+// files of any size can be sent.
+func (a *API) ImportHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	query := r.URL.Query()
+
+	format := query.Get("format")
+	if format == "" {
+		format = importer.FormatCSV
+		if strings.HasPrefix(r.Header.Get("Content-Type"), "application/x-ndjson") {
+			format = importer.FormatNDJSON
+		}
+	}
+	mapping, err := importer.ParseMapping(query.Get("map"))
+	if err != nil {
+		fail(w, r, http.StatusBadRequest, CodeInvalidParameter, "Invalid map: "+err.Error())
+		return
+	}
+
+	report, err := importer.Import(ctx, r.Body, a.pipeline, importer.Options{
+		Format:  format,
+		AppID:   query.Get("app_id"),
+		Mapping: mapping,
+		DryRun:  query.Get("dry_run") == "true",
+		Cutoff:  a.cfg.Load().RecencyCutoff,
+	})
+	if err != nil {
+		fail(w, r, http.StatusBadRequest, CodeInvalidBody, "Invalid import: "+err.Error())
+		return
+	}
+	logger.InfoContext(ctx, "imported reviews", "format", format, "dry_run", report.DryRun,
+		"rows", report.Rows, "invalid", report.Invalid, "new", report.New, "duplicate", report.Duplicate, "failed", report.Failed)
+
+	respond(w, r, http.StatusOK, report)
+}
@@ -0,0 +1,94 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+
+	"github.com/furqanmk/reviews-browser/internal/jobs"
+	"github.com/furqanmk/reviews-browser/internal/model"
+)
+
+// SetJobs gives the API a queue to run requested polls on. Without one, poll
+// requests are refused.
+func (a *API) SetJobs(queue *jobs.Queue) {
+	a.jobs = queue
+}
+
+// PollAppHandler queues a poll of a tracked app and responds 202 with the new
+// job. If the app already has a job queued or running, that job is returned
+// with 200 instead, so repeated requests don't repeat the work.
+func (a *API) PollAppHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	appID := r.PathValue("app_id")
+	spanApp(r, appID)
+
+	if a.jobs == nil {
+		fail(w, r, http.StatusServiceUnavailable, CodePollingUnavailable, "Polling unavailable")
+		return
+	}
+
+	apps, err := a.db.GetApps(ctx)
+	if err != nil {
+		storeError(w, r, err)
+		return
+	}
+	if !slices.ContainsFunc(apps, func(app model.App) bool { return app.ID == appID }) {
+		fail(w, r, http.StatusNotFound, CodeNotFound, "App not tracked")
+		return
+	}
+	a.enqueue(w, r, appID)
+}
+
+// FetchHandler queues a fetch of any app's recent reviews, tracked or not, so
+// an app can be browsed before anyone tracks it. It answers like
+// PollAppHandler; an app that isn't tracked gets its reviews stored but no
+// poll history.
+func (a *API) FetchHandler(w http.ResponseWriter, r *http.Request) {
+	appID := r.PathValue("app_id")
+	spanApp(r, appID)
+
+	if a.jobs == nil {
+		fail(w, r, http.StatusServiceUnavailable, CodePollingUnavailable, "Polling unavailable")
+		return
+	}
+	a.enqueue(w, r, appID)
+}
+
+// enqueue queues a job for an app and responds with it: 202 for a new job,
+// or 200 for the one already queued or running.
+func (a *API) enqueue(w http.ResponseWriter, r *http.Request, appID string) {
+	ctx := r.Context()
+	job, created, err := a.jobs.Enqueue(ctx, appID)
+	if errors.Is(err, jobs.ErrQueueFull) {
+		w.Header().Set("Retry-After", "60")
+		fail(w, r, http.StatusServiceUnavailable, CodeQueueFull, "Too many polls queued")
+		return
+	}
+	if err != nil {
+		fail(w, r, http.StatusInternalServerError, CodeInternalError, "Queue error")
+		logger.ErrorContext(ctx, "queue error", "error", err)
+		return
+	}
+
+	status := http.StatusOK
+	if created {
+		status = http.StatusAccepted
+	}
+	w.Header().Set("Location", apiPath(r, "/jobs/"+job.ID))
+	respond(w, r, status, job)
+}
+
+// JobHandler reports the status of a poll job, and its result once finished.
+func (a *API) JobHandler(w http.ResponseWriter, r *http.Request) {
+	if a.jobs == nil {
+		fail(w, r, http.StatusNotFound, CodeNotFound, "Job not found")
+		return
+	}
+	job, ok := a.jobs.Get(r.PathValue("id"))
+	if !ok || !allowsApp(r, job.AppID) {
+		fail(w, r, http.StatusNotFound, CodeNotFound, "Job not found")
+		return
+	}
+	respond(w, r, http.StatusOK, job)
+}
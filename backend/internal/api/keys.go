@@ -0,0 +1,110 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/furqanmk/reviews-browser/internal/auth"
+	"github.com/furqanmk/reviews-browser/internal/database"
+	"github.com/furqanmk/reviews-browser/internal/model"
+)
+
+// newKey is the body accepted by CreateKeyHandler.
+type newKey struct {
+	Name string   `json:"name"`
+	Role string   `json:"role"`
+	Apps []string `json:"apps"`
+}
+
+// createdKey is a new API key, with the key itself. It is the only time the
+// key is shown.
+type createdKey struct {
+	model.APIKey
+	Key string `json:"key"`
+}
+
+// KeysHandler lists the stored API keys, without the keys themselves.
+func (a *API) KeysHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	keys, err := a.db.GetAPIKeys(ctx)
+	if err != nil {
+		storeError(w, r, err)
+		return
+	}
+	respondList(w, r, keys)
+}
+
+// CreateKeyHandler creates an API key and responds 201 with it.
+func (a *API) CreateKeyHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var body newKey
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		fail(w, r, http.StatusBadRequest, CodeInvalidBody, "Invalid request body")
+		return
+	}
+	if body.Name == "" {
+		fail(w, r, http.StatusBadRequest, CodeInvalidBody, "Missing name")
+		return
+	}
+	if !auth.ValidRole(body.Role) {
+		fail(w, r, http.StatusBadRequest, CodeInvalidBody, "Invalid role")
+		return
+	}
+	if body.Role == model.RoleFeed && len(body.Apps) != 1 {
+		fail(w, r, http.StatusBadRequest, CodeInvalidBody, "Feed keys must be limited to one app")
+		return
+	}
+	for i, appID := range body.Apps {
+		if !model.ValidAppID(appID) {
+			fail(w, r, http.StatusBadRequest, CodeInvalidBody, "Invalid app ID "+strconv.Quote(appID)+": must be numeric")
+			return
+		}
+		if slices.Contains(body.Apps[:i], appID) {
+			fail(w, r, http.StatusBadRequest, CodeInvalidBody, "App "+appID+" is listed twice")
+			return
+		}
+	}
+
+	id, secret, hash := auth.NewKey()
+	key := model.APIKey{
+		ID:        id,
+		Name:      body.Name,
+		Role:      body.Role,
+		Apps:      body.Apps,
+		CreatedAt: time.Now().UTC(),
+		Hash:      hash,
+	}
+	if err := a.db.AddAPIKey(ctx, key); err != nil {
+		storeError(w, r, err)
+		return
+	}
+	logger.InfoContext(ctx, "created API key", "key_id", id, "role", key.Role, "apps", key.Apps)
+
+	w.Header().Set("Location", apiPath(r, "/keys/"+id))
+	respond(w, r, http.StatusCreated, createdKey{APIKey: key, Key: secret})
+}
+
+// DeleteKeyHandler revokes an API key.
+func (a *API) DeleteKeyHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	id := r.PathValue("id")
+
+	err := a.db.RemoveAPIKey(ctx, id)
+	if errors.Is(err, database.ErrNotFound) {
+		fail(w, r, http.StatusNotFound, CodeNotFound, "Key not found")
+		return
+	}
+	if err != nil {
+		storeError(w, r, err)
+		return
+	}
+	logger.InfoContext(ctx, "revoked API key", "key_id", id)
+
+	w.WriteHeader(http.StatusNoContent)
+}
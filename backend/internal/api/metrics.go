@@ -0,0 +1,96 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	httpRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "reviews_browser",
+		Subsystem: "http",
+		Name:      "requests_total",
+		Help:      "HTTP requests served, by route pattern, method and status code.",
+	}, []string{"route", "method", "status"})
+
+	httpDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "reviews_browser",
+		Subsystem: "http",
+		Name:      "request_duration_seconds",
+		Help:      "Time taken to serve HTTP requests, by route pattern and method.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"route", "method"})
+
+	httpRateLimited = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "reviews_browser",
+		Subsystem: "http",
+		Name:      "rate_limited_total",
+		Help:      "API requests refused for being over a client's rate limit, by budget.",
+	}, []string{"budget"})
+
+	httpDeprecated = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "reviews_browser",
+		Subsystem: "http",
+		Name:      "deprecated_requests_total",
+		Help:      "Requests to legacy routes that have a v1 successor, by route pattern.",
+	}, []string{"route"})
+
+	httpNotModified = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "reviews_browser",
+		Subsystem: "http",
+		Name:      "not_modified_total",
+		Help:      "Conditional requests answered with 304 Not Modified, by route pattern.",
+	}, []string{"route"})
+)
+
+// statusRecorder remembers the status code written through it.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *statusRecorder) Write(b []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	return r.ResponseWriter.Write(b)
+}
+
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+// Instrument counts, times and logs, at debug level, every request handled by
+// next. Requests are labelled with the ServeMux pattern that matched rather
+// than the raw path, so IDs in paths don't multiply the series.
+func Instrument(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w}
+		next.ServeHTTP(rec, r)
+
+		route := r.Pattern
+		if route == "" {
+			route = "unmatched"
+		}
+		if rec.status == 0 {
+			rec.status = http.StatusOK
+		}
+		elapsed := time.Since(start)
+		httpRequests.WithLabelValues(route, r.Method, strconv.Itoa(rec.status)).Inc()
+		httpDuration.WithLabelValues(route, r.Method).Observe(elapsed.Seconds())
+		logger.DebugContext(r.Context(), "request served",
+			"method", r.Method, "path", r.URL.Path, "route", route, "status", rec.status, "duration", elapsed)
+	})
+}
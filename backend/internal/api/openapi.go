@@ -0,0 +1,53 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/furqanmk/reviews-browser/docs"
+	"github.com/furqanmk/reviews-browser/internal/openapi"
+)
+
+// spec is docs/api.yaml, which every v1 route must be described in.
+var spec = mustLoadSpec()
+
+func mustLoadSpec() *openapi.Spec {
+	s, err := openapi.Load(docs.APISpec)
+	if err != nil {
+		panic("api: loading docs/api.yaml: " + err.Error())
+	}
+	return s
+}
+
+// OpenAPIHandler serves the API's OpenAPI description as JSON.
+func (a *API) OpenAPIHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(spec.JSON())
+}
+
+// validate refuses requests that don't match op's parameters and body in the
+// spec with 400, listing what was wrong with each part.
+func validate(op *openapi.Operation, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		errs := spec.ValidateRequest(op, r)
+		if len(errs) == 0 {
+			next(w, r)
+			return
+		}
+
+		code := CodeInvalidParameter
+		switch first := errs[0]; {
+		case first.In == "body" || first.In == "header":
+			code = CodeInvalidBody
+		case first.Reason == "is required":
+			code = CodeMissingParameter
+		}
+		reasons := make([]string, len(errs))
+		for i, e := range errs {
+			reasons[i] = e.String()
+		}
+		problem := newProblem(r, http.StatusBadRequest, code, "Invalid request: "+strings.Join(reasons, "; "))
+		problem.InvalidParams = errs
+		writeProblem(w, r, problem)
+	}
+}
@@ -0,0 +1,130 @@
+package api
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/furqanmk/reviews-browser/internal/ratelimit"
+)
+
+// Rate limit budgets. Every client has one of each, per minute.
+const (
+	// budgetRead covers requests answered from the store.
+	budgetRead = "read"
+	// budgetFetch covers requests that call out to the App Store.
+	budgetFetch = "fetch"
+)
+
+// Usage is a client's standing against its rate limits, as reported by
+// RateLimitHandler.
+type Usage struct {
+	// Client is who the budgets belong to: "key:<id>" for requests with an
+	// API key, otherwise "ip:<address>".
+	Client string `json:"client"`
+	// Budgets maps each limited budget to its state. Budgets with no limit
+	// are left out.
+	Budgets map[string]BudgetUsage `json:"budgets"`
+}
+
+// BudgetUsage is the state of one of a client's budgets.
+type BudgetUsage struct {
+	Limit         int `json:"limit"`
+	Remaining     int `json:"remaining"`
+	WindowSeconds int `json:"window_seconds"`
+	// ResetSeconds is how long until the budget is full again.
+	ResetSeconds int `json:"reset_seconds"`
+}
+
+// limit only lets a request through to next if its client has some of the
+// budget left, and tells it how much with RateLimit-* headers. Requests over
+// budget get 429 with Retry-After. It goes inside authorize, so requests with
+// an API key are counted against the key wherever they come from.
+func (a *API) limit(budget string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if a.allow(w, r, budget, rateClient(r)) {
+			next(w, r)
+		}
+	}
+}
+
+// allow takes a request from a client's budget, setting the RateLimit-*
+// headers. If the budget is spent, it responds 429 and returns false.
+func (a *API) allow(w http.ResponseWriter, r *http.Request, budget, client string) bool {
+	limit := a.budgetLimit(budget)
+	if limit == 0 {
+		return true
+	}
+	d := a.limiter.Allow(budget+"|"+client, limit, time.Now())
+	h := w.Header()
+	h.Set("RateLimit-Policy", strconv.Itoa(limit)+";w="+strconv.Itoa(int(ratelimit.Window.Seconds())))
+	h.Set("RateLimit-Limit", strconv.Itoa(d.Limit))
+	h.Set("RateLimit-Remaining", strconv.Itoa(d.Remaining))
+	h.Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(d.Reset)))
+	if d.Allowed {
+		return true
+	}
+
+	httpRateLimited.WithLabelValues(budget).Inc()
+	logger.InfoContext(r.Context(), "rate limited", "client", client, "budget", budget)
+	h.Set("Retry-After", strconv.Itoa(ceilSeconds(d.RetryAfter)))
+	fail(w, r, http.StatusTooManyRequests, CodeRateLimited, "Rate limit exceeded")
+	return false
+}
+
+// budgetLimit returns the requests per minute a budget allows, or 0 for no
+// limit.
+func (a *API) budgetLimit(budget string) int {
+	cfg := a.cfg.Load()
+	if budget == budgetFetch {
+		return cfg.RateLimitFetches
+	}
+	return cfg.RateLimitReads
+}
+
+// RateLimitHandler reports the calling client's remaining budgets, without
+// spending any.
+func (a *API) RateLimitHandler(w http.ResponseWriter, r *http.Request) {
+	client := rateClient(r)
+	usage := Usage{Client: client, Budgets: map[string]BudgetUsage{}}
+	now := time.Now()
+	for _, budget := range []string{budgetRead, budgetFetch} {
+		limit := a.budgetLimit(budget)
+		if limit == 0 {
+			continue
+		}
+		d := a.limiter.Peek(budget+"|"+client, limit, now)
+		usage.Budgets[budget] = BudgetUsage{
+			Limit:         d.Limit,
+			Remaining:     d.Remaining,
+			WindowSeconds: int(ratelimit.Window.Seconds()),
+			ResetSeconds:  ceilSeconds(d.Reset),
+		}
+	}
+	respond(w, r, http.StatusOK, usage)
+}
+
+// rateClient names who a request's budgets belong to: its API key, or else
+// the address it came from.
+func rateClient(r *http.Request) string {
+	if key := requestKey(r); key != nil {
+		return "key:" + key.ID
+	}
+	return "ip:" + clientIP(r)
+}
+
+// clientIP returns the address a request came from. Forwarding headers are
+// ignored, since any client could set them.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
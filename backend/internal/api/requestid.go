@@ -0,0 +1,47 @@
+package api
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+
+	"github.com/furqanmk/reviews-browser/internal/logging"
+)
+
+// RequestIDHeader carries the ID of a request, in both directions.
+const RequestIDHeader = "X-Request-ID"
+
+// RequestID gives every request an ID, reusing the caller's X-Request-ID when
+// it is reasonable and generating one otherwise. The ID is echoed in the
+// response and carried by the request context, so everything logged while
+// handling the request, down to the store and App Store client, includes it.
+func RequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(RequestIDHeader)
+		if !validRequestID(id) {
+			id = newRequestID()
+		}
+		w.Header().Set(RequestIDHeader, id)
+		next.ServeHTTP(w, r.WithContext(logging.WithRequestID(r.Context(), id)))
+	})
+}
+
+func newRequestID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// validRequestID accepts short IDs of printable ASCII, so that callers can't
+// inject anything odd into the logs.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > 64 {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < '!' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
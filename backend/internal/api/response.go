@@ -0,0 +1,276 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/furqanmk/reviews-browser/internal/logging"
+	"github.com/furqanmk/reviews-browser/internal/openapi"
+)
+
+// Problem codes, carried by every v1 error response so clients needn't match
+// on the text.
+const (
+	CodeMissingParameter    = "missing_parameter"
+	CodeInvalidParameter    = "invalid_parameter"
+	CodeInvalidBody         = "invalid_body"
+	CodeUnauthorized        = "unauthorized"
+	CodeForbidden           = "forbidden"
+	CodeNotFound            = "not_found"
+	CodeMethodNotAllowed    = "method_not_allowed"
+	CodeRateLimited         = "rate_limited"
+	CodeStoreError          = "store_error"
+	CodeInternalError       = "internal_error"
+	CodeAppStoreUnavailable = "appstore_unavailable"
+	CodePollingUnavailable  = "polling_unavailable"
+	CodeQueueFull           = "queue_full"
+)
+
+// problemTitles are the short, fixed summaries of each problem code.
+var problemTitles = map[string]string{
+	CodeMissingParameter:    "Missing parameter",
+	CodeInvalidParameter:    "Invalid parameter",
+	CodeInvalidBody:         "Invalid request body",
+	CodeUnauthorized:        "Unauthorized",
+	CodeForbidden:           "Forbidden",
+	CodeNotFound:            "Not found",
+	CodeMethodNotAllowed:    "Method not allowed",
+	CodeRateLimited:         "Rate limit exceeded",
+	CodeStoreError:          "Store error",
+	CodeInternalError:       "Internal error",
+	CodeAppStoreUnavailable: "App Store unavailable",
+	CodePollingUnavailable:  "Polling unavailable",
+	CodeQueueFull:           "Poll queue full",
+}
+
+// problemType prefixes a problem code to make the problem's type URI.
+const problemType = "urn:reviews-browser:problem:"
+
+// Problem is an RFC 7807 problem details body, as v1 routes fail with.
+type Problem struct {
+	Type      string `json:"type"`
+	Title     string `json:"title"`
+	Status    int    `json:"status"`
+	Detail    string `json:"detail,omitempty"`
+	Instance  string `json:"instance,omitempty"`
+	Code      string `json:"code"`
+	RequestID string `json:"request_id,omitempty"`
+	// InvalidParams says what was wrong with each part of a request that
+	// didn't match the API's OpenAPI description.
+	InvalidParams []openapi.FieldError `json:"invalid_params,omitempty"`
+}
+
+// Envelope wraps every successful v1 response body.
+type Envelope struct {
+	Data       any         `json:"data"`
+	Pagination *Pagination `json:"pagination,omitempty"`
+	Meta       Meta        `json:"meta"`
+}
+
+// Pagination describes the page of a list a v1 response carries.
+type Pagination struct {
+	Limit  int `json:"limit"`
+	Offset int `json:"offset"`
+	Total  int `json:"total"`
+	// NextOffset is the offset of the next page, if there is one.
+	NextOffset *int `json:"next_offset,omitempty"`
+}
+
+// Meta is information about a v1 response rather than its data.
+type Meta struct {
+	RequestID string `json:"request_id,omitempty"`
+}
+
+const (
+	// defaultPageSize and maxPageSize bound the limit parameter of v1 lists.
+	defaultPageSize = 50
+	maxPageSize     = 500
+)
+
+type versionContextKey struct{}
+
+// methods maps the HTTP methods a route accepts to their handlers.
+type methods map[string]http.HandlerFunc
+
+// v1 registers a v1 route that only accepts the given methods. Others get 405
+// with Allow; HEAD is accepted wherever GET is. Requests are checked against
+// the route's description in docs/api.yaml, so a route with a method that
+// isn't described there is left unregistered and reported as an error.
+func v1(mux *http.ServeMux, path string, handlers methods) error {
+	path = "/api/v1" + path
+	allowed := make([]string, 0, len(handlers)+1)
+	validated := make(methods, len(handlers))
+	var undescribed []error
+	for method, handler := range handlers {
+		op := spec.Operation(method, path)
+		if op == nil {
+			undescribed = append(undescribed, fmt.Errorf("api: %s %s is not described in docs/api.yaml", method, path))
+			continue
+		}
+		validated[method] = validate(op, handler)
+		allowed = append(allowed, method)
+	}
+	if len(undescribed) > 0 {
+		return errors.Join(undescribed...)
+	}
+	if handlers[http.MethodGet] != nil {
+		allowed = append(allowed, http.MethodHead)
+	}
+	slices.Sort(allowed)
+
+	mux.HandleFunc(path, func(w http.ResponseWriter, r *http.Request) {
+		r = r.WithContext(context.WithValue(r.Context(), versionContextKey{}, "v1"))
+		method := r.Method
+		if method == http.MethodHead {
+			method = http.MethodGet
+		}
+		handler, ok := validated[method]
+		if !ok {
+			w.Header().Set("Allow", strings.Join(allowed, ", "))
+			fail(w, r, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "Method "+r.Method+" not allowed")
+			return
+		}
+		handler(w, r)
+	})
+	return nil
+}
+
+// v1NotFound answers requests under /api/v1 that match no route.
+func v1NotFound(w http.ResponseWriter, r *http.Request) {
+	r = r.WithContext(context.WithValue(r.Context(), versionContextKey{}, "v1"))
+	fail(w, r, http.StatusNotFound, CodeNotFound, "No such endpoint")
+}
+
+// isV1 reports whether a request came in through a v1 route.
+func isV1(r *http.Request) bool {
+	return r.Context().Value(versionContextKey{}) == "v1"
+}
+
+// deprecated marks responses from a legacy route as deprecated, linking to the
+// v1 route that replaces it. {app_id} and {id} in successor are filled in from
+// the request.
+func deprecated(successor string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		httpDeprecated.WithLabelValues(r.Pattern).Inc()
+		w.Header().Set("Deprecation", "true")
+		link := strings.NewReplacer("{app_id}", appParam(r), "{id}", r.PathValue("id")).Replace(successor)
+		if !strings.Contains(link, "//") && !strings.HasSuffix(link, "/") {
+			w.Header().Set("Link", "<"+link+`>; rel="successor-version"`)
+		}
+		next(w, r)
+	}
+}
+
+// appParam returns the app a request is about, from its path on v1 routes and
+// its app_id query parameter on legacy ones.
+func appParam(r *http.Request) string {
+	if appID := r.PathValue("app_id"); appID != "" {
+		return appID
+	}
+	return r.URL.Query().Get("app_id")
+}
+
+// apiPath returns the path of a resource under the API version the request
+// came in through, e.g. "/jobs/1".
+func apiPath(r *http.Request, path string) string {
+	if isV1(r) {
+		return "/api/v1" + path
+	}
+	return "/api" + path
+}
+
+// fail responds with an error: an RFC 7807 problem on v1 routes, and detail
+// as plain text on legacy ones.
+func fail(w http.ResponseWriter, r *http.Request, status int, code, detail string) {
+	if !isV1(r) {
+		http.Error(w, detail, status)
+		return
+	}
+	writeProblem(w, r, newProblem(r, status, code, detail))
+}
+
+func newProblem(r *http.Request, status int, code, detail string) Problem {
+	return Problem{
+		Type:      problemType + code,
+		Title:     problemTitles[code],
+		Status:    status,
+		Detail:    detail,
+		Instance:  r.URL.Path,
+		Code:      code,
+		RequestID: logging.RequestID(r.Context()),
+	}
+}
+
+func writeProblem(w http.ResponseWriter, r *http.Request, problem Problem) {
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(problem.Status)
+	if err := json.NewEncoder(w).Encode(problem); err != nil {
+		logger.ErrorContext(r.Context(), "encoding error", "error", err)
+	}
+}
+
+// storeError responds 500 for an error from the store, and logs it.
+func storeError(w http.ResponseWriter, r *http.Request, err error) {
+	fail(w, r, http.StatusInternalServerError, CodeStoreError, "Database error")
+	logger.ErrorContext(r.Context(), "database error", "error", err)
+}
+
+// respond responds with data, in an envelope on v1 routes.
+func respond(w http.ResponseWriter, r *http.Request, status int, data any) {
+	if !isV1(r) {
+		writeJSON(w, r, status, data)
+		return
+	}
+	writeJSON(w, r, status, Envelope{Data: data, Meta: meta(r)})
+}
+
+// respondList responds with a list. On v1 routes it is paged by the limit and
+// offset query parameters and enveloped; legacy routes get all of it.
+func respondList[T any](w http.ResponseWriter, r *http.Request, items []T) {
+	if items == nil {
+		items = []T{}
+	}
+	if !isV1(r) {
+		writeJSON(w, r, http.StatusOK, items)
+		return
+	}
+
+	query := r.URL.Query()
+	limit, err := intParam(query.Get("limit"), defaultPageSize)
+	if err != nil || limit <= 0 || limit > maxPageSize {
+		fail(w, r, http.StatusBadRequest, CodeInvalidParameter, "Invalid limit, must be 1 to "+strconv.Itoa(maxPageSize))
+		return
+	}
+	offset, err := intParam(query.Get("offset"), 0)
+	if err != nil || offset < 0 {
+		fail(w, r, http.StatusBadRequest, CodeInvalidParameter, "Invalid offset")
+		return
+	}
+
+	page := &Pagination{Limit: limit, Offset: offset, Total: len(items)}
+	start, end := min(offset, len(items)), min(offset+limit, len(items))
+	if end < len(items) {
+		page.NextOffset = &end
+	}
+	writeJSON(w, r, http.StatusOK, Envelope{Data: items[start:end], Pagination: page, Meta: meta(r)})
+}
+
+func meta(r *http.Request) Meta {
+	return Meta{RequestID: logging.RequestID(r.Context())}
+}
+
+// writeJSON responds with v encoded as JSON.
+func writeJSON(w http.ResponseWriter, r *http.Request, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		logger.ErrorContext(r.Context(), "encoding error", "error", err)
+	}
+}
@@ -0,0 +1,73 @@
+// Package auth creates and checks API keys and ranks their roles.
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"strings"
+
+	"github.com/furqanmk/reviews-browser/internal/model"
+)
+
+// keyPrefix starts every API key, so leaked keys are easy to spot.
+const keyPrefix = "rb_"
+
+// roleRank orders the roles; a role may do whatever a lower one may.
+var roleRank = map[string]int{
+	model.RoleFeed:    1,
+	model.RoleViewer:  2,
+	model.RoleTriager: 3,
+	model.RoleAdmin:   4,
+}
+
+// ValidRole reports whether role is one of the API key roles.
+func ValidRole(role string) bool {
+	return roleRank[role] > 0
+}
+
+// Allows reports whether a key with role may do what need requires.
+func Allows(role, need string) bool {
+	return ValidRole(role) && roleRank[role] >= roleRank[need]
+}
+
+// NewKey generates an API key, returning its ID, the key to hand out, and the
+// hash to store. The key itself can't be recovered from what is stored.
+func NewKey() (id, key, hash string) {
+	id = randomHex(4)
+	key = keyPrefix + id + "_" + randomHex(24)
+	return id, key, Hash(key)
+}
+
+// Hash hashes an API key for storage. Keys are long and random, so a fast
+// hash is enough.
+func Hash(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// KeyID extracts the ID from a key made by NewKey, so its stored hash can be
+// found. It returns "" for anything else.
+func KeyID(key string) string {
+	rest, ok := strings.CutPrefix(key, keyPrefix)
+	if !ok {
+		return ""
+	}
+	id, _, ok := strings.Cut(rest, "_")
+	if !ok {
+		return ""
+	}
+	return id
+}
+
+// Matches reports whether key is the key hash was made from, in constant time.
+func Matches(key, hash string) bool {
+	return subtle.ConstantTimeCompare([]byte(Hash(key)), []byte(hash)) == 1
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,31 @@
+package auth_test
+
+import (
+	"testing"
+
+	"github.com/furqanmk/reviews-browser/internal/auth"
+	"github.com/furqanmk/reviews-browser/internal/model"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewKey(t *testing.T) {
+	id, key, hash := auth.NewKey()
+	require.Equal(t, id, auth.KeyID(key))
+	require.True(t, auth.Matches(key, hash))
+	require.False(t, auth.Matches(key+"x", hash))
+	require.NotContains(t, hash, key)
+
+	_, other, _ := auth.NewKey()
+	require.NotEqual(t, key, other)
+
+	require.Empty(t, auth.KeyID("not-a-key"))
+	require.Empty(t, auth.KeyID("rb_nounderscore"))
+}
+
+func TestAllows(t *testing.T) {
+	require.True(t, auth.Allows(model.RoleAdmin, model.RoleViewer))
+	require.True(t, auth.Allows(model.RoleTriager, model.RoleTriager))
+	require.False(t, auth.Allows(model.RoleViewer, model.RoleTriager))
+	require.False(t, auth.Allows(model.RoleFeed, model.RoleViewer))
+	require.False(t, auth.Allows("owner", model.RoleViewer))
+}
@@ -2,40 +2,79 @@ package cleanup
 
 import (
 	"context"
-	"log"
+	"sync/atomic"
 	"time"
 
 	"github.com/furqanmk/reviews-browser/config"
+	"github.com/furqanmk/reviews-browser/internal/logging"
 )
 
+var logger = logging.For("cleanup")
+
 // CleanupScheduler manages cleanup of old reviews.
 type CleanupScheduler struct {
-	db  CleanupDB
-	cfg *config.Config
+	db     CleanupDB
+	cfg    atomic.Pointer[config.Config]
+	reload chan struct{}
 }
 
 type CleanupDB interface {
-	CleanUpOldReviews(ctx context.Context) error
+	CleanUpOldReviews(ctx context.Context) (int, error)
 }
 
 func NewCleanupScheduler(db CleanupDB, cfg *config.Config) *CleanupScheduler {
-	return &CleanupScheduler{
-		db:  db,
-		cfg: cfg,
+	s := &CleanupScheduler{
+		db:     db,
+		reload: make(chan struct{}, 1),
 	}
+	s.cfg.Store(cfg)
+	return s
 }
 
-// StartCleanupScheduler runs CleanUpOldReviews every number of hours configured in the env.
+// SetConfig switches the scheduler to a new configuration snapshot. A changed
+// CleanupEvery applies to the wait already in progress, measured from the last
+// cleanup.
+func (s *CleanupScheduler) SetConfig(cfg *config.Config) {
+	s.cfg.Store(cfg)
+	select {
+	case s.reload <- struct{}{}:
+	default:
+	}
+}
+
+// StartCleanupScheduler runs CleanUpOldReviews every CleanupEvery.
 func (s *CleanupScheduler) Start(ctx context.Context) {
-	log.Print("Starting cleanup scheduler...")
+	logger.InfoContext(ctx, "starting cleanup scheduler")
 
 	go func() {
 		for {
-			err := s.db.CleanUpOldReviews(ctx)
+			removed, err := s.db.CleanUpOldReviews(ctx)
 			if err != nil {
-				log.Printf("Error cleaning up old reviews: %v", err)
+				logger.ErrorContext(ctx, "cleaning up old reviews failed", "error", err)
+			} else {
+				logger.InfoContext(ctx, "cleaned up old reviews", "removed", removed)
+			}
+			reviewsRemoved.Add(float64(removed))
+			if !s.wait(ctx, time.Now()) {
+				return
 			}
-			time.Sleep(time.Duration(s.cfg.CleanupEveryHrs) * time.Hour)
 		}
 	}()
 }
+
+// wait blocks until CleanupEvery has passed since last, re-reading the interval
+// whenever the config changes. It reports false if ctx is cancelled first.
+func (s *CleanupScheduler) wait(ctx context.Context, last time.Time) bool {
+	for {
+		timer := time.NewTimer(time.Until(last.Add(s.cfg.Load().CleanupEvery)))
+		select {
+		case <-timer.C:
+			return true
+		case <-s.reload:
+			timer.Stop()
+		case <-ctx.Done():
+			timer.Stop()
+			return false
+		}
+	}
+}
@@ -0,0 +1,13 @@
+package cleanup
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var reviewsRemoved = promauto.NewCounter(prometheus.CounterOpts{
+	Namespace: "reviews_browser",
+	Subsystem: "cleanup",
+	Name:      "reviews_removed_total",
+	Help:      "Reviews purged for being older than the recency cutoff.",
+})
@@ -1,15 +1,28 @@
 package appstore
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/furqanmk/reviews-browser/config"
+	"github.com/furqanmk/reviews-browser/internal/logging"
 	"github.com/furqanmk/reviews-browser/internal/model"
+	"github.com/furqanmk/reviews-browser/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var (
+	logger = logging.For("appstore")
+	tracer = tracing.Tracer("appstore")
 )
 
 // ReviewResponse models the relevant parts of the App Store reviews API response.
@@ -28,68 +41,104 @@ type ReviewResponse struct {
 	} `json:"feed"`
 }
 
+// ErrBadResponse is returned for a feed response that can't be parsed.
+var ErrBadResponse = errors.New("failed to parse response")
+
+// StatusError is returned for a feed response with a status other than 200.
+type StatusError struct {
+	StatusCode int
+	Status     string
+}
+
+func (e *StatusError) Error() string {
+	return "unexpected status " + e.Status
+}
+
+// Fetch is what FetchRecentReviews or FetchAllReviews got.
+type Fetch struct {
+	Reviews []model.Review
+	// Pages counts the feed pages received, including those before a
+	// failure.
+	Pages int
+}
+
 type Client struct {
 	HttpClient *http.Client
-	Config     *config.Config
+	config     atomic.Pointer[config.Config]
+
+	// mu guards nextRequest, the earliest time the next request may be sent.
+	// Everything sharing a client shares its rate limit.
+	mu          sync.Mutex
+	nextRequest time.Time
+
+	circuit circuit
 }
 
 // NewClient creates a new App Store client.
 func NewClient(cfg *config.Config) *Client {
-	return &Client{
-		HttpClient: &http.Client{Timeout: 10 * time.Second},
-		Config:     cfg,
-	}
+	c := &Client{HttpClient: &http.Client{Timeout: 10 * time.Second}}
+	c.config.Store(cfg)
+	return c
 }
 
-// FetchRecentReviews fetches reviews for the given appID, returning only those from the last 48 hours.
-func (c *Client) FetchRecentReviews(appID string) ([]model.Review, error) {
-	// Backoff strategy constants
-	const (
-		maxFailures  = 3
-		initialDelay = 5 * time.Second
-	)
+// Circuit reports the state of the client's circuit breaker, which refuses
+// fetches for a while once the App Store keeps failing.
+func (c *Client) Circuit() CircuitStatus {
+	return c.circuit.status(time.Now())
+}
 
+// SetConfig switches the client to a new configuration snapshot. A fetch in
+// progress finishes with the settings it started with.
+func (c *Client) SetConfig(cfg *config.Config) {
+	c.config.Store(cfg)
+}
+
+// FetchRecentReviews fetches reviews for the given appID, returning only those
+// within the recency cutoff. Cancelling ctx abandons the fetch. While the
+// circuit is open it fails at once with ErrCircuitOpen. The pages received
+// are counted even when the fetch fails.
+func (c *Client) FetchRecentReviews(ctx context.Context, appID string) (Fetch, error) {
+	return c.fetch(ctx, "appstore.FetchRecentReviews", appID, true)
+}
+
+// FetchAllReviews fetches every review the App Store still serves for the
+// given appID, walking the feed to its last page whatever the recency cutoff
+// and PollMaxPages, to backfill an app's history. It fails like
+// FetchRecentReviews.
+func (c *Client) FetchAllReviews(ctx context.Context, appID string) (Fetch, error) {
+	return c.fetch(ctx, "appstore.FetchAllReviews", appID, false)
+}
+
+// fetch walks an app's feed a page at a time. With recent, it stops at the
+// first review past the recency cutoff or after PollMaxPages.
+func (c *Client) fetch(ctx context.Context, spanName, appID string, recent bool) (_ Fetch, err error) {
+	ctx, span := tracer.Start(ctx, spanName,
+		trace.WithAttributes(attribute.String("app_id", appID)))
+	defer func() { tracing.End(span, err) }()
+
+	if err := c.circuit.allow(time.Now()); err != nil {
+		return Fetch{}, err
+	}
+	defer func() { c.circuit.record(time.Now(), err, ctx.Err() != nil) }()
+
+	cfg := c.config.Load()
 	var (
 		reviews       []model.Review
 		page          = 1
 		now           = time.Now()
-		recencyCutOff = time.Duration(c.Config.RecencyCutoffHrs) * time.Hour
+		recencyCutOff = cfg.RecencyCutoff
 	)
 
 	for {
 		var resp ReviewResponse
-		var body []byte
-		var err error
-
-		// Rudimentary exponential backoff
-		for attempt := 0; attempt < maxFailures; attempt++ {
-			url := fmt.Sprintf(c.Config.AppStoreReviewsURL, appID, page)
-			httpResp, reqErr := c.HttpClient.Get(url)
-			if reqErr != nil {
-				delay := initialDelay * (time.Duration(attempt + 1))
-				time.Sleep(delay)
-				continue
-			}
-			defer httpResp.Body.Close()
-			body, err = io.ReadAll(httpResp.Body)
-			if err != nil {
-				delay := initialDelay * (time.Duration(attempt + 1))
-				time.Sleep(delay)
-				continue
-			}
-			if httpResp.StatusCode != http.StatusOK {
-				delay := initialDelay * (time.Duration(attempt + 1))
-				time.Sleep(delay)
-				continue
-			}
-			break
-		}
+		body, err := c.fetchPage(ctx, cfg, appID, page)
 		if err != nil {
-			return nil, errors.New("failed to fetch reviews after retries")
+			return Fetch{Pages: page - 1}, err
 		}
 
 		if err := json.Unmarshal(body, &resp); err != nil {
-			return nil, fmt.Errorf("failed to parse response: %w", err)
+			fetchFailures.WithLabelValues(appID).Inc()
+			return Fetch{Pages: page}, fmt.Errorf("%w: %w", ErrBadResponse, err)
 		}
 
 		entries := resp.Feed.Entry
@@ -103,7 +152,7 @@ func (c *Client) FetchRecentReviews(appID string) ([]model.Review, error) {
 			if err != nil {
 				continue
 			}
-			if now.Sub(updated) > recencyCutOff {
+			if recent && now.Sub(updated) > recencyCutOff {
 				stop = true
 				break
 			}
@@ -122,11 +171,119 @@ func (c *Client) FetchRecentReviews(appID string) ([]model.Review, error) {
 			}
 			reviews = append(reviews, review)
 		}
-		if stop {
+		if stop || recent && cfg.PollMaxPages > 0 && page >= cfg.PollMaxPages {
 			break
 		}
 		page++
 	}
 
-	return reviews, nil
+	span.SetAttributes(attribute.Int("pages", page), attribute.Int("reviews", len(reviews)))
+	return Fetch{Reviews: reviews, Pages: page}, nil
+}
+
+// fetchPage gets one page of an app's feed, retrying failed requests with a
+// growing delay.
+func (c *Client) fetchPage(ctx context.Context, cfg *config.Config, appID string, page int) (body []byte, err error) {
+	// Backoff strategy constants
+	const (
+		maxFailures  = 3
+		initialDelay = 5 * time.Second
+	)
+
+	ctx, span := tracer.Start(ctx, "appstore.page",
+		trace.WithAttributes(attribute.String("app_id", appID), attribute.Int("page", page)))
+	defer func() { tracing.End(span, err) }()
+
+	// Rudimentary exponential backoff
+	url := fmt.Sprintf(cfg.AppStoreReviewsURL, appID, page)
+	for attempt := 0; attempt < maxFailures; attempt++ {
+		if attempt > 0 {
+			fetchRetries.WithLabelValues(appID).Inc()
+			if err := sleep(ctx, initialDelay*time.Duration(attempt)); err != nil {
+				return nil, err
+			}
+		}
+		if err := c.throttle(ctx, cfg.AppStoreMinInterval); err != nil {
+			return nil, err
+		}
+		logger.DebugContext(ctx, "fetching reviews page", "page", page, "attempt", attempt+1)
+		if body, err = c.get(ctx, url, appID, attempt+1); err == nil {
+			return body, nil
+		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		logger.WarnContext(ctx, "reviews page request failed", "page", page, "attempt", attempt+1, "error", err)
+	}
+	fetchFailures.WithLabelValues(appID).Inc()
+	return nil, fmt.Errorf("failed to fetch reviews after retries: %w", err)
+}
+
+// get sends one feed request and returns the body of a 200 response.
+func (c *Client) get(ctx context.Context, url, appID string, attempt int) ([]byte, error) {
+	fetchAttempts.WithLabelValues(appID).Inc()
+	start := time.Now()
+	defer func() { fetchDuration.WithLabelValues(appID).Observe(time.Since(start).Seconds()) }()
+	return c.request(ctx, url, attribute.Int("attempt", attempt))
+}
+
+// request sends a GET request to the App Store and returns the body of a 200
+// response.
+func (c *Client) request(ctx context.Context, url string, attrs ...attribute.KeyValue) (_ []byte, err error) {
+	ctx, span := tracer.Start(ctx, "appstore.request", trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(append(attrs, semconv.URLFull(url))...))
+	defer func() { tracing.End(span, err) }()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.HttpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	span.SetAttributes(semconv.HTTPResponseStatusCode(resp.StatusCode))
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, &StatusError{StatusCode: resp.StatusCode, Status: resp.Status}
+	}
+	return body, nil
+}
+
+// throttle blocks until the client may send another request, spacing requests
+// at least interval apart. It returns early with ctx's error if ctx is done.
+func (c *Client) throttle(ctx context.Context, interval time.Duration) error {
+	if interval <= 0 {
+		return nil
+	}
+
+	c.mu.Lock()
+	now := time.Now()
+	at := c.nextRequest
+	if at.Before(now) {
+		at = now
+	}
+	c.nextRequest = at.Add(interval)
+	c.mu.Unlock()
+
+	return sleep(ctx, time.Until(at))
+}
+
+// sleep waits for d, or until ctx is done.
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
 }
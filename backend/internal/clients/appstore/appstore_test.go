@@ -2,8 +2,13 @@ package appstore_test
 
 import (
 	"bytes"
+	"context"
+	"io"
 	"io/ioutil"
 	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
 
@@ -11,6 +16,9 @@ import (
 	"github.com/furqanmk/reviews-browser/internal/clients/appstore"
 	"github.com/furqanmk/reviews-browser/internal/model"
 	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
 )
 
 // mockRoundTripper implements http.RoundTripper for testing.
@@ -69,18 +77,17 @@ func TestFetchRecentReviews(t *testing.T) {
 
 	mockCfg := &config.Config{
 		AppStoreReviewsURL: "http://example.com/app/%s/page=%d",
-		RecencyCutoffHrs:   5,
+		RecencyCutoff:      5 * time.Hour,
 	}
 
-	c := &appstore.Client{
-		HttpClient: mockClient,
-		Config:     mockCfg,
-	}
+	c := appstore.NewClient(mockCfg)
+	c.HttpClient = mockClient
 
-	reviews, err := c.FetchRecentReviews("123456")
+	fetch, err := c.FetchRecentReviews(context.Background(), "123456")
 	if err != nil {
 		t.Fatalf("FetchRecentReviews returned error: %v", err)
 	}
+	reviews := fetch.Reviews
 
 	if len(reviews) != 2 {
 		t.Fatalf("expected 2 reviews, got %d", len(reviews))
@@ -114,3 +121,233 @@ func TestFetchRecentReviews(t *testing.T) {
 		require.Equal(t, want[i].Rating, r.Rating)
 	}
 }
+
+// roundTripFunc adapts a function to http.RoundTripper.
+type roundTripFunc func(req *http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func TestFetchRecentReviews_PollMaxPages(t *testing.T) {
+	pages := 0
+	page := `{"feed": {"entry": [{
+		"id": {"label": "1"},
+		"im:rating": {"label": "4"},
+		"updated": {"label": "` + time.Now().Format(time.RFC3339) + `"}
+	}]}}`
+
+	c := appstore.NewClient(&config.Config{
+		AppStoreReviewsURL: "http://example.com/app/%s/page=%d",
+		RecencyCutoff:      time.Hour,
+		PollMaxPages:       3,
+	})
+	c.HttpClient = &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		pages++
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       ioutil.NopCloser(bytes.NewBufferString(page)),
+			Header:     make(http.Header),
+		}, nil
+	})}
+
+	fetch, err := c.FetchRecentReviews(context.Background(), "123456")
+	require.NoError(t, err)
+	require.Len(t, fetch.Reviews, 3)
+	require.Equal(t, 3, pages)
+	require.Equal(t, 3, fetch.Pages)
+
+	// a new snapshot applies to the next fetch
+	c.SetConfig(&config.Config{
+		AppStoreReviewsURL: "http://example.com/app/%s/page=%d",
+		RecencyCutoff:      time.Hour,
+		PollMaxPages:       1,
+	})
+	pages = 0
+	_, err = c.FetchRecentReviews(context.Background(), "123456")
+	require.NoError(t, err)
+	require.Equal(t, 1, pages)
+}
+
+func TestFetchAllReviews(t *testing.T) {
+	old := time.Now().Add(-30 * 24 * time.Hour).Format(time.RFC3339)
+	var requested []string
+	c := appstore.NewClient(&config.Config{
+		AppStoreReviewsURL: "http://example.com/app/%s/page=%d",
+		RecencyCutoff:      time.Hour,
+		PollMaxPages:       1,
+	})
+	c.HttpClient = &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		requested = append(requested, req.URL.Path)
+		body := `{"feed": {}}`
+		if len(requested) <= 3 {
+			body = `{"feed": {"entry": [{
+				"id": {"label": "` + strconv.Itoa(len(requested)) + `"},
+				"im:rating": {"label": "2"},
+				"updated": {"label": "` + old + `"}
+			}]}}`
+		}
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       ioutil.NopCloser(bytes.NewBufferString(body)),
+			Header:     make(http.Header),
+		}, nil
+	})}
+
+	// the cutoff and page limit don't apply: the feed is walked to its end
+	fetch, err := c.FetchAllReviews(context.Background(), "123456")
+	require.NoError(t, err)
+	require.Len(t, fetch.Reviews, 3)
+	require.Equal(t, "3", fetch.Reviews[2].ID)
+	require.Equal(t, 4, fetch.Pages)
+	require.Equal(t, "/app/123456/page=4", requested[3])
+
+	requested = nil
+	fetch, err = c.FetchRecentReviews(context.Background(), "123456")
+	require.NoError(t, err)
+	require.Empty(t, fetch.Reviews)
+	require.Len(t, requested, 1)
+}
+
+func TestFetchRecentReviews_Spans(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+
+	c := appstore.NewClient(&config.Config{
+		AppStoreReviewsURL: "http://example.com/app/%s/page=%d",
+		RecencyCutoff:      time.Hour,
+	})
+	page := `{"feed": {"entry": [{
+		"id": {"label": "1"},
+		"im:rating": {"label": "4"},
+		"updated": {"label": "` + time.Now().Format(time.RFC3339) + `"}
+	}]}}`
+	c.HttpClient = &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		body := page
+		if strings.HasSuffix(req.URL.Path, "page=2") {
+			body = `{"feed": {}}`
+		}
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       ioutil.NopCloser(bytes.NewBufferString(body)),
+			Header:     make(http.Header),
+		}, nil
+	})}
+
+	_, err := c.FetchRecentReviews(context.Background(), "123456")
+	require.NoError(t, err)
+
+	// one span per request, within one per page, within the fetch
+	spans := recorder.Ended()
+	var names []string
+	for _, span := range spans {
+		names = append(names, span.Name())
+	}
+	require.Equal(t, []string{
+		"appstore.request", "appstore.page",
+		"appstore.request", "appstore.page",
+		"appstore.FetchRecentReviews",
+	}, names)
+	fetch := spans[len(spans)-1]
+	require.Equal(t, fetch.SpanContext().SpanID(), spans[1].Parent().SpanID())
+	require.Equal(t, spans[1].SpanContext().SpanID(), spans[0].Parent().SpanID())
+}
+
+func TestFetchRecentReviews_Circuit(t *testing.T) {
+	requests := 0
+	c := appstore.NewClient(&config.Config{
+		AppStoreReviewsURL: "http://example.com/app/%s/page=%d",
+		RecencyCutoff:      time.Hour,
+	})
+	c.HttpClient = &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		requests++
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       ioutil.NopCloser(strings.NewReader("<html>maintenance</html>")),
+			Header:     make(http.Header),
+		}, nil
+	})}
+	require.Equal(t, appstore.CircuitClosed, c.Circuit().State)
+
+	// consecutive failures open the circuit
+	for range 5 {
+		_, err := c.FetchRecentReviews(context.Background(), "123")
+		require.ErrorIs(t, err, appstore.ErrBadResponse)
+	}
+	status := c.Circuit()
+	require.Equal(t, appstore.CircuitOpen, status.State)
+	require.Equal(t, 5, status.ConsecutiveFailures)
+	require.Contains(t, status.LastError, "failed to parse response")
+	require.NotNil(t, status.OpenUntil)
+
+	// and then fetches fail without a request
+	_, err := c.FetchRecentReviews(context.Background(), "123")
+	require.ErrorIs(t, err, appstore.ErrCircuitOpen)
+	require.Equal(t, 5, requests)
+
+	// a cancelled fetch doesn't count as a failure
+	c = appstore.NewClient(&config.Config{AppStoreReviewsURL: "http://example.com/app/%s/page=%d"})
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = c.FetchRecentReviews(ctx, "123")
+	require.Error(t, err)
+	require.Zero(t, c.Circuit().ConsecutiveFailures)
+}
+
+func TestLookupApp(t *testing.T) {
+	var queries []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		queries = append(queries, r.URL.RawQuery)
+		switch r.URL.Query().Get("id") {
+		case "284882215":
+			io.WriteString(w, `{"resultCount": 1, "results": [{
+				"trackName": "Facebook", "artistName": "Meta Platforms, Inc.",
+				"artworkUrl100": "https://example.com/100.png", "artworkUrl512": "https://example.com/512.png",
+				"version": "480.0", "primaryGenreName": "Social Networking",
+				"averageUserRating": 4.2, "userRatingCount": 1234567
+			}]}`)
+		case "1":
+			io.WriteString(w, `{"resultCount": 0, "results": []}`)
+		case "2":
+			io.WriteString(w, `<html>`)
+		default:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+	client := appstore.NewClient(&config.Config{AppStoreLookupURL: server.URL + "/lookup?id=%s&country=gb"})
+
+	metadata, err := client.LookupApp(context.Background(), "284882215")
+	require.NoError(t, err)
+	require.Equal(t, model.AppMetadata{
+		Name:          "Facebook",
+		Developer:     "Meta Platforms, Inc.",
+		IconURL:       "https://example.com/512.png",
+		Version:       "480.0",
+		Genre:         "Social Networking",
+		AverageRating: 4.2,
+		RatingCount:   1234567,
+	}, metadata)
+	require.Equal(t, "id=284882215&country=gb", queries[0])
+
+	_, err = client.LookupApp(context.Background(), "1")
+	require.ErrorIs(t, err, appstore.ErrAppNotFound)
+	_, err = client.LookupApp(context.Background(), "2")
+	require.ErrorIs(t, err, appstore.ErrBadResponse)
+	_, err = client.LookupApp(context.Background(), "3")
+	var statusErr *appstore.StatusError
+	require.ErrorAs(t, err, &statusErr)
+	require.Equal(t, http.StatusServiceUnavailable, statusErr.StatusCode)
+	require.Equal(t, 2, client.Circuit().ConsecutiveFailures, "an unlisted app isn't a failure")
+
+	// lookups share the feed's circuit
+	for range 3 {
+		_, err = client.LookupApp(context.Background(), "3")
+		require.Error(t, err)
+	}
+	require.Equal(t, appstore.CircuitOpen, client.Circuit().State)
+	asked := len(queries)
+	_, err = client.LookupApp(context.Background(), "284882215")
+	require.ErrorIs(t, err, appstore.ErrCircuitOpen)
+	require.Len(t, queries, asked)
+}
@@ -0,0 +1,102 @@
+package appstore
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// Circuit states reported by Client.Circuit.
+const (
+	CircuitClosed   = "closed"
+	CircuitOpen     = "open"
+	CircuitHalfOpen = "half-open"
+)
+
+const (
+	// circuitThreshold consecutive failed fetches open the circuit.
+	circuitThreshold = 5
+	// circuitCooldown is how long an open circuit refuses fetches before
+	// letting one through to probe the App Store.
+	circuitCooldown = 5 * time.Minute
+)
+
+// ErrCircuitOpen is returned instead of fetching while the App Store has been
+// failing and the circuit is open.
+var ErrCircuitOpen = errors.New("app store circuit is open")
+
+// CircuitStatus describes the client's circuit breaker.
+type CircuitStatus struct {
+	State               string     `json:"state"`
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	LastError           string     `json:"last_error,omitempty"`
+	OpenUntil           *time.Time `json:"open_until,omitempty"`
+}
+
+// circuit stops a client hammering an App Store that keeps failing. After
+// circuitThreshold failed fetches in a row it opens, refusing fetches for
+// circuitCooldown; then it is half-open, letting a single fetch through whose
+// outcome closes or reopens it.
+type circuit struct {
+	mu        sync.Mutex
+	failures  int
+	lastErr   string
+	openUntil time.Time
+	probing   bool
+}
+
+// allow returns ErrCircuitOpen if a fetch may not be sent now.
+func (c *circuit) allow(now time.Time) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.failures < circuitThreshold {
+		return nil
+	}
+	if now.Before(c.openUntil) || c.probing {
+		return ErrCircuitOpen
+	}
+	c.probing = true
+	return nil
+}
+
+// record notes the outcome of a fetch allow let through. Abandoned fetches,
+// such as cancelled ones, say nothing about the App Store and don't count.
+func (c *circuit) record(now time.Time, err error, abandoned bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.probing = false
+	switch {
+	case abandoned:
+	case err == nil:
+		c.failures = 0
+		c.lastErr = ""
+	default:
+		c.failures++
+		c.lastErr = err.Error()
+		if c.failures >= circuitThreshold {
+			c.openUntil = now.Add(circuitCooldown)
+		}
+	}
+}
+
+func (c *circuit) status(now time.Time) CircuitStatus {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	status := CircuitStatus{
+		State:               CircuitClosed,
+		ConsecutiveFailures: c.failures,
+		LastError:           c.lastErr,
+	}
+	if c.failures >= circuitThreshold {
+		status.State = CircuitHalfOpen
+		if now.Before(c.openUntil) {
+			status.State = CircuitOpen
+			openUntil := c.openUntil
+			status.OpenUntil = &openUntil
+		}
+	}
+	return status
+}
@@ -0,0 +1,88 @@
+package appstore
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/furqanmk/reviews-browser/internal/model"
+	"github.com/furqanmk/reviews-browser/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ErrAppNotFound is returned by LookupApp for an app the App Store doesn't
+// list, such as one withdrawn from sale or not sold in the looked up country.
+var ErrAppNotFound = errors.New("app not found in the App Store")
+
+// LookupResponse models the relevant parts of an iTunes Lookup API response.
+type LookupResponse struct {
+	ResultCount int `json:"resultCount"`
+	Results     []struct {
+		TrackName         string  `json:"trackName"`
+		ArtistName        string  `json:"artistName"`
+		ArtworkURL512     string  `json:"artworkUrl512"`
+		ArtworkURL100     string  `json:"artworkUrl100"`
+		Version           string  `json:"version"`
+		PrimaryGenreName  string  `json:"primaryGenreName"`
+		AverageUserRating float64 `json:"averageUserRating"`
+		UserRatingCount   int     `json:"userRatingCount"`
+	} `json:"results"`
+}
+
+// LookupApp fetches how the App Store lists an app from the iTunes Lookup
+// API: its name, developer, icon, current version, genre and average rating.
+// It shares the client's request spacing and circuit with the reviews feed,
+// so it fails at once with ErrCircuitOpen while the App Store is failing, and
+// isn't retried. An app that isn't listed is an answer from the App Store, so
+// it doesn't count against the circuit. RefreshedAt is left for the caller to
+// set.
+func (c *Client) LookupApp(ctx context.Context, appID string) (_ model.AppMetadata, err error) {
+	ctx, span := tracer.Start(ctx, "appstore.LookupApp",
+		trace.WithAttributes(attribute.String("app_id", appID)))
+	defer func() { tracing.End(span, err) }()
+
+	if err := c.circuit.allow(time.Now()); err != nil {
+		return model.AppMetadata{}, err
+	}
+	defer func() {
+		failure := err
+		if errors.Is(err, ErrAppNotFound) {
+			failure = nil
+		}
+		c.circuit.record(time.Now(), failure, ctx.Err() != nil)
+	}()
+
+	cfg := c.config.Load()
+	if err := c.throttle(ctx, cfg.AppStoreMinInterval); err != nil {
+		return model.AppMetadata{}, err
+	}
+	body, err := c.request(ctx, fmt.Sprintf(cfg.AppStoreLookupURL, appID))
+	if err != nil {
+		return model.AppMetadata{}, err
+	}
+
+	var resp LookupResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return model.AppMetadata{}, fmt.Errorf("%w: %w", ErrBadResponse, err)
+	}
+	if len(resp.Results) == 0 {
+		return model.AppMetadata{}, ErrAppNotFound
+	}
+	result := resp.Results[0]
+	icon := result.ArtworkURL512
+	if icon == "" {
+		icon = result.ArtworkURL100
+	}
+	return model.AppMetadata{
+		Name:          result.TrackName,
+		Developer:     result.ArtistName,
+		IconURL:       icon,
+		Version:       result.Version,
+		Genre:         result.PrimaryGenreName,
+		AverageRating: result.AverageUserRating,
+		RatingCount:   result.UserRatingCount,
+	}, nil
+}
@@ -0,0 +1,37 @@
+package appstore
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	fetchAttempts = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "reviews_browser",
+		Subsystem: "appstore",
+		Name:      "requests_total",
+		Help:      "Requests sent to the App Store reviews feed, by app.",
+	}, []string{"app_id"})
+
+	fetchRetries = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "reviews_browser",
+		Subsystem: "appstore",
+		Name:      "retries_total",
+		Help:      "Feed requests that repeated a failed one, by app.",
+	}, []string{"app_id"})
+
+	fetchFailures = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "reviews_browser",
+		Subsystem: "appstore",
+		Name:      "fetch_failures_total",
+		Help:      "Fetches abandoned after exhausting their retries or getting an unreadable response, by app.",
+	}, []string{"app_id"})
+
+	fetchDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "reviews_browser",
+		Subsystem: "appstore",
+		Name:      "request_duration_seconds",
+		Help:      "Latency of feed requests, by app, excluding time spent throttled.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"app_id"})
+)
@@ -0,0 +1,128 @@
+// Package compare puts the review aggregates of several apps side by side,
+// such as an app and its competitors, from a single pass over the store.
+package compare
+
+import (
+	"context"
+	"iter"
+	"math"
+	"time"
+
+	"github.com/furqanmk/reviews-browser/internal/model"
+	"github.com/furqanmk/reviews-browser/internal/tracing"
+	"github.com/furqanmk/reviews-browser/internal/trends"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = tracing.Tracer("compare")
+
+// Source iterates over stored reviews, as the database does.
+type Source interface {
+	Reviews(ctx context.Context, appIDs ...string) iter.Seq2[model.Review, error]
+}
+
+// Options controls how a comparison is computed.
+type Options struct {
+	// Window is how far back from now reviews are counted.
+	Window time.Duration
+	// Limit is the most terms of each kind reported per app, and MinCount
+	// drops terms seen in fewer reviews than this.
+	Limit    int
+	MinCount int
+	// ExcludeSpam leaves reviews flagged as suspected spam out.
+	ExcludeSpam bool
+}
+
+// Report holds the aggregates of each compared app, in the order asked for.
+type Report struct {
+	WindowHrs   int       `json:"window_hrs"`
+	GeneratedAt time.Time `json:"generated_at"`
+	Apps        []App     `json:"apps"`
+}
+
+// App aggregates one app's reviews over the window.
+type App struct {
+	AppID         string        `json:"app_id"`
+	Total         int           `json:"total"`
+	AverageRating float64       `json:"average_rating"`
+	Ratings       map[int]int   `json:"ratings"`
+	Sentiment     Sentiment     `json:"sentiment"`
+	Unigrams      []trends.Term `json:"unigrams"`
+	Bigrams       []trends.Term `json:"bigrams"`
+}
+
+// Sentiment counts reviews by the feeling their rating shows: four or five
+// stars are positive, three neutral and one or two negative.
+type Sentiment struct {
+	Positive int `json:"positive"`
+	Neutral  int `json:"neutral"`
+	Negative int `json:"negative"`
+}
+
+func (s *Sentiment) add(rating int) {
+	switch {
+	case rating >= 4:
+		s.Positive++
+	case rating == 3:
+		s.Neutral++
+	default:
+		s.Negative++
+	}
+}
+
+// tally accumulates an app's aggregates as its reviews stream past.
+type tally struct {
+	app       App
+	ratingSum int
+	terms     *trends.Counter
+}
+
+// Compare reads the reviews of appIDs from src once and aggregates each app's
+// reviews created in the window ending at now. Apps without any reviews in the
+// window are reported with zero counts.
+func Compare(ctx context.Context, src Source, appIDs []string, now time.Time, opts Options) (_ Report, err error) {
+	ctx, span := tracer.Start(ctx, "compare.Compare",
+		trace.WithAttributes(attribute.StringSlice("app_ids", appIDs)))
+	defer func() { tracing.End(span, err) }()
+
+	tallies := make(map[string]*tally, len(appIDs))
+	for _, appID := range appIDs {
+		tallies[appID] = &tally{
+			app:   App{AppID: appID, Ratings: map[int]int{1: 0, 2: 0, 3: 0, 4: 0, 5: 0}},
+			terms: trends.NewCounter(),
+		}
+	}
+
+	start := now.Add(-opts.Window)
+	for review, err := range src.Reviews(ctx, appIDs...) {
+		if err != nil {
+			return Report{}, err
+		}
+		t, ok := tallies[review.AppID]
+		if !ok || review.CreatedAt.Before(start) || review.CreatedAt.After(now) ||
+			opts.ExcludeSpam && review.SuspectedSpam {
+			continue
+		}
+		t.app.Total++
+		t.app.Ratings[review.Rating]++
+		t.app.Sentiment.add(review.Rating)
+		t.ratingSum += review.Rating
+		t.terms.Add(review)
+	}
+
+	report := Report{
+		WindowHrs:   int(opts.Window / time.Hour),
+		GeneratedAt: now,
+		Apps:        make([]App, len(appIDs)),
+	}
+	for i, appID := range appIDs {
+		t := tallies[appID]
+		if t.app.Total > 0 {
+			t.app.AverageRating = math.Round(float64(t.ratingSum)/float64(t.app.Total)*100) / 100
+		}
+		t.app.Unigrams, t.app.Bigrams = t.terms.Top(opts.Limit, opts.MinCount)
+		report.Apps[i] = t.app
+	}
+	return report, nil
+}
@@ -0,0 +1,90 @@
+package compare_test
+
+import (
+	"context"
+	"errors"
+	"iter"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/furqanmk/reviews-browser/internal/compare"
+	"github.com/furqanmk/reviews-browser/internal/model"
+	"github.com/furqanmk/reviews-browser/internal/trends"
+	"github.com/stretchr/testify/require"
+)
+
+type source struct {
+	reviews []model.Review
+	err     error
+	passes  int
+}
+
+func (s *source) Reviews(ctx context.Context, appIDs ...string) iter.Seq2[model.Review, error] {
+	s.passes++
+	return func(yield func(model.Review, error) bool) {
+		for _, review := range s.reviews {
+			if len(appIDs) > 0 && !slices.Contains(appIDs, review.AppID) {
+				continue
+			}
+			if !yield(review, nil) {
+				return
+			}
+		}
+		if s.err != nil {
+			yield(model.Review{}, s.err)
+		}
+	}
+}
+
+func TestCompare(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	review := func(appID string, rating int, content string, age time.Duration) model.Review {
+		return model.Review{AppID: appID, Rating: rating, Content: content, CreatedAt: now.Add(-age)}
+	}
+	src := &source{reviews: []model.Review{
+		review("1", 5, "Love the dark mode", time.Hour),
+		review("2", 1, "Login loop", time.Hour),
+		review("1", 4, "Dark mode is great", 2*time.Hour),
+		review("2", 2, "Stuck in a login loop", 3*time.Hour),
+		review("1", 1, "Crashes", 3*time.Hour),
+		review("2", 3, "Fine", 30*time.Hour),
+		review("3", 5, "Not compared", time.Hour),
+	}}
+	spam := review("1", 5, "Buy followers", time.Hour)
+	spam.SuspectedSpam = true
+	src.reviews = append(src.reviews, spam)
+
+	report, err := compare.Compare(context.Background(), src, []string{"2", "1", "4"}, now, compare.Options{Window: 24 * time.Hour, Limit: 1, MinCount: 2, ExcludeSpam: true})
+	require.NoError(t, err)
+	require.Equal(t, 1, src.passes, "the store is read once")
+	require.Equal(t, 24, report.WindowHrs)
+	require.Len(t, report.Apps, 3)
+
+	two, one, four := report.Apps[0], report.Apps[1], report.Apps[2]
+	require.Equal(t, "2", two.AppID)
+	require.Equal(t, 2, two.Total, "reviews before the window are left out")
+	require.Equal(t, 1.5, two.AverageRating)
+	require.Equal(t, map[int]int{1: 1, 2: 1, 3: 0, 4: 0, 5: 0}, two.Ratings)
+	require.Equal(t, compare.Sentiment{Negative: 2}, two.Sentiment)
+	require.Equal(t, []trends.Term{{Term: "login", Count: 2}}, two.Unigrams)
+	require.Equal(t, []trends.Term{{Term: "login loop", Count: 2}}, two.Bigrams)
+
+	require.Equal(t, 3, one.Total, "suspected spam is left out")
+	require.Equal(t, 3.33, one.AverageRating)
+	require.Equal(t, compare.Sentiment{Positive: 2, Negative: 1}, one.Sentiment)
+	require.Equal(t, []trends.Term{{Term: "dark mode", Count: 2}}, one.Bigrams)
+
+	require.Equal(t, compare.App{
+		AppID:    "4",
+		Ratings:  map[int]int{1: 0, 2: 0, 3: 0, 4: 0, 5: 0},
+		Unigrams: []trends.Term{},
+		Bigrams:  []trends.Term{},
+	}, four, "apps without reviews are reported empty")
+}
+
+func TestCompare_Error(t *testing.T) {
+	src := &source{err: errors.New("disk gone")}
+	_, err := compare.Compare(context.Background(), src, []string{"1"}, time.Now(), compare.Options{Window: time.Hour})
+	require.ErrorIs(t, err, src.err)
+}
@@ -2,23 +2,57 @@ package database
 
 import (
 	"encoding/csv"
+	"errors"
 	"os"
+	"slices"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
 
 	"github.com/furqanmk/reviews-browser/config"
+	"github.com/furqanmk/reviews-browser/internal/spam"
+)
+
+var (
+	// ErrNotFound is returned when a record being updated does not exist.
+	ErrNotFound = errors.New("not found")
+	// ErrExists is returned when a record being added already exists.
+	ErrExists = errors.New("already exists")
+
+	errShortRow = errors.New("row has too few columns")
 )
 
 // DB holds paths to CSV files.
 type DB struct {
-	config *config.Config
+	snapshot atomic.Pointer[config.Config]
+	spam     *spam.Detector
+	// mu serializes changes to the tables. A change reads a table and writes
+	// it back whole, so two at once would lose one of them. Reads don't take
+	// it: tables are replaced by renaming a complete file over them, so a
+	// read sees a table as it was before or after a change, never half
+	// written. The lock only orders changes made in this process.
+	mu sync.Mutex
 }
 
-func (db *DB) Close() {
-	panic("unimplemented")
-}
+// Close releases the store. CSV files are opened per operation, so there is
+// nothing to release yet.
+func (db *DB) Close() {}
 
 // NewDBConnection initializes DB with CSV file paths.
-func NewDBConnection(config *config.Config) (*DB, error) {
-	return &DB{config: config}, nil
+func NewDBConnection(cfg *config.Config) (*DB, error) {
+	db := &DB{spam: spam.NewDetector(spam.DefaultOptions)}
+	db.snapshot.Store(cfg)
+	return db, nil
+}
+
+// SetConfig switches the store to a new configuration snapshot, such as one
+// with a different recency cutoff.
+func (db *DB) SetConfig(cfg *config.Config) {
+	db.snapshot.Store(cfg)
+}
+
+func (db *DB) config() *config.Config {
+	return db.snapshot.Load()
 }
 
 func getReader(filePath string) (*csv.Reader, *os.File, error) {
@@ -27,6 +61,8 @@ func getReader(filePath string) (*csv.Reader, *os.File, error) {
 		return nil, nil, err
 	}
 	reader := csv.NewReader(file)
+	// rows written before a column was added are shorter than the header
+	reader.FieldsPerRecord = -1
 	return reader, file, nil
 }
 
@@ -39,17 +75,62 @@ func getWriter(filePath string) (*csv.Writer, *os.File, error) {
 	return writer, file, nil
 }
 
-func emptyFile(filePath string, header []string) (*csv.Writer, *os.File, error) {
-	outFile, err := os.Create(filePath)
+// rewriteFile replaces a table with header and the rows write adds. They are
+// written to a temporary file beside the table, which is then renamed over it.
+// Callers hold db.mu.
+func rewriteFile(filePath string, header []string, write func(*csv.Writer) error) (err error) {
+	tmp, err := os.CreateTemp(filepath.Dir(filePath), "."+filepath.Base(filePath)+"-*")
 	if err != nil {
-		return nil, nil, err
+		return err
 	}
-	writer := csv.NewWriter(outFile)
+	defer func() {
+		if err != nil {
+			tmp.Close()
+			os.Remove(tmp.Name())
+		}
+	}()
 
-	// Write header row
+	writer := csv.NewWriter(tmp)
 	if err := writer.Write(header); err != nil {
-		return nil, nil, err
+		return err
+	}
+	if err := write(writer); err != nil {
+		return err
 	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return err
+	}
+	// temporary files are only readable by their owner
+	if err := tmp.Chmod(0644); err != nil {
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), filePath)
+}
 
-	return writer, outFile, nil
-}
\ No newline at end of file
+// optionalColumn returns the value at index, or "" for rows written before the
+// column existed.
+func optionalColumn(row []string, index int) string {
+	if index >= len(row) {
+		return ""
+	}
+	return row[index]
+}
+
+// isHeader reports whether row is a table's header. Older headers, which lack
+// the columns added since, are recognised too.
+func isHeader(row, header []string) bool {
+	return len(row) > 0 && len(row) <= len(header) && slices.Equal(row, header[:len(row)])
+}
+
+// records returns a table's rows without its header. A table without a header
+// row keeps its first row.
+func records(rows [][]string, header []string) [][]string {
+	if len(rows) == 0 || !isHeader(rows[0], header) {
+		return rows
+	}
+	return rows[1:]
+}
@@ -2,6 +2,7 @@ package database
 
 import (
 	"context"
+	"encoding/csv"
 	"strconv"
 	"time"
 
@@ -12,6 +13,17 @@ const (
 	COLUMN_APPS_ID = iota
 	COLUMN_APPS_LAST_POLLED
 	COLUMN_APPS_POLL_EVERY
+	COLUMN_APPS_LAST_SUCCESS
+	COLUMN_APPS_LAST_ERROR
+	COLUMN_APPS_LAST_ERROR_AT
+	COLUMN_APPS_NAME
+	COLUMN_APPS_DEVELOPER
+	COLUMN_APPS_ICON_URL
+	COLUMN_APPS_VERSION
+	COLUMN_APPS_GENRE
+	COLUMN_APPS_AVERAGE_RATING
+	COLUMN_APPS_RATING_COUNT
+	COLUMN_APPS_METADATA_REFRESHED_AT
 )
 
 var (
@@ -19,12 +31,25 @@ var (
 		"id",
 		"last_fetched",
 		"poll_every_seconds",
+		"last_success",
+		"last_error",
+		"last_error_at",
+		"name",
+		"developer",
+		"icon_url",
+		"version",
+		"genre",
+		"average_rating",
+		"rating_count",
+		"metadata_refreshed_at",
 	}
 )
 
 // GetApps retrieves all apps from the apps CSV file.
 func (db *DB) GetApps(ctx context.Context) ([]model.App, error) {
-	reader, file, err := getReader(db.config.AppsCSV)
+	defer observe(ctx, "get_apps")()
+
+	reader, file, err := getReader(db.config().AppsCSV)
 	if err != nil {
 		return nil, err
 	}
@@ -36,7 +61,7 @@ func (db *DB) GetApps(ctx context.Context) ([]model.App, error) {
 	}
 
 	var apps []model.App
-	for _, row := range rows[1:] {
+	for _, row := range records(rows, appsHeader) {
 		lastFetched, err := time.Parse(time.RFC3339, row[COLUMN_APPS_LAST_POLLED])
 		if err != nil {
 			continue
@@ -49,42 +74,162 @@ func (db *DB) GetApps(ctx context.Context) ([]model.App, error) {
 			ID:               row[COLUMN_APPS_ID],
 			LastFetched:      lastFetched,
 			PollEverySeconds: pollEverySeconds,
+			LastSuccess:      optionalTime(row, COLUMN_APPS_LAST_SUCCESS),
+			LastError:        optionalColumn(row, COLUMN_APPS_LAST_ERROR),
+			LastErrorAt:      optionalTime(row, COLUMN_APPS_LAST_ERROR_AT),
+			Metadata:         parseMetadata(row),
 		})
 	}
 
 	return apps, nil
 }
 
-func (db *DB) UpdateLastFetched(ctx context.Context, appID string, lastFetched time.Time) error {
-	// Update the last fetched time for the app in the database
+// AddApp starts tracking a new app. It returns ErrExists if the app is already
+// tracked.
+func (db *DB) AddApp(ctx context.Context, app model.App) error {
+	defer observe(ctx, "add_app")()
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
 	apps, err := db.GetApps(ctx)
 	if err != nil {
 		return err
 	}
+	for _, existing := range apps {
+		if existing.ID == app.ID {
+			return ErrExists
+		}
+	}
+	return db.writeApps(append(apps, app))
+}
 
-	for i, app := range apps {
-		if app.ID == appID {
-			apps[i].LastFetched = lastFetched
-			break
+// RemoveApp stops tracking an app. Its stored reviews are left for the cleanup
+// to purge. It returns ErrNotFound if the app isn't tracked.
+func (db *DB) RemoveApp(ctx context.Context, appID string) error {
+	defer observe(ctx, "remove_app")()
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	apps, err := db.GetApps(ctx)
+	if err != nil {
+		return err
+	}
+	kept := apps[:0]
+	for _, app := range apps {
+		if app.ID != appID {
+			kept = append(kept, app)
 		}
 	}
+	if len(kept) == len(apps) {
+		return ErrNotFound
+	}
+	return db.writeApps(kept)
+}
+
+// SetPollInterval changes how often an app is polled.
+func (db *DB) SetPollInterval(ctx context.Context, appID string, seconds int) error {
+	defer observe(ctx, "set_poll_interval")()
+	db.mu.Lock()
+	defer db.mu.Unlock()
 
-	writer, file, err := emptyFile(db.config.AppsCSV, appsHeader)
+	return db.updateApp(ctx, appID, func(app *model.App) {
+		app.PollEverySeconds = seconds
+	})
+}
+
+// SetAppMetadata caches what the App Store lists about an app, returning
+// ErrNotFound if the app isn't tracked.
+func (db *DB) SetAppMetadata(ctx context.Context, appID string, metadata model.AppMetadata) error {
+	defer observe(ctx, "set_app_metadata")()
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	return db.updateApp(ctx, appID, func(app *model.App) {
+		app.Metadata = metadata
+	})
+}
+
+// updateApp applies update to the app with the given ID and saves it, returning
+// ErrNotFound if the app isn't tracked. Callers hold db.mu.
+func (db *DB) updateApp(ctx context.Context, appID string, update func(app *model.App)) error {
+	apps, err := db.GetApps(ctx)
 	if err != nil {
 		return err
 	}
-	defer file.Close()
-	defer writer.Flush()
 
-	for _, app := range apps {
-		record := []string{
-			app.ID,
-			app.LastFetched.Format(time.RFC3339),
-			strconv.Itoa(app.PollEverySeconds),
+	found := false
+	for i := range apps {
+		if apps[i].ID == appID {
+			update(&apps[i])
+			found = true
+			break
 		}
-		if err := writer.Write(record); err != nil {
-			return err
+	}
+	if !found {
+		return ErrNotFound
+	}
+	return db.writeApps(apps)
+}
+
+// writeApps replaces the apps table. Callers hold db.mu.
+func (db *DB) writeApps(apps []model.App) error {
+	return rewriteFile(db.config().AppsCSV, appsHeader, func(writer *csv.Writer) error {
+		for _, app := range apps {
+			record := []string{
+				app.ID,
+				app.LastFetched.Format(time.RFC3339),
+				strconv.Itoa(app.PollEverySeconds),
+				formatOptionalTime(app.LastSuccess),
+				app.LastError,
+				formatOptionalTime(app.LastErrorAt),
+				app.Metadata.Name,
+				app.Metadata.Developer,
+				app.Metadata.IconURL,
+				app.Metadata.Version,
+				app.Metadata.Genre,
+				strconv.FormatFloat(app.Metadata.AverageRating, 'f', -1, 64),
+				strconv.Itoa(app.Metadata.RatingCount),
+				formatOptionalTime(app.Metadata.RefreshedAt),
+			}
+			if err := writer.Write(record); err != nil {
+				return err
+			}
 		}
+		return nil
+	})
+}
+
+// parseMetadata reads an app's cached App Store details, which rows written
+// before they were kept don't have.
+func parseMetadata(row []string) model.AppMetadata {
+	rating, _ := strconv.ParseFloat(optionalColumn(row, COLUMN_APPS_AVERAGE_RATING), 64)
+	count, _ := strconv.Atoi(optionalColumn(row, COLUMN_APPS_RATING_COUNT))
+	return model.AppMetadata{
+		Name:          optionalColumn(row, COLUMN_APPS_NAME),
+		Developer:     optionalColumn(row, COLUMN_APPS_DEVELOPER),
+		IconURL:       optionalColumn(row, COLUMN_APPS_ICON_URL),
+		Version:       optionalColumn(row, COLUMN_APPS_VERSION),
+		Genre:         optionalColumn(row, COLUMN_APPS_GENRE),
+		AverageRating: rating,
+		RatingCount:   count,
+		RefreshedAt:   optionalTime(row, COLUMN_APPS_METADATA_REFRESHED_AT),
+	}
+}
+
+// optionalTime parses the time at index, giving the zero time for rows written
+// before the column existed and for times never set.
+func optionalTime(row []string, index int) time.Time {
+	t, err := time.Parse(time.RFC3339, optionalColumn(row, index))
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
+
+// formatOptionalTime formats t for optionalTime, leaving the zero time empty.
+func formatOptionalTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
 	}
-	return nil
+	return t.Format(time.RFC3339)
 }
@@ -0,0 +1,177 @@
+package database
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"io/fs"
+	"strings"
+	"time"
+
+	"github.com/furqanmk/reviews-browser/internal/model"
+)
+
+const (
+	COLUMN_KEYS_ID = iota
+	COLUMN_KEYS_NAME
+	COLUMN_KEYS_HASH
+	COLUMN_KEYS_ROLE
+	COLUMN_KEYS_APPS
+	COLUMN_KEYS_CREATED_AT
+	COLUMN_KEYS_LAST_USED_AT
+)
+
+var (
+	keysHeader = []string{
+		"id",
+		"name",
+		"hash",
+		"role",
+		"apps",
+		"created_at",
+		"last_used_at",
+	}
+)
+
+// GetAPIKeys retrieves every stored API key.
+func (db *DB) GetAPIKeys(ctx context.Context) ([]model.APIKey, error) {
+	defer observe(ctx, "get_api_keys")()
+
+	return db.readKeys()
+}
+
+// GetAPIKey retrieves a stored API key by ID, returning ErrNotFound if there is
+// none.
+func (db *DB) GetAPIKey(ctx context.Context, id string) (model.APIKey, error) {
+	defer observe(ctx, "get_api_key")()
+
+	keys, err := db.readKeys()
+	if err != nil {
+		return model.APIKey{}, err
+	}
+	for _, key := range keys {
+		if key.ID == id {
+			return key, nil
+		}
+	}
+	return model.APIKey{}, ErrNotFound
+}
+
+// AddAPIKey stores a new API key. It returns ErrExists if the ID is taken.
+func (db *DB) AddAPIKey(ctx context.Context, key model.APIKey) error {
+	defer observe(ctx, "add_api_key")()
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	keys, err := db.readKeys()
+	if err != nil {
+		return err
+	}
+	for _, existing := range keys {
+		if existing.ID == key.ID {
+			return ErrExists
+		}
+	}
+	return db.writeKeys(append(keys, key))
+}
+
+// RemoveAPIKey revokes an API key. It returns ErrNotFound if there is none
+// with the given ID.
+func (db *DB) RemoveAPIKey(ctx context.Context, id string) error {
+	defer observe(ctx, "remove_api_key")()
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	keys, err := db.readKeys()
+	if err != nil {
+		return err
+	}
+	kept := keys[:0]
+	for _, key := range keys {
+		if key.ID != id {
+			kept = append(kept, key)
+		}
+	}
+	if len(kept) == len(keys) {
+		return ErrNotFound
+	}
+	return db.writeKeys(kept)
+}
+
+// TouchAPIKey records when an API key was last used.
+func (db *DB) TouchAPIKey(ctx context.Context, id string, at time.Time) error {
+	defer observe(ctx, "touch_api_key")()
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	keys, err := db.readKeys()
+	if err != nil {
+		return err
+	}
+	for i := range keys {
+		if keys[i].ID == id {
+			keys[i].LastUsedAt = &at
+			return db.writeKeys(keys)
+		}
+	}
+	return ErrNotFound
+}
+
+// readKeys reads every API key row. A missing file means no keys have been
+// created yet.
+func (db *DB) readKeys() ([]model.APIKey, error) {
+	rows, err := readAll(db.config().KeysCSV)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var keys []model.APIKey
+	for _, row := range records(rows, keysHeader) {
+		if len(row) <= COLUMN_KEYS_LAST_USED_AT {
+			continue
+		}
+		key := model.APIKey{
+			ID:        row[COLUMN_KEYS_ID],
+			Name:      row[COLUMN_KEYS_NAME],
+			Hash:      row[COLUMN_KEYS_HASH],
+			Role:      row[COLUMN_KEYS_ROLE],
+			CreatedAt: optionalTime(row, COLUMN_KEYS_CREATED_AT),
+		}
+		if apps := row[COLUMN_KEYS_APPS]; apps != "" {
+			key.Apps = strings.Split(apps, ";")
+		}
+		if lastUsed := optionalTime(row, COLUMN_KEYS_LAST_USED_AT); !lastUsed.IsZero() {
+			key.LastUsedAt = &lastUsed
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// writeKeys replaces the keys table. Callers hold db.mu.
+func (db *DB) writeKeys(keys []model.APIKey) error {
+	return rewriteFile(db.config().KeysCSV, keysHeader, func(writer *csv.Writer) error {
+		for _, key := range keys {
+			lastUsed := ""
+			if key.LastUsedAt != nil {
+				lastUsed = key.LastUsedAt.Format(time.RFC3339)
+			}
+			record := []string{
+				key.ID,
+				key.Name,
+				key.Hash,
+				key.Role,
+				strings.Join(key.Apps, ";"),
+				key.CreatedAt.Format(time.RFC3339),
+				lastUsed,
+			}
+			if err := writer.Write(record); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,217 @@
+package database
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+)
+
+// table pairs a CSV file with the header its rows are written under.
+type table struct {
+	name   string
+	path   string
+	header []string
+	// required tables must exist for the store to work; the rest are created
+	// on first write.
+	required bool
+}
+
+func (db *DB) tables() []table {
+	return []table{
+		{name: "apps", path: db.config().AppsCSV, header: appsHeader, required: true},
+		{name: "reviews", path: db.config().ReviewsCSV, header: reviewsHeader, required: true},
+		{name: "triage", path: db.config().TriageCSV, header: triageHeader},
+		{name: "polls", path: db.config().PollsCSV, header: pollsHeader},
+		{name: "keys", path: db.config().KeysCSV, header: keysHeader},
+		{name: "versions", path: db.config().VersionsCSV, header: versionsHeader},
+	}
+}
+
+// Migrate brings every table up to the current schema: missing files are
+// created, and files with an older header are rewritten with the current one,
+// padding rows that predate newly added columns.
+func (db *DB) Migrate(ctx context.Context) error {
+	defer observe(ctx, "migrate")()
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	for _, t := range db.tables() {
+		rows, err := readAll(t.path)
+		switch {
+		case errors.Is(err, fs.ErrNotExist):
+			rows = nil
+		case err != nil:
+			return fmt.Errorf("%s: %w", t.name, err)
+		case len(rows) > 0 && slices.Equal(rows[0], t.header):
+			continue
+		}
+
+		err = rewriteFile(t.path, t.header, func(writer *csv.Writer) error {
+			for _, row := range records(rows, t.header) {
+				for len(row) < len(t.header) {
+					row = append(row, "")
+				}
+				if err := writer.Write(row); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("%s: %w", t.name, err)
+		}
+	}
+	return nil
+}
+
+// Check reports schema problems without changing anything. An empty result
+// means the store is healthy.
+func (db *DB) Check(ctx context.Context) ([]string, error) {
+	defer observe(ctx, "check")()
+
+	var problems []string
+	for _, t := range db.tables() {
+		rows, err := readAll(t.path)
+		if errors.Is(err, fs.ErrNotExist) {
+			if t.required {
+				problems = append(problems, fmt.Sprintf("%s: file %s does not exist", t.name, t.path))
+			}
+			continue
+		}
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %v", t.name, err))
+			continue
+		}
+		if len(rows) == 0 || !slices.Equal(rows[0], t.header) {
+			problems = append(problems, fmt.Sprintf("%s: header is out of date, run migrate", t.name))
+		}
+	}
+
+	// rows that can't be parsed are silently skipped on read, so surface them
+	if rows, err := readAll(db.config().ReviewsCSV); err == nil {
+		bad := 0
+		for _, row := range records(rows, reviewsHeader) {
+			if _, err := parseReviewRow(row); err != nil {
+				bad++
+			}
+		}
+		if bad > 0 {
+			problems = append(problems, fmt.Sprintf("reviews: %d rows cannot be parsed", bad))
+		}
+	}
+	return problems, nil
+}
+
+// Ping checks the store can be read and written: every table that exists is
+// opened for reading and writing. It reads nothing and creates no files, so it
+// is cheap enough for probes; Check looks at what the tables hold.
+func (db *DB) Ping(ctx context.Context) error {
+	defer observe(ctx, "ping")()
+
+	for _, t := range db.tables() {
+		file, err := os.OpenFile(t.path, os.O_RDWR, 0)
+		if errors.Is(err, fs.ErrNotExist) && !t.required {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %w", t.name, err)
+		}
+		file.Close()
+	}
+	return nil
+}
+
+// Backup copies every table file that exists into dir, creating it if needed.
+func (db *DB) Backup(ctx context.Context, dir string) error {
+	defer observe(ctx, "backup")()
+	// changes wait, so the tables are copied as they were at one moment
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	for _, t := range db.tables() {
+		err := copyFile(t.path, filepath.Join(dir, filepath.Base(t.path)))
+		if errors.Is(err, fs.ErrNotExist) && !t.required {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %w", t.name, err)
+		}
+	}
+	return nil
+}
+
+// Restore replaces the table files with the copies in a backup directory made
+// by Backup. Required tables must be present in the backup.
+func (db *DB) Restore(ctx context.Context, dir string) error {
+	defer observe(ctx, "restore")()
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	for _, t := range db.tables() {
+		src := filepath.Join(dir, filepath.Base(t.path))
+		if !fileExists(src) {
+			if t.required {
+				return fmt.Errorf("%s: backup is missing %s", t.name, src)
+			}
+			continue
+		}
+	}
+	for _, t := range db.tables() {
+		err := copyFile(filepath.Join(dir, filepath.Base(t.path)), t.path)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %w", t.name, err)
+		}
+	}
+	return nil
+}
+
+func readAll(path string) ([][]string, error) {
+	reader, file, err := getReader(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return reader.ReadAll()
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// copyFile copies src to a temporary file beside dst and renames it over dst,
+// so a table being restored is never seen half copied.
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.CreateTemp(filepath.Dir(dst), "."+filepath.Base(dst)+"-*")
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		os.Remove(out.Name())
+		return err
+	}
+	if err := errors.Join(out.Chmod(0644), out.Close()); err != nil {
+		os.Remove(out.Name())
+		return err
+	}
+	return os.Rename(out.Name(), dst)
+}
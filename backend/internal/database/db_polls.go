@@ -0,0 +1,182 @@
+package database
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"io/fs"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/furqanmk/reviews-browser/internal/model"
+)
+
+const (
+	COLUMN_POLLS_APP_ID = iota
+	COLUMN_POLLS_STARTED_AT
+	COLUMN_POLLS_FINISHED_AT
+	COLUMN_POLLS_PAGES
+	COLUMN_POLLS_SEEN
+	COLUMN_POLLS_NEW
+	COLUMN_POLLS_DUPLICATE
+	COLUMN_POLLS_FAILED
+	COLUMN_POLLS_ERROR_CLASS
+	COLUMN_POLLS_ERROR
+	COLUMN_POLLS_STALE
+)
+
+var (
+	pollsHeader = []string{
+		"app_id",
+		"started_at",
+		"finished_at",
+		"pages",
+		"seen",
+		"new",
+		"duplicate",
+		"failed",
+		"error_class",
+		"error",
+		"stale",
+	}
+)
+
+// RecordPoll stores a poll run in the app's history, dropping its oldest runs
+// past the history limit, and updates the app: the fetch time always, and
+// either its last success or its last error. It returns ErrNotFound, and
+// records nothing, if the app isn't tracked.
+func (db *DB) RecordPoll(ctx context.Context, run model.PollRun) error {
+	defer observe(ctx, "record_poll")()
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	apps, err := db.GetApps(ctx)
+	if err != nil {
+		return err
+	}
+	if !slices.ContainsFunc(apps, func(app model.App) bool { return app.ID == run.AppID }) {
+		return ErrNotFound
+	}
+
+	runs, err := db.readPolls()
+	if err != nil {
+		return err
+	}
+	runs = append(runs, run)
+	if limit := db.config().PollHistoryLimit; limit > 0 {
+		runs = trimHistory(runs, run.AppID, limit)
+	}
+	if err := db.writePolls(runs); err != nil {
+		return err
+	}
+
+	return db.updateApp(ctx, run.AppID, func(app *model.App) {
+		app.LastFetched = run.FinishedAt
+		if !run.Succeeded() {
+			app.LastError = run.Error
+			app.LastErrorAt = run.FinishedAt
+			return
+		}
+		app.LastSuccess = run.FinishedAt
+		app.LastError = ""
+		app.LastErrorAt = time.Time{}
+	})
+}
+
+// GetPollRuns retrieves an app's poll history, newest first.
+func (db *DB) GetPollRuns(ctx context.Context, appID string) ([]model.PollRun, error) {
+	defer observe(ctx, "get_poll_runs")()
+
+	all, err := db.readPolls()
+	if err != nil {
+		return nil, err
+	}
+
+	var runs []model.PollRun
+	for i := len(all) - 1; i >= 0; i-- {
+		if all[i].AppID == appID {
+			runs = append(runs, all[i])
+		}
+	}
+	return runs, nil
+}
+
+// trimHistory drops an app's oldest runs until it has at most limit. Runs are
+// in the order they were recorded.
+func trimHistory(runs []model.PollRun, appID string, limit int) []model.PollRun {
+	excess := -limit
+	for _, run := range runs {
+		if run.AppID == appID {
+			excess++
+		}
+	}
+	kept := runs[:0]
+	for _, run := range runs {
+		if run.AppID == appID && excess > 0 {
+			excess--
+			continue
+		}
+		kept = append(kept, run)
+	}
+	return kept
+}
+
+// readPolls reads every poll run. A missing file means nothing has been polled
+// yet.
+func (db *DB) readPolls() ([]model.PollRun, error) {
+	rows, err := readAll(db.config().PollsCSV)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var runs []model.PollRun
+	for _, row := range records(rows, pollsHeader) {
+		if len(row) <= COLUMN_POLLS_ERROR {
+			continue
+		}
+		run := model.PollRun{
+			AppID:      row[COLUMN_POLLS_APP_ID],
+			StartedAt:  optionalTime(row, COLUMN_POLLS_STARTED_AT),
+			FinishedAt: optionalTime(row, COLUMN_POLLS_FINISHED_AT),
+			ErrorClass: row[COLUMN_POLLS_ERROR_CLASS],
+			Error:      row[COLUMN_POLLS_ERROR],
+		}
+		run.Pages, _ = strconv.Atoi(row[COLUMN_POLLS_PAGES])
+		run.Seen, _ = strconv.Atoi(row[COLUMN_POLLS_SEEN])
+		run.New, _ = strconv.Atoi(row[COLUMN_POLLS_NEW])
+		run.Duplicate, _ = strconv.Atoi(row[COLUMN_POLLS_DUPLICATE])
+		run.Failed, _ = strconv.Atoi(row[COLUMN_POLLS_FAILED])
+		run.Stale, _ = strconv.Atoi(optionalColumn(row, COLUMN_POLLS_STALE))
+		runs = append(runs, run)
+	}
+	return runs, nil
+}
+
+// writePolls replaces the polls table. Callers hold db.mu.
+func (db *DB) writePolls(runs []model.PollRun) error {
+	return rewriteFile(db.config().PollsCSV, pollsHeader, func(writer *csv.Writer) error {
+		for _, run := range runs {
+			record := []string{
+				run.AppID,
+				run.StartedAt.Format(time.RFC3339Nano),
+				run.FinishedAt.Format(time.RFC3339Nano),
+				strconv.Itoa(run.Pages),
+				strconv.Itoa(run.Seen),
+				strconv.Itoa(run.New),
+				strconv.Itoa(run.Duplicate),
+				strconv.Itoa(run.Failed),
+				run.ErrorClass,
+				run.Error,
+				strconv.Itoa(run.Stale),
+			}
+			if err := writer.Write(record); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
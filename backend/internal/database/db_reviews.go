@@ -2,7 +2,12 @@ package database
 
 import (
 	"context"
+	"encoding/csv"
+	"io"
+	"iter"
+	"slices"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/furqanmk/reviews-browser/internal/model"
@@ -16,6 +21,12 @@ const (
 	COLUMN_REVIEWS_CONTENT
 	COLUMN_REVIEWS_RATING
 	COLUMN_REVIEWS_DATE
+	COLUMN_REVIEWS_LANGUAGE
+	COLUMN_REVIEWS_SUSPECTED_SPAM
+	COLUMN_REVIEWS_SPAM_REASON
+	COLUMN_REVIEWS_REDACTIONS
+	COLUMN_REVIEWS_ORIGINAL_TITLE
+	COLUMN_REVIEWS_ORIGINAL_CONTENT
 )
 
 var (
@@ -27,12 +38,20 @@ var (
 		"content",
 		"rating",
 		"date",
+		"language",
+		"suspected_spam",
+		"spam_reason",
+		"redactions",
+		"original_title",
+		"original_content",
 	}
 )
 
 // GetRecentReviews retrieves reviews for a specific app ID from the last x hours.
 func (db *DB) GetRecentReviews(ctx context.Context, appID string) ([]model.Review, error) {
-	reader, file, err := getReader(db.config.ReviewsCSV)
+	defer observe(ctx, "get_recent_reviews")()
+
+	reader, file, err := getReader(db.config().ReviewsCSV)
 	if err != nil {
 		return nil, err
 	}
@@ -44,40 +63,23 @@ func (db *DB) GetRecentReviews(ctx context.Context, appID string) ([]model.Revie
 	}
 
 	var reviews []model.Review
-	cutoff := time.Now().Add(-time.Duration(db.config.RecencyCutoffHrs) * time.Hour)
-
-	for _, row := range rows[1:] {
-		// Ensure row has enough columns
-		if len(row) < 7 {
-			continue
-		}
+	cutoff := time.Now().Add(-db.config().RecencyCutoff)
 
+	for _, row := range records(rows, reviewsHeader) {
 		// Filter out reviews by App ID
-		if row[COLUMN_REVIEWS_APP_ID] != appID {
+		if len(row) <= COLUMN_REVIEWS_APP_ID || row[COLUMN_REVIEWS_APP_ID] != appID {
 			continue
 		}
 
-		// Parse the review creation date
-		createdAt, err := time.Parse(time.RFC3339, row[COLUMN_REVIEWS_DATE])
-		if err != nil || createdAt.Before(cutoff) {
+		review, err := parseReviewRow(row)
+		if err != nil {
+			logger.WarnContext(ctx, "skipping unreadable review row", "review_id", row[COLUMN_REVIEWS_ID], "error", err)
 			continue
 		}
-
-		// Parse the review rating
-		rating, err := strconv.Atoi(row[COLUMN_REVIEWS_RATING])
-		if err != nil {
+		if review.CreatedAt.Before(cutoff) {
 			continue
 		}
-
-		reviews = append(reviews, model.Review{
-			ID:        row[COLUMN_REVIEWS_ID],
-			AppID:     row[COLUMN_REVIEWS_APP_ID],
-			Author:    row[COLUMN_REVIEWS_AUTHOR],
-			Title:     row[COLUMN_REVIEWS_TITLE],
-			Content:   row[COLUMN_REVIEWS_CONTENT],
-			Rating:    rating,
-			CreatedAt: createdAt,
-		})
+		reviews = append(reviews, review)
 	}
 
 	// Sort reviews by CreatedAt descending
@@ -92,88 +94,377 @@ func (db *DB) GetRecentReviews(ctx context.Context, appID string) ([]model.Revie
 	return reviews, nil
 }
 
-// InsertReview adds a new review to the reviews CSV file and updates the in-memory data.
-func (db *DB) InsertReview(ctx context.Context, review model.Review, appID string) error {
-	existingReviews, err := db.GetRecentReviews(ctx, appID)
+// Reviews iterates over the stored reviews within the recency cutoff, in the
+// order they were stored, reading the table a row at a time so that callers
+// can stream them. Only the given apps' reviews are yielded, or every app's if
+// none are given. Unreadable rows are skipped; an error reading the table, or
+// ctx ending, is yielded once and ends the iteration.
+func (db *DB) Reviews(ctx context.Context, appIDs ...string) iter.Seq2[model.Review, error] {
+	return func(yield func(model.Review, error) bool) {
+		defer observe(ctx, "iterate_reviews")()
+
+		reader, file, err := getReader(db.config().ReviewsCSV)
+		if err != nil {
+			yield(model.Review{}, err)
+			return
+		}
+		defer file.Close()
+
+		cutoff := time.Now().Add(-db.config().RecencyCutoff)
+		for {
+			if err := ctx.Err(); err != nil {
+				yield(model.Review{}, err)
+				return
+			}
+			row, err := reader.Read()
+			if err == io.EOF {
+				return
+			}
+			if err != nil {
+				yield(model.Review{}, err)
+				return
+			}
+			if isHeader(row, reviewsHeader) {
+				continue
+			}
+			if len(row) <= COLUMN_REVIEWS_APP_ID || len(appIDs) > 0 && !slices.Contains(appIDs, row[COLUMN_REVIEWS_APP_ID]) {
+				continue
+			}
+
+			review, err := parseReviewRow(row)
+			if err != nil {
+				logger.WarnContext(ctx, "skipping unreadable review row", "review_id", row[COLUMN_REVIEWS_ID], "error", err)
+				continue
+			}
+			if review.CreatedAt.Before(cutoff) {
+				continue
+			}
+			if !yield(review, nil) {
+				return
+			}
+		}
+	}
+}
+
+// InsertReview stores a single review with InsertReviews, reporting whether
+// it was new.
+func (db *DB) InsertReview(ctx context.Context, review model.Review, appID string) (bool, error) {
+	inserted, err := db.InsertReviews(ctx, appID, []model.Review{review})
+	return inserted == 1, err
+}
+
+// InsertReviews adds an app's new reviews to the reviews CSV file, flagging
+// those that look like spam next to the app's existing reviews and the ones
+// before them in the batch. Reviews that are already stored are skipped,
+// however old, so imports and backfills can be repeated. However many reviews
+// there are, the table is read once and appended to once, and the app's data
+// version is bumped once. It returns how many reviews were new.
+func (db *DB) InsertReviews(ctx context.Context, appID string, reviews []model.Review) (int, error) {
+	defer observe(ctx, "insert_reviews")()
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	stored, recent, err := db.appReviews(ctx, appID)
+	if err != nil {
+		return 0, err
+	}
+
+	cutoff := time.Now().Add(-db.config().RecencyCutoff)
+	var rows [][]string
+	for _, review := range reviews {
+		if stored[review.ID] {
+			continue
+		}
+		stored[review.ID] = true
+
+		review.AppID = appID
+		if reasons := db.spam.Check(review, recent); len(reasons) > 0 {
+			review.SuspectedSpam = true
+			review.SpamReason = strings.Join(reasons, ",")
+		}
+		rows = append(rows, reviewRecord(review))
+		if !review.CreatedAt.Before(cutoff) {
+			recent = append(recent, review)
+		}
+	}
+	if len(rows) == 0 {
+		return 0, nil
+	}
+
+	// Write the new reviews to the end of the CSV file
+	writer, file, err := getWriter(db.config().ReviewsCSV)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+	if err := writer.WriteAll(rows); err != nil {
+		return 0, err
+	}
+	return len(rows), db.bumpVersions(map[string][]model.Review{appID: recent})
+}
+
+// appReviews reads the table a row at a time for the IDs of every review an
+// app has stored, however old, and the reviews it has within the recency
+// cutoff.
+func (db *DB) appReviews(ctx context.Context, appID string) (map[string]bool, []model.Review, error) {
+	reader, file, err := getReader(db.config().ReviewsCSV)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer file.Close()
+
+	cutoff := time.Now().Add(-db.config().RecencyCutoff)
+	stored := make(map[string]bool)
+	var recent []model.Review
+	for {
+		row, err := reader.Read()
+		if err == io.EOF {
+			return stored, recent, nil
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		if isHeader(row, reviewsHeader) {
+			continue
+		}
+		if len(row) <= COLUMN_REVIEWS_APP_ID || row[COLUMN_REVIEWS_APP_ID] != appID {
+			continue
+		}
+		stored[row[COLUMN_REVIEWS_ID]] = true
+		review, err := parseReviewRow(row)
+		if err != nil {
+			logger.WarnContext(ctx, "skipping unreadable review row", "review_id", row[COLUMN_REVIEWS_ID], "error", err)
+			continue
+		}
+		if !review.CreatedAt.Before(cutoff) {
+			recent = append(recent, review)
+		}
+	}
+}
+
+// RecencyCutoff is how old a review may be and still be read, as currently
+// configured.
+func (db *DB) RecencyCutoff() time.Duration {
+	return db.config().RecencyCutoff
+}
+
+// GetReview retrieves a single stored review by ID, returning ErrNotFound if
+// there is none.
+func (db *DB) GetReview(ctx context.Context, reviewID string) (model.Review, error) {
+	defer observe(ctx, "get_review")()
+
+	reader, file, err := getReader(db.config().ReviewsCSV)
+	if err != nil {
+		return model.Review{}, err
+	}
+	defer file.Close()
+
+	rows, err := reader.ReadAll()
+	if err != nil {
+		return model.Review{}, err
+	}
+
+	for _, row := range records(rows, reviewsHeader) {
+		if len(row) == 0 || row[COLUMN_REVIEWS_ID] != reviewID {
+			continue
+		}
+		return parseReviewRow(row)
+	}
+	return model.Review{}, ErrNotFound
+}
+
+// UpdateSpamFlag overrides the spam flag on a stored review and bumps its app's
+// data version. It returns ErrNotFound if no review has the given ID.
+func (db *DB) UpdateSpamFlag(ctx context.Context, reviewID string, suspected bool, reason string) error {
+	defer observe(ctx, "update_spam_flag")()
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	reader, file, err := getReader(db.config().ReviewsCSV)
+	if err != nil {
+		return err
+	}
+	rows, err := reader.ReadAll()
+	file.Close()
 	if err != nil {
 		return err
 	}
 
-	// skip writing if the review already exists
-	for _, r := range existingReviews {
-		if r.ID == review.ID {
-			return nil
+	rows = records(rows, reviewsHeader)
+	appID := ""
+	for i, row := range rows {
+		if len(row) == 0 || row[COLUMN_REVIEWS_ID] != reviewID {
+			continue
 		}
+		review, err := parseReviewRow(row)
+		if err != nil {
+			return err
+		}
+		review.SuspectedSpam = suspected
+		review.SpamReason = reason
+		rows[i] = reviewRecord(review)
+		appID = review.AppID
+	}
+	if appID == "" {
+		return ErrNotFound
 	}
 
-	// Write the new review to the end of the CSV file
-	writer, file, err := getWriter(db.config.ReviewsCSV)
+	err = rewriteFile(db.config().ReviewsCSV, reviewsHeader, func(writer *csv.Writer) error {
+		return writer.WriteAll(rows)
+	})
 	if err != nil {
 		return err
 	}
-	defer file.Close()
-	defer writer.Flush()
+	return db.bumpVersion(ctx, appID)
+}
+
+// CountReviews counts the readable stored reviews of each app.
+func (db *DB) CountReviews(ctx context.Context) (map[string]int, error) {
+	defer observe(ctx, "count_reviews")()
+
+	rows, err := readAll(db.config().ReviewsCSV)
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make(map[string]int)
+	for _, row := range records(rows, reviewsHeader) {
+		if review, err := parseReviewRow(row); err == nil {
+			counts[review.AppID]++
+		}
+	}
+	return counts, nil
+}
+
+// parseReviewRow converts a CSV row into a review.
+func parseReviewRow(row []string) (model.Review, error) {
+	// Ensure row has enough columns
+	if len(row) <= COLUMN_REVIEWS_DATE {
+		return model.Review{}, errShortRow
+	}
 
-	record := []string{
+	// Parse the review creation date
+	createdAt, err := time.Parse(time.RFC3339, row[COLUMN_REVIEWS_DATE])
+	if err != nil {
+		return model.Review{}, err
+	}
+
+	// Parse the review rating
+	rating, err := strconv.Atoi(row[COLUMN_REVIEWS_RATING])
+	if err != nil {
+		return model.Review{}, err
+	}
+
+	review := model.Review{
+		ID:            row[COLUMN_REVIEWS_ID],
+		AppID:         row[COLUMN_REVIEWS_APP_ID],
+		Author:        row[COLUMN_REVIEWS_AUTHOR],
+		Title:         row[COLUMN_REVIEWS_TITLE],
+		Content:       row[COLUMN_REVIEWS_CONTENT],
+		Rating:        rating,
+		CreatedAt:     createdAt,
+		Language:      optionalColumn(row, COLUMN_REVIEWS_LANGUAGE),
+		SuspectedSpam: optionalColumn(row, COLUMN_REVIEWS_SUSPECTED_SPAM) == "true",
+		SpamReason:    optionalColumn(row, COLUMN_REVIEWS_SPAM_REASON),
+	}
+	if redactions := optionalColumn(row, COLUMN_REVIEWS_REDACTIONS); redactions != "" {
+		review.Redactions = strings.Split(redactions, ";")
+	}
+	if len(row) > COLUMN_REVIEWS_ORIGINAL_CONTENT &&
+		(row[COLUMN_REVIEWS_ORIGINAL_TITLE] != "" || row[COLUMN_REVIEWS_ORIGINAL_CONTENT] != "") {
+		review.Original = &model.OriginalText{
+			Title:   row[COLUMN_REVIEWS_ORIGINAL_TITLE],
+			Content: row[COLUMN_REVIEWS_ORIGINAL_CONTENT],
+		}
+	}
+	return review, nil
+}
+
+// reviewRecord converts a review into a CSV row.
+func reviewRecord(review model.Review) []string {
+	var originalTitle, originalContent string
+	if review.Original != nil {
+		originalTitle, originalContent = review.Original.Title, review.Original.Content
+	}
+	return []string{
 		review.ID,
-		appID,
+		review.AppID,
 		review.Author,
 		review.Title,
 		review.Content,
 		strconv.Itoa(review.Rating),
 		review.CreatedAt.Format(time.RFC3339),
+		review.Language,
+		strconv.FormatBool(review.SuspectedSpam),
+		review.SpamReason,
+		strings.Join(review.Redactions, ";"),
+		originalTitle,
+		originalContent,
 	}
-
-	if err := writer.Write(record); err != nil {
-		return err
-	}
-
-	return nil
 }
 
-func (db *DB) CleanUpOldReviews(ctx context.Context) error {
+// CleanUpOldReviews removes reviews older than the recency cutoff, and rows
+// too damaged to date, returning how many were removed. The data version of
+// each app that loses reviews is bumped.
+func (db *DB) CleanUpOldReviews(ctx context.Context) (int, error) {
+	defer observe(ctx, "clean_up_old_reviews")()
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
 	// Read all reviews from CSV
-	reader, file, err := getReader(db.config.ReviewsCSV)
+	reader, file, err := getReader(db.config().ReviewsCSV)
 	if err != nil {
-		return err
+		return 0, err
 	}
 	defer file.Close()
 
 	rows, err := reader.ReadAll()
 	if err != nil {
-		return err
+		return 0, err
 	}
 
 	// Prepare cutoff time
-	cutoff := time.Now().Add(-time.Duration(db.config.RecencyCutoffHrs) * time.Hour)
+	cutoff := time.Now().Add(-db.config().RecencyCutoff)
 
 	// Filter out the older reviews
+	rows = records(rows, reviewsHeader)
 	var newRows [][]string
-	for _, row := range rows[1:] {
-		if len(row) < 7 {
-			continue
+	var removedIDs []string
+	// changed maps each app that loses reviews to the reviews it keeps
+	changed := make(map[string][]model.Review)
+	for _, row := range rows {
+		if len(row) >= 7 {
+			createdAt, err := time.Parse(time.RFC3339, row[COLUMN_REVIEWS_DATE])
+			if err == nil && !createdAt.Before(cutoff) {
+				newRows = append(newRows, row)
+				continue
+			}
 		}
-		createdAt, err := time.Parse(time.RFC3339, row[COLUMN_REVIEWS_DATE])
-		if err != nil {
+		removedIDs = append(removedIDs, row[COLUMN_REVIEWS_ID])
+		if len(row) > COLUMN_REVIEWS_APP_ID {
+			changed[row[COLUMN_REVIEWS_APP_ID]] = nil
+		}
+	}
+	for _, row := range newRows {
+		if _, ok := changed[row[COLUMN_REVIEWS_APP_ID]]; !ok {
 			continue
 		}
-		if !createdAt.Before(cutoff) {
-			newRows = append(newRows, row)
+		if review, err := parseReviewRow(row); err == nil {
+			changed[review.AppID] = append(changed[review.AppID], review)
 		}
 	}
 
-	writer, file, err := emptyFile(db.config.ReviewsCSV, reviewsHeader)
-	if err != nil {
-		return err
+	removed := len(rows) - len(newRows)
+	if removed == 0 {
+		return 0, nil
 	}
-	defer file.Close()
-	defer writer.Flush()
 
-	for _, row := range newRows {
-		if err := writer.Write(row); err != nil {
-			return err
-		}
+	err = rewriteFile(db.config().ReviewsCSV, reviewsHeader, func(writer *csv.Writer) error {
+		return writer.WriteAll(newRows)
+	})
+	if err != nil {
+		return 0, err
 	}
-
-	return nil
+	db.spam.Forget(removedIDs...)
+	return removed, db.bumpVersions(changed)
 }
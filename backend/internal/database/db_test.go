@@ -0,0 +1,193 @@
+package database_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/furqanmk/reviews-browser/config"
+	"github.com/furqanmk/reviews-browser/internal/database"
+	"github.com/furqanmk/reviews-browser/internal/model"
+	"github.com/stretchr/testify/require"
+)
+
+// newDB creates a store with every table empty in a temporary directory.
+func newDB(t *testing.T) *database.DB {
+	t.Helper()
+	db, _ := newDBConfig(t)
+	return db
+}
+
+// newDBConfig is newDB, also returning the store's configuration.
+func newDBConfig(t *testing.T) (*database.DB, *config.Config) {
+	t.Helper()
+	dir := t.TempDir()
+	cfg := &config.Config{
+		ReviewsCSV:    filepath.Join(dir, "reviews.csv"),
+		AppsCSV:       filepath.Join(dir, "apps.csv"),
+		TriageCSV:     filepath.Join(dir, "triage.csv"),
+		PollsCSV:      filepath.Join(dir, "polls.csv"),
+		KeysCSV:       filepath.Join(dir, "keys.csv"),
+		VersionsCSV:   filepath.Join(dir, "versions.csv"),
+		RecencyCutoff: 48 * time.Hour,
+	}
+	db, err := database.NewDBConnection(cfg)
+	require.NoError(t, err)
+	require.NoError(t, db.Migrate(context.Background()))
+	return db, cfg
+}
+
+func TestRecordPoll_Concurrent(t *testing.T) {
+	ctx := context.Background()
+	db := newDB(t)
+	const apps = 8
+	for i := range apps {
+		require.NoError(t, db.AddApp(ctx, model.App{ID: fmt.Sprint(i), PollEverySeconds: 60}))
+	}
+
+	finished := time.Now().UTC().Truncate(time.Second)
+	var wg sync.WaitGroup
+	for i := range apps {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			run := model.PollRun{AppID: fmt.Sprint(i), StartedAt: finished, FinishedAt: finished}
+			if err := db.RecordPoll(ctx, run); err != nil {
+				t.Error(err)
+			}
+		}()
+		// readers racing the writers must never see a table half written
+		go func() {
+			defer wg.Done()
+			if got, err := db.GetApps(ctx); err != nil || len(got) != apps {
+				t.Errorf("read %d apps, error %v", len(got), err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	got, err := db.GetApps(ctx)
+	require.NoError(t, err)
+	for _, app := range got {
+		require.Equal(t, finished, app.LastSuccess, "app %s lost its poll", app.ID)
+	}
+	for i := range apps {
+		runs, err := db.GetPollRuns(ctx, fmt.Sprint(i))
+		require.NoError(t, err)
+		require.Len(t, runs, 1)
+	}
+}
+
+func TestEmptyFiles(t *testing.T) {
+	ctx := context.Background()
+	db, cfg := newDBConfig(t)
+	// files emptied by hand have lost even their header
+	for _, path := range []string{cfg.ReviewsCSV, cfg.TriageCSV, cfg.AppsCSV} {
+		require.NoError(t, os.Truncate(path, 0))
+	}
+
+	reviews, err := db.GetRecentReviews(ctx, "1")
+	require.NoError(t, err)
+	require.Empty(t, reviews)
+	_, err = db.GetReview(ctx, "r1")
+	require.ErrorIs(t, err, database.ErrNotFound)
+	require.ErrorIs(t, db.UpdateSpamFlag(ctx, "r1", true, "manual"), database.ErrNotFound)
+	triage, err := db.GetTriage(ctx, "1")
+	require.NoError(t, err)
+	require.Empty(t, triage)
+	apps, err := db.GetApps(ctx)
+	require.NoError(t, err)
+	require.Empty(t, apps)
+}
+
+func TestSetAppMetadata_ConcurrentWithPolls(t *testing.T) {
+	ctx := context.Background()
+	db := newDB(t)
+	const apps = 8
+	for i := range apps {
+		require.NoError(t, db.AddApp(ctx, model.App{ID: fmt.Sprint(i), PollEverySeconds: 60}))
+	}
+
+	// the metadata refresher and the pollers update the same rows
+	finished := time.Now().UTC().Truncate(time.Second)
+	var wg sync.WaitGroup
+	for i := range apps {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			metadata := model.AppMetadata{Name: fmt.Sprint("App ", i), RefreshedAt: finished}
+			if err := db.SetAppMetadata(ctx, fmt.Sprint(i), metadata); err != nil {
+				t.Error(err)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			run := model.PollRun{AppID: fmt.Sprint(i), StartedAt: finished, FinishedAt: finished}
+			if err := db.RecordPoll(ctx, run); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	got, err := db.GetApps(ctx)
+	require.NoError(t, err)
+	require.Len(t, got, apps)
+	for _, app := range got {
+		require.Equal(t, "App "+app.ID, app.Metadata.Name, "app %s lost its metadata", app.ID)
+		require.Equal(t, finished, app.LastSuccess, "app %s lost its poll", app.ID)
+	}
+}
+
+func TestRecordPoll_Untracked(t *testing.T) {
+	ctx := context.Background()
+	db := newDB(t)
+
+	err := db.RecordPoll(ctx, model.PollRun{AppID: "42", FinishedAt: time.Now()})
+	require.ErrorIs(t, err, database.ErrNotFound)
+	runs, err := db.GetPollRuns(ctx, "42")
+	require.NoError(t, err)
+	require.Empty(t, runs, "apps fetched without being tracked keep no history")
+}
+
+func TestHeaderlessFiles(t *testing.T) {
+	ctx := context.Background()
+	db, cfg := newDBConfig(t)
+	require.NoError(t, db.AddApp(ctx, model.App{ID: "1", PollEverySeconds: 60}))
+	_, err := db.InsertReviews(ctx, "1", []model.Review{{ID: "r1", Content: "hi", CreatedAt: time.Now().UTC()}})
+	require.NoError(t, err)
+	// files written by hand may lack a header, so their first row is a record
+	for _, path := range []string{cfg.ReviewsCSV, cfg.AppsCSV} {
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+		_, records, _ := strings.Cut(string(data), "\n")
+		require.NoError(t, os.WriteFile(path, []byte(records), 0o644))
+	}
+
+	reviews, err := db.GetRecentReviews(ctx, "1")
+	require.NoError(t, err)
+	require.Len(t, reviews, 1)
+	streamed := 0
+	for _, err := range db.Reviews(ctx, "1") {
+		require.NoError(t, err)
+		streamed++
+	}
+	require.Equal(t, 1, streamed)
+	_, err = db.GetReview(ctx, "r1")
+	require.NoError(t, err)
+	require.NoError(t, db.UpdateSpamFlag(ctx, "r1", true, "manual"))
+	counts, err := db.CountReviews(ctx)
+	require.NoError(t, err)
+	require.Equal(t, map[string]int{"1": 1}, counts)
+	removed, err := db.CleanUpOldReviews(ctx)
+	require.NoError(t, err)
+	require.Zero(t, removed)
+	apps, err := db.GetApps(ctx)
+	require.NoError(t, err)
+	require.Len(t, apps, 1)
+}
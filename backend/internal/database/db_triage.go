@@ -0,0 +1,140 @@
+package database
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"io/fs"
+	"strings"
+	"time"
+
+	"github.com/furqanmk/reviews-browser/internal/model"
+)
+
+const (
+	COLUMN_TRIAGE_REVIEW_ID = iota
+	COLUMN_TRIAGE_APP_ID
+	COLUMN_TRIAGE_STATUS
+	COLUMN_TRIAGE_ASSIGNEE
+	COLUMN_TRIAGE_TAGS
+	COLUMN_TRIAGE_NOTES
+	COLUMN_TRIAGE_UPDATED_AT
+)
+
+var (
+	triageHeader = []string{
+		"review_id",
+		"app_id",
+		"status",
+		"assignee",
+		"tags",
+		"notes",
+		"updated_at",
+	}
+)
+
+// GetTriage retrieves the triage state of an app's reviews, keyed by review ID.
+// Reviews that were never triaged have no entry.
+func (db *DB) GetTriage(ctx context.Context, appID string) (map[string]model.Triage, error) {
+	defer observe(ctx, "get_triage")()
+
+	all, err := db.readTriage()
+	if err != nil {
+		return nil, err
+	}
+
+	triage := make(map[string]model.Triage)
+	for _, t := range all {
+		if t.AppID == appID {
+			triage[t.ReviewID] = t
+		}
+	}
+	return triage, nil
+}
+
+// UpdateTriage inserts or replaces the triage state of a review, bumping its
+// app's data version.
+func (db *DB) UpdateTriage(ctx context.Context, triage model.Triage) error {
+	defer observe(ctx, "update_triage")()
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	all, err := db.readTriage()
+	if err != nil {
+		return err
+	}
+
+	replaced := false
+	for i, t := range all {
+		if t.ReviewID == triage.ReviewID {
+			all[i] = triage
+			replaced = true
+			break
+		}
+	}
+	if !replaced {
+		all = append(all, triage)
+	}
+
+	err = rewriteFile(db.config().TriageCSV, triageHeader, func(writer *csv.Writer) error {
+		for _, t := range all {
+			record := []string{
+				t.ReviewID,
+				t.AppID,
+				t.Status,
+				t.Assignee,
+				strings.Join(t.Tags, ";"),
+				t.Notes,
+				t.UpdatedAt.Format(time.RFC3339),
+			}
+			if err := writer.Write(record); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return db.bumpVersion(ctx, triage.AppID)
+}
+
+// readTriage reads every triage row. A missing file means nothing has been
+// triaged yet.
+func (db *DB) readTriage() ([]model.Triage, error) {
+	reader, file, err := getReader(db.config().TriageCSV)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	rows, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+
+	var all []model.Triage
+	for _, row := range records(rows, triageHeader) {
+		if len(row) <= COLUMN_TRIAGE_UPDATED_AT {
+			continue
+		}
+		updatedAt, _ := time.Parse(time.RFC3339, row[COLUMN_TRIAGE_UPDATED_AT])
+		var tags []string
+		if row[COLUMN_TRIAGE_TAGS] != "" {
+			tags = strings.Split(row[COLUMN_TRIAGE_TAGS], ";")
+		}
+		all = append(all, model.Triage{
+			ReviewID:  row[COLUMN_TRIAGE_REVIEW_ID],
+			AppID:     row[COLUMN_TRIAGE_APP_ID],
+			Status:    row[COLUMN_TRIAGE_STATUS],
+			Assignee:  row[COLUMN_TRIAGE_ASSIGNEE],
+			Tags:      tags,
+			Notes:     row[COLUMN_TRIAGE_NOTES],
+			UpdatedAt: updatedAt,
+		})
+	}
+	return all, nil
+}
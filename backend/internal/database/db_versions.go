@@ -0,0 +1,138 @@
+package database
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"io/fs"
+	"strconv"
+	"time"
+
+	"github.com/furqanmk/reviews-browser/internal/model"
+)
+
+const (
+	COLUMN_VERSIONS_APP_ID = iota
+	COLUMN_VERSIONS_VERSION
+	COLUMN_VERSIONS_MODIFIED_AT
+	COLUMN_VERSIONS_OLDEST
+)
+
+var (
+	versionsHeader = []string{
+		"app_id",
+		"version",
+		"modified_at",
+		"oldest",
+	}
+)
+
+// GetDataVersion retrieves the data version of an app's reviews. An app whose
+// reviews haven't changed since versions were first kept gets version 0.
+func (db *DB) GetDataVersion(ctx context.Context, appID string) (model.DataVersion, error) {
+	defer observe(ctx, "get_data_version")()
+
+	versions, err := db.readVersions()
+	if err != nil {
+		return model.DataVersion{}, err
+	}
+	for _, v := range versions {
+		if v.AppID == appID {
+			return v, nil
+		}
+	}
+	return model.DataVersion{AppID: appID}, nil
+}
+
+// bumpVersions records a change to the reviews of each app in recent, which
+// maps the app to the reviews it has within the recency cutoff after the
+// change. Callers hold db.mu.
+func (db *DB) bumpVersions(recent map[string][]model.Review) error {
+	versions, err := db.readVersions()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	for appID, reviews := range recent {
+		var oldest time.Time
+		for _, review := range reviews {
+			if oldest.IsZero() || review.CreatedAt.Before(oldest) {
+				oldest = review.CreatedAt
+			}
+		}
+
+		bumped := model.DataVersion{AppID: appID, Version: 1, ModifiedAt: now, Oldest: oldest}
+		found := false
+		for i, v := range versions {
+			if v.AppID == appID {
+				bumped.Version = v.Version + 1
+				versions[i] = bumped
+				found = true
+				break
+			}
+		}
+		if !found {
+			versions = append(versions, bumped)
+		}
+	}
+	return db.writeVersions(versions)
+}
+
+// bumpVersion records a change to an app's reviews, reading the reviews it now
+// has within the recency cutoff. Callers hold db.mu.
+func (db *DB) bumpVersion(ctx context.Context, appID string) error {
+	reviews, err := db.GetRecentReviews(ctx, appID)
+	if err != nil {
+		return err
+	}
+	return db.bumpVersions(map[string][]model.Review{appID: reviews})
+}
+
+// readVersions reads every data version. A missing file means no app's
+// reviews have changed yet.
+func (db *DB) readVersions() ([]model.DataVersion, error) {
+	rows, err := readAll(db.config().VersionsCSV)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var versions []model.DataVersion
+	for _, row := range records(rows, versionsHeader) {
+		if len(row) <= COLUMN_VERSIONS_OLDEST {
+			continue
+		}
+		version, err := strconv.ParseInt(row[COLUMN_VERSIONS_VERSION], 10, 64)
+		if err != nil {
+			continue
+		}
+		versions = append(versions, model.DataVersion{
+			AppID:      row[COLUMN_VERSIONS_APP_ID],
+			Version:    version,
+			ModifiedAt: optionalTime(row, COLUMN_VERSIONS_MODIFIED_AT),
+			Oldest:     optionalTime(row, COLUMN_VERSIONS_OLDEST),
+		})
+	}
+	return versions, nil
+}
+
+// writeVersions replaces the versions table. Callers hold db.mu.
+func (db *DB) writeVersions(versions []model.DataVersion) error {
+	return rewriteFile(db.config().VersionsCSV, versionsHeader, func(writer *csv.Writer) error {
+		for _, v := range versions {
+			record := []string{
+				v.AppID,
+				strconv.FormatInt(v.Version, 10),
+				v.ModifiedAt.Format(time.RFC3339Nano),
+				formatOptionalTime(v.Oldest),
+			}
+			if err := writer.Write(record); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
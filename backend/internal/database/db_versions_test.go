@@ -0,0 +1,78 @@
+package database_test
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/furqanmk/reviews-browser/internal/model"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInsertReview_VersionGoesUp(t *testing.T) {
+	ctx := context.Background()
+	db := newDB(t)
+	const inserts = 100
+
+	// a reader polling the version must only ever see it go up
+	done := make(chan struct{})
+	watched := make(chan error, 1)
+	go func() {
+		var last int64
+		for {
+			select {
+			case <-done:
+				watched <- nil
+				return
+			default:
+			}
+			v, err := db.GetDataVersion(ctx, "1")
+			if err != nil {
+				watched <- err
+				return
+			}
+			if v.Version < last {
+				watched <- fmt.Errorf("version went from %d back to %d", last, v.Version)
+				return
+			}
+			last = v.Version
+		}
+	}()
+
+	now := time.Now().UTC().Truncate(time.Second)
+	var wg sync.WaitGroup
+	for i := range inserts {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			review := model.Review{ID: fmt.Sprint(i), Author: fmt.Sprint("author ", i), Content: fmt.Sprint("review number ", i), Rating: 4, CreatedAt: now}
+			before, err := db.GetDataVersion(ctx, "1")
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if _, err := db.InsertReview(ctx, review, "1"); err != nil {
+				t.Error(err)
+				return
+			}
+			after, err := db.GetDataVersion(ctx, "1")
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if after.Version <= before.Version {
+				t.Errorf("inserting review %d left the version at %d", i, after.Version)
+			}
+		}()
+	}
+	wg.Wait()
+	close(done)
+	require.NoError(t, <-watched)
+
+	v, err := db.GetDataVersion(ctx, "1")
+	require.NoError(t, err)
+	require.Equal(t, int64(inserts), v.Version, "every insert bumps the version once")
+	require.Equal(t, now, v.Oldest)
+}
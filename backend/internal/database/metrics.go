@@ -0,0 +1,41 @@
+package database
+
+import (
+	"context"
+	"time"
+
+	"github.com/furqanmk/reviews-browser/internal/logging"
+	"github.com/furqanmk/reviews-browser/internal/tracing"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	logger = logging.For("database")
+	tracer = tracing.Tracer("database")
+)
+
+var operationDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+	Namespace: "reviews_browser",
+	Subsystem: "store",
+	Name:      "operation_duration_seconds",
+	Help:      "Time taken by store operations, by operation.",
+	Buckets:   prometheus.DefBuckets,
+}, []string{"operation"})
+
+// observe starts timing a store operation; call the result when it finishes:
+//
+//	defer observe(ctx, "get_apps")()
+//
+// The operation is also traced as a child of ctx's span, and logged at debug
+// level with the attributes ctx carries.
+func observe(ctx context.Context, operation string) func() {
+	start := time.Now()
+	ctx, span := tracer.Start(ctx, "store."+operation)
+	return func() {
+		span.End()
+		elapsed := time.Since(start)
+		operationDuration.WithLabelValues(operation).Observe(elapsed.Seconds())
+		logger.DebugContext(ctx, "store operation", "operation", operation, "duration", elapsed)
+	}
+}
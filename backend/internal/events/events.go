@@ -0,0 +1,72 @@
+package events
+
+import (
+	"sync"
+	"time"
+)
+
+// Event types.
+const (
+	// ReviewsIngested is published when new reviews for an app are stored.
+	ReviewsIngested = "reviews.ingested"
+)
+
+// Event is a notification that something changed in the store.
+type Event struct {
+	Type  string    `json:"type"`
+	AppID string    `json:"app_id"`
+	Count int       `json:"count"`
+	At    time.Time `json:"at"`
+}
+
+// Bus fans events out to in-process subscribers. Publishing never blocks: a
+// subscriber that falls behind its buffer misses events rather than stalling
+// the publisher.
+type Bus struct {
+	mu     sync.Mutex
+	nextID int
+	subs   map[int]chan Event
+}
+
+// NewBus creates an event bus with no subscribers.
+func NewBus() *Bus {
+	return &Bus{subs: make(map[int]chan Event)}
+}
+
+// Publish delivers an event to every subscriber with room in its buffer.
+func (b *Bus) Publish(event Event) {
+	if event.At.IsZero() {
+		event.At = time.Now()
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, ch := range b.subs {
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+}
+
+// Subscribe returns a channel of events and a function that unsubscribes and
+// closes it.
+func (b *Bus) Subscribe(buffer int) (<-chan Event, func()) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	id := b.nextID
+	b.nextID++
+	ch := make(chan Event, buffer)
+	b.subs[id] = ch
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			b.mu.Lock()
+			defer b.mu.Unlock()
+			delete(b.subs, id)
+			close(ch)
+		})
+	}
+}
@@ -0,0 +1,33 @@
+package events_test
+
+import (
+	"testing"
+
+	"github.com/furqanmk/reviews-browser/internal/events"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBus(t *testing.T) {
+	bus := events.NewBus()
+	a, unsubscribeA := bus.Subscribe(1)
+	b, unsubscribeB := bus.Subscribe(1)
+
+	bus.Publish(events.Event{Type: events.ReviewsIngested, AppID: "123", Count: 2})
+	require.Equal(t, "123", (<-a).AppID)
+	require.Equal(t, "123", (<-b).AppID)
+
+	// a full subscriber misses events instead of blocking the publisher
+	bus.Publish(events.Event{AppID: "1"})
+	bus.Publish(events.Event{AppID: "2"})
+	require.Equal(t, "1", (<-a).AppID)
+	require.Empty(t, a)
+
+	unsubscribeB()
+	unsubscribeB()
+	require.Equal(t, "1", (<-b).AppID)
+	_, open := <-b
+	require.False(t, open)
+
+	unsubscribeA()
+	bus.Publish(events.Event{AppID: "3"})
+}
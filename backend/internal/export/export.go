@@ -0,0 +1,379 @@
+// Package export writes reviews out for spreadsheets and notebooks, one review
+// at a time, so exports of any size are streamed rather than held in memory.
+package export
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"iter"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/furqanmk/reviews-browser/internal/model"
+)
+
+// Formats.
+const (
+	FormatCSV = "csv"
+	// FormatExcel is CSV as spreadsheets expect it: with a UTF-8 BOM and CRLF
+	// line endings, and text that would be read as a formula escaped.
+	FormatExcel  = "excel"
+	FormatNDJSON = "ndjson"
+	// FormatJSON is a single JSON array.
+	FormatJSON = "json"
+)
+
+// bom is the UTF-8 byte order mark, which tells spreadsheets the encoding.
+const bom = "\uFEFF"
+
+// Formats lists every format.
+var Formats = []string{FormatCSV, FormatExcel, FormatNDJSON, FormatJSON}
+
+// column is a field of an exported review.
+type column struct {
+	name  string
+	value func(review model.Review) any
+	// restricted columns hold the unredacted text, for elevated callers only.
+	restricted bool
+}
+
+var columns = []column{
+	{name: "id", value: func(r model.Review) any { return r.ID }},
+	{name: "app_id", value: func(r model.Review) any { return r.AppID }},
+	{name: "author", value: func(r model.Review) any { return r.Author }},
+	{name: "title", value: func(r model.Review) any { return r.Title }},
+	{name: "content", value: func(r model.Review) any { return r.Content }},
+	{name: "rating", value: func(r model.Review) any { return r.Rating }},
+	{name: "created_at", value: func(r model.Review) any { return r.CreatedAt }},
+	{name: "language", value: func(r model.Review) any { return r.Language }},
+	{name: "suspected_spam", value: func(r model.Review) any { return r.SuspectedSpam }},
+	{name: "spam_reason", value: func(r model.Review) any { return r.SpamReason }},
+	{name: "redactions", value: func(r model.Review) any { return list(r.Redactions) }},
+	{name: "triage_status", value: func(r model.Review) any { return triage(r).Status }},
+	{name: "triage_assignee", value: func(r model.Review) any { return triage(r).Assignee }},
+	{name: "triage_tags", value: func(r model.Review) any { return list(triage(r).Tags) }},
+	{name: "triage_notes", value: func(r model.Review) any { return triage(r).Notes }},
+	{name: "original_title", restricted: true, value: func(r model.Review) any { return original(r).Title }},
+	{name: "original_content", restricted: true, value: func(r model.Review) any { return original(r).Content }},
+}
+
+func triage(r model.Review) model.Triage {
+	if r.Triage == nil {
+		return model.Triage{Status: model.TriageNew}
+	}
+	return *r.Triage
+}
+
+func original(r model.Review) model.OriginalText {
+	if r.Original == nil {
+		return model.OriginalText{}
+	}
+	return *r.Original
+}
+
+// list keeps empty lists from being exported as null.
+func list(items []string) []string {
+	if items == nil {
+		return []string{}
+	}
+	return items
+}
+
+// Columns returns the names of the columns that may be exported, in their
+// default order, leaving out the restricted ones unless restricted is set.
+func Columns(restricted bool) []string {
+	var names []string
+	for _, c := range columns {
+		if restricted || !c.restricted {
+			names = append(names, c.name)
+		}
+	}
+	return names
+}
+
+// ParseColumns parses a comma-separated list of column names, giving every
+// column open to the caller if the list is empty.
+func ParseColumns(list string, restricted bool) ([]string, error) {
+	if strings.TrimSpace(list) == "" {
+		return Columns(restricted), nil
+	}
+	var names []string
+	for _, name := range strings.Split(list, ",") {
+		name = strings.TrimSpace(name)
+		i := slices.IndexFunc(columns, func(c column) bool { return c.name == name })
+		switch {
+		case i < 0:
+			return nil, fmt.Errorf("unknown column %q", name)
+		case columns[i].restricted && !restricted:
+			return nil, fmt.Errorf("column %q needs an admin key", name)
+		case slices.Contains(names, name):
+			return nil, fmt.Errorf("column %q is listed twice", name)
+		}
+		names = append(names, name)
+	}
+	return names, nil
+}
+
+// Options configure a Writer.
+type Options struct {
+	Format string
+	// Columns are the columns to write, in order; see ParseColumns.
+	Columns []string
+	// BOM starts CSV with a UTF-8 byte order mark. Excel output always has
+	// one.
+	BOM bool
+}
+
+// ContentType returns the media type of the output.
+func (o Options) ContentType() string {
+	switch o.Format {
+	case FormatNDJSON:
+		return "application/x-ndjson"
+	case FormatJSON:
+		return "application/json"
+	}
+	return "text/csv; charset=utf-8"
+}
+
+// Extension returns the file extension for the output, without the dot.
+func (o Options) Extension() string {
+	if o.Format == FormatExcel {
+		return FormatCSV
+	}
+	return o.Format
+}
+
+// Writer writes reviews out in one format. Nothing is written until the first
+// review, or Close for an export with none, so a caller can still report an
+// error instead if the export fails before then.
+type Writer struct {
+	w       io.Writer
+	opts    Options
+	columns []column
+	csv     *csv.Writer
+	started bool
+	count   int
+}
+
+// NewWriter creates a Writer of opts.Format to w.
+func NewWriter(w io.Writer, opts Options) (*Writer, error) {
+	if !slices.Contains(Formats, opts.Format) {
+		return nil, fmt.Errorf("unknown format %q, want one of %s", opts.Format, strings.Join(Formats, ", "))
+	}
+	if opts.BOM && opts.Format != FormatCSV && opts.Format != FormatExcel {
+		return nil, fmt.Errorf("a byte order mark only applies to CSV")
+	}
+	if len(opts.Columns) == 0 {
+		opts.Columns = Columns(false)
+	}
+
+	ew := &Writer{w: w, opts: opts}
+	for _, name := range opts.Columns {
+		i := slices.IndexFunc(columns, func(c column) bool { return c.name == name })
+		if i < 0 {
+			return nil, fmt.Errorf("unknown column %q", name)
+		}
+		ew.columns = append(ew.columns, columns[i])
+	}
+	if opts.Format == FormatCSV || opts.Format == FormatExcel {
+		ew.csv = csv.NewWriter(w)
+		ew.csv.UseCRLF = opts.Format == FormatExcel
+	}
+	return ew, nil
+}
+
+// Count returns how many reviews have been written.
+func (w *Writer) Count() int {
+	return w.count
+}
+
+// Write writes a review.
+func (w *Writer) Write(review model.Review) error {
+	w.count++
+	if err := w.start(); err != nil {
+		return err
+	}
+
+	if w.csv != nil {
+		record := make([]string, len(w.columns))
+		for i, c := range w.columns {
+			record[i] = w.cell(c.value(review))
+		}
+		return w.csv.Write(record)
+	}
+
+	var b strings.Builder
+	if w.opts.Format == FormatJSON && w.count > 1 {
+		b.WriteString(",\n")
+	}
+	b.WriteByte('{')
+	for i, c := range w.columns {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		name, _ := json.Marshal(c.name)
+		value, err := json.Marshal(c.value(review))
+		if err != nil {
+			return err
+		}
+		b.Write(name)
+		b.WriteByte(':')
+		b.Write(value)
+	}
+	b.WriteByte('}')
+	if w.opts.Format == FormatNDJSON {
+		b.WriteByte('\n')
+	}
+	_, err := io.WriteString(w.w, b.String())
+	return err
+}
+
+// Close finishes the output, and must be called once all reviews are written.
+func (w *Writer) Close() error {
+	if err := w.start(); err != nil {
+		return err
+	}
+	if w.csv != nil {
+		w.csv.Flush()
+		return w.csv.Error()
+	}
+	if w.opts.Format == FormatJSON {
+		end := "\n]\n"
+		if w.count == 0 {
+			end = "]\n"
+		}
+		_, err := io.WriteString(w.w, end)
+		return err
+	}
+	return nil
+}
+
+// start writes whatever comes before the first review.
+func (w *Writer) start() error {
+	if w.started {
+		return nil
+	}
+	w.started = true
+
+	switch {
+	case w.csv != nil:
+		if w.opts.BOM || w.opts.Format == FormatExcel {
+			if _, err := io.WriteString(w.w, bom); err != nil {
+				return err
+			}
+		}
+		header := make([]string, len(w.columns))
+		for i, c := range w.columns {
+			header[i] = c.name
+		}
+		return w.csv.Write(header)
+	case w.opts.Format == FormatJSON:
+		start := "[\n"
+		if w.count == 0 {
+			start = "["
+		}
+		_, err := io.WriteString(w.w, start)
+		return err
+	}
+	return nil
+}
+
+// cell formats a value for CSV. Lists are joined with ";", as the store keeps
+// them.
+func (w *Writer) cell(value any) string {
+	switch v := value.(type) {
+	case string:
+		if w.opts.Format == FormatExcel {
+			return escapeFormula(v)
+		}
+		return v
+	case int:
+		return strconv.Itoa(v)
+	case bool:
+		return strconv.FormatBool(v)
+	case time.Time:
+		return v.Format(time.RFC3339)
+	case []string:
+		if w.opts.Format == FormatExcel {
+			return escapeFormula(strings.Join(v, ";"))
+		}
+		return strings.Join(v, ";")
+	}
+	return fmt.Sprint(value)
+}
+
+// escapeFormula stops a spreadsheet from evaluating text that starts like a
+// formula, as review text is written by anyone, by prefixing it with a quote.
+func escapeFormula(s string) string {
+	if s != "" && strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
+
+// Filter selects the reviews to export. Zero fields match every review.
+type Filter struct {
+	Language    string
+	Status      string
+	Assignee    string
+	Tag         string
+	ExcludeSpam bool
+}
+
+// Match reports whether a review with its triage attached passes the filter.
+func (f Filter) Match(review model.Review) bool {
+	t := triage(review)
+	switch {
+	case f.Language != "" && review.Language != f.Language,
+		f.Status != "" && t.Status != f.Status,
+		f.Assignee != "" && t.Assignee != f.Assignee,
+		f.Tag != "" && !slices.Contains(t.Tags, f.Tag),
+		f.ExcludeSpam && review.SuspectedSpam:
+		return false
+	}
+	return true
+}
+
+// Source is a store to export reviews from.
+type Source interface {
+	Reviews(ctx context.Context, appIDs ...string) iter.Seq2[model.Review, error]
+	GetTriage(ctx context.Context, appID string) (map[string]model.Triage, error)
+}
+
+// Export streams the recent reviews of the given apps, or every app if none
+// are given, that pass the filter to w, in the order they were stored, with
+// their triage attached. It doesn't close w.
+func Export(ctx context.Context, src Source, w *Writer, appIDs []string, filter Filter) error {
+	triaged := make(map[string]map[string]model.Triage)
+	for review, err := range src.Reviews(ctx, appIDs...) {
+		if err != nil {
+			return err
+		}
+
+		byReview, ok := triaged[review.AppID]
+		if !ok {
+			if byReview, err = src.GetTriage(ctx, review.AppID); err != nil {
+				return err
+			}
+			triaged[review.AppID] = byReview
+		}
+		t, ok := byReview[review.ID]
+		if !ok {
+			t = model.Triage{ReviewID: review.ID, AppID: review.AppID, Status: model.TriageNew}
+		}
+		review.Triage = &t
+
+		if !filter.Match(review) {
+			continue
+		}
+		if err := w.Write(review); err != nil {
+			return err
+		}
+	}
+	return nil
+}
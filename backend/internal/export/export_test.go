@@ -0,0 +1,177 @@
+package export_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"iter"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/furqanmk/reviews-browser/internal/export"
+	"github.com/furqanmk/reviews-browser/internal/model"
+	"github.com/stretchr/testify/require"
+)
+
+type source struct {
+	reviews []model.Review
+	triage  map[string]model.Triage
+	err     error
+}
+
+func (s *source) Reviews(ctx context.Context, appIDs ...string) iter.Seq2[model.Review, error] {
+	return func(yield func(model.Review, error) bool) {
+		for _, review := range s.reviews {
+			if len(appIDs) > 0 && !slices.Contains(appIDs, review.AppID) {
+				continue
+			}
+			if !yield(review, nil) {
+				return
+			}
+		}
+		if s.err != nil {
+			yield(model.Review{}, s.err)
+		}
+	}
+}
+
+func (s *source) GetTriage(ctx context.Context, appID string) (map[string]model.Triage, error) {
+	return s.triage, nil
+}
+
+func testSource() *source {
+	created := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	return &source{
+		reviews: []model.Review{
+			{ID: "1", AppID: "100", Author: "Ann", Title: "Great, \"really\"", Content: "line one\nline two", Rating: 5, CreatedAt: created, Language: "en"},
+			{ID: "2", AppID: "200", Author: "Bob", Title: "=HYPERLINK(\"x\")", Content: "-1 star", Rating: 1, CreatedAt: created, Language: "de", SuspectedSpam: true, SpamReason: "links"},
+			{ID: "3", AppID: "100", Author: "Cy", Title: "Fine", Content: "ok", Rating: 3, CreatedAt: created, Language: "en"},
+		},
+		triage: map[string]model.Triage{
+			"3": {ReviewID: "3", AppID: "100", Status: model.TriageResolved, Assignee: "dana", Tags: []string{"ux", "crash"}},
+		},
+	}
+}
+
+func run(t *testing.T, src *source, opts export.Options, appIDs []string, filter export.Filter) (string, int) {
+	t.Helper()
+	var buf bytes.Buffer
+	w, err := export.NewWriter(&buf, opts)
+	require.NoError(t, err)
+	require.NoError(t, export.Export(context.Background(), src, w, appIDs, filter))
+	require.NoError(t, w.Close())
+	return buf.String(), w.Count()
+}
+
+func TestExport_CSV(t *testing.T) {
+	out, count := run(t, testSource(), export.Options{Format: export.FormatCSV, Columns: []string{"id", "title", "content", "triage_tags"}}, nil, export.Filter{})
+	require.Equal(t, 3, count)
+	require.Equal(t, "id,title,content,triage_tags\n"+
+		"1,\"Great, \"\"really\"\"\",\"line one\nline two\",\n"+
+		"2,\"=HYPERLINK(\"\"x\"\")\",-1 star,\n"+
+		"3,Fine,ok,ux;crash\n", out)
+
+	out, _ = run(t, testSource(), export.Options{Format: export.FormatCSV, Columns: []string{"id"}, BOM: true}, []string{"200"}, export.Filter{})
+	require.Equal(t, "\uFEFFid\n2\n", out)
+}
+
+func TestExport_Excel(t *testing.T) {
+	out, _ := run(t, testSource(), export.Options{Format: export.FormatExcel, Columns: []string{"id", "title", "content"}}, []string{"200"}, export.Filter{})
+	require.Equal(t, "\uFEFFid,title,content\r\n2,\"'=HYPERLINK(\"\"x\"\")\",'-1 star\r\n", out)
+}
+
+func TestExport_NDJSON(t *testing.T) {
+	out, count := run(t, testSource(), export.Options{Format: export.FormatNDJSON}, []string{"100"}, export.Filter{})
+	require.Equal(t, 2, count)
+
+	lines := strings.Split(strings.TrimSuffix(out, "\n"), "\n")
+	require.Len(t, lines, 2)
+	var row map[string]any
+	require.NoError(t, json.Unmarshal([]byte(lines[1]), &row))
+	require.Equal(t, "3", row["id"])
+	require.Equal(t, float64(3), row["rating"])
+	require.Equal(t, "2026-03-01T12:00:00Z", row["created_at"])
+	require.Equal(t, "resolved", row["triage_status"])
+	require.Equal(t, []any{"ux", "crash"}, row["triage_tags"])
+	require.NotContains(t, row, "original_content", "restricted columns are left out by default")
+
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &row))
+	require.Equal(t, "new", row["triage_status"], "untriaged reviews are new")
+	require.Equal(t, []any{}, row["redactions"])
+}
+
+func TestExport_JSON(t *testing.T) {
+	out, _ := run(t, testSource(), export.Options{Format: export.FormatJSON, Columns: []string{"id", "suspected_spam"}}, nil, export.Filter{})
+	var rows []map[string]any
+	require.NoError(t, json.Unmarshal([]byte(out), &rows))
+	require.Equal(t, []map[string]any{
+		{"id": "1", "suspected_spam": false},
+		{"id": "2", "suspected_spam": true},
+		{"id": "3", "suspected_spam": false},
+	}, rows)
+
+	out, count := run(t, testSource(), export.Options{Format: export.FormatJSON}, []string{"999"}, export.Filter{})
+	require.Zero(t, count)
+	require.Equal(t, "[]\n", out)
+}
+
+func TestExport_Filter(t *testing.T) {
+	cols := []string{"id"}
+	out, _ := run(t, testSource(), export.Options{Format: export.FormatCSV, Columns: cols}, nil, export.Filter{Language: "en"})
+	require.Equal(t, "id\n1\n3\n", out)
+	out, _ = run(t, testSource(), export.Options{Format: export.FormatCSV, Columns: cols}, nil, export.Filter{ExcludeSpam: true})
+	require.Equal(t, "id\n1\n3\n", out)
+	out, _ = run(t, testSource(), export.Options{Format: export.FormatCSV, Columns: cols}, nil, export.Filter{Status: model.TriageNew})
+	require.Equal(t, "id\n1\n2\n", out)
+	out, _ = run(t, testSource(), export.Options{Format: export.FormatCSV, Columns: cols}, nil, export.Filter{Assignee: "dana", Tag: "crash"})
+	require.Equal(t, "id\n3\n", out)
+}
+
+func TestExport_Error(t *testing.T) {
+	src := testSource()
+	src.err = errors.New("disk gone")
+
+	var buf bytes.Buffer
+	w, err := export.NewWriter(&buf, export.Options{Format: export.FormatNDJSON})
+	require.NoError(t, err)
+	require.ErrorIs(t, export.Export(context.Background(), src, w, nil, export.Filter{}), src.err)
+	require.Equal(t, 3, w.Count(), "reviews before the failure are written")
+}
+
+func TestParseColumns(t *testing.T) {
+	cols, err := export.ParseColumns("", false)
+	require.NoError(t, err)
+	require.Equal(t, export.Columns(false), cols)
+	require.NotContains(t, cols, "original_title")
+	require.Contains(t, export.Columns(true), "original_title")
+
+	cols, err = export.ParseColumns(" rating, id ", false)
+	require.NoError(t, err)
+	require.Equal(t, []string{"rating", "id"}, cols)
+
+	_, err = export.ParseColumns("id,bogus", false)
+	require.ErrorContains(t, err, "unknown column")
+	_, err = export.ParseColumns("original_content", false)
+	require.ErrorContains(t, err, "admin")
+	_, err = export.ParseColumns("original_content", true)
+	require.NoError(t, err)
+	_, err = export.ParseColumns("id,id", false)
+	require.ErrorContains(t, err, "twice")
+}
+
+func TestNewWriter(t *testing.T) {
+	_, err := export.NewWriter(&bytes.Buffer{}, export.Options{Format: "xlsx"})
+	require.ErrorContains(t, err, "unknown format")
+	_, err = export.NewWriter(&bytes.Buffer{}, export.Options{Format: export.FormatJSON, BOM: true})
+	require.Error(t, err)
+
+	opts := export.Options{Format: export.FormatExcel}
+	require.Equal(t, "text/csv; charset=utf-8", opts.ContentType())
+	require.Equal(t, "csv", opts.Extension())
+	opts.Format = export.FormatNDJSON
+	require.Equal(t, "application/x-ndjson", opts.ContentType())
+	require.Equal(t, "ndjson", opts.Extension())
+}
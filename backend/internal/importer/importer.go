@@ -0,0 +1,422 @@
+// Package importer loads reviews from CSV or NDJSON files, such as dumps made
+// before an app was tracked, through the ingestion pipeline, so they are
+// redacted, tagged and deduplicated like polled reviews.
+package importer
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/furqanmk/reviews-browser/internal/ingest"
+	"github.com/furqanmk/reviews-browser/internal/model"
+	"github.com/furqanmk/reviews-browser/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = tracing.Tracer("importer")
+
+// Formats.
+const (
+	FormatCSV    = "csv"
+	FormatNDJSON = "ndjson"
+)
+
+// Formats lists every format.
+var Formats = []string{FormatCSV, FormatNDJSON}
+
+// Fields of a review that are read from a file. Fields are read from the
+// column, or NDJSON key, of the same name unless mapped to another.
+var Fields = []string{"id", "app_id", "author", "title", "content", "rating", "created_at"}
+
+// bom is the UTF-8 byte order mark some tools start files with.
+const bom = "\uFEFF"
+
+// required are the fields every file must have a column for. Without an
+// app_id column, Options.AppID must be given.
+var required = []string{"id", "rating", "created_at"}
+
+// timeLayouts are the forms created_at may take, tried in order. Times
+// without a zone are UTC.
+var timeLayouts = []string{time.RFC3339, "2006-01-02 15:04:05", "2006-01-02"}
+
+const (
+	// batchSize is how many rows are read before the valid ones are stored.
+	batchSize = 1000
+	// maxProblems is how many problems a report lists. All are counted.
+	maxProblems = 100
+	// maxLine is the longest NDJSON line that can be read.
+	maxLine = 1 << 20
+)
+
+// Ingester stores reviews, as ingest.Pipeline does.
+type Ingester interface {
+	Ingest(ctx context.Context, appID string, reviews []model.Review) (ingest.Result, error)
+}
+
+// Options configure an import.
+type Options struct {
+	Format string
+	// AppID is the app of rows without an app_id.
+	AppID string
+	// Mapping maps fields to the columns holding them; see ParseMapping.
+	Mapping map[string]string
+	// DryRun validates the file without storing anything.
+	DryRun bool
+	// Cutoff is the recency cutoff. Reviews older than it aren't imported,
+	// since reads leave them out and the next cleanup would remove them. Zero
+	// imports reviews however old.
+	Cutoff time.Duration
+	// Progress, if set, is called with the report so far after every batch.
+	Progress func(Report)
+}
+
+// Problem is a row that couldn't be imported.
+type Problem struct {
+	// Line is the row's line in the file, or 0 for a problem storing a batch.
+	Line   int    `json:"line,omitempty"`
+	ID     string `json:"id,omitempty"`
+	Reason string `json:"reason"`
+}
+
+// Report describes an import.
+type Report struct {
+	DryRun  bool `json:"dry_run"`
+	Rows    int  `json:"rows"`
+	Valid   int  `json:"valid"`
+	Invalid int  `json:"invalid"`
+	// New, Duplicate and Failed count what happened to the valid rows when
+	// they were stored. They stay zero on a dry run.
+	New       int `json:"new"`
+	Duplicate int `json:"duplicate"`
+	Failed    int `json:"failed"`
+	// Stale counts valid reviews older than the recency cutoff, which aren't
+	// imported; see Options.Cutoff.
+	Stale int `json:"stale"`
+	// Problems lists the first invalid rows and storage failures.
+	Problems []Problem `json:"problems"`
+}
+
+func (r *Report) problem(p Problem) {
+	if len(r.Problems) < maxProblems {
+		r.Problems = append(r.Problems, p)
+	}
+}
+
+// ParseMapping parses a comma-separated list of field=column pairs, such as
+// "created_at=date,content=body".
+func ParseMapping(list string) (map[string]string, error) {
+	mapping := make(map[string]string)
+	if strings.TrimSpace(list) == "" {
+		return mapping, nil
+	}
+	for _, pair := range strings.Split(list, ",") {
+		field, column, ok := strings.Cut(pair, "=")
+		field, column = strings.TrimSpace(field), strings.TrimSpace(column)
+		switch {
+		case !ok || column == "":
+			return nil, fmt.Errorf("mapping %q is not field=column", pair)
+		case !slices.Contains(Fields, field):
+			return nil, fmt.Errorf("unknown field %q, want one of %s", field, strings.Join(Fields, ", "))
+		case mapping[field] != "":
+			return nil, fmt.Errorf("field %q is mapped twice", field)
+		}
+		mapping[field] = column
+	}
+	return mapping, nil
+}
+
+// FormatOf guesses a file's format from its name: NDJSON for .ndjson and
+// .jsonl, CSV otherwise.
+func FormatOf(name string) string {
+	name = strings.ToLower(name)
+	if strings.HasSuffix(name, ".ndjson") || strings.HasSuffix(name, ".jsonl") {
+		return FormatNDJSON
+	}
+	return FormatCSV
+}
+
+// row is a record read from a file, with its fields picked out.
+type row struct {
+	line   int
+	fields map[string]string
+	// err is why the record couldn't be read. A fatal error means nothing
+	// more can be read.
+	err   error
+	fatal bool
+}
+
+// Import reads reviews from r, validates them and, unless it's a dry run,
+// stores the valid ones a batch at a time. Invalid rows are reported and
+// skipped, as are rows repeating an earlier row's review. It fails only if the
+// file can't be read at all, its columns don't cover the required fields, or
+// ctx ends, returning the report so far.
+func Import(ctx context.Context, r io.Reader, store Ingester, opts Options) (_ Report, err error) {
+	ctx, span := tracer.Start(ctx, "importer.Import",
+		trace.WithAttributes(attribute.String("format", opts.Format), attribute.Bool("dry_run", opts.DryRun)))
+	defer func() { tracing.End(span, err) }()
+
+	report := Report{DryRun: opts.DryRun, Problems: []Problem{}}
+	rows, err := readRows(r, opts)
+	if err != nil {
+		return report, err
+	}
+
+	var (
+		now   = time.Now()
+		seen  = make(map[string]int) // app and review ID to the line first seen on
+		batch = make(map[string][]model.Review)
+		order []string // apps in the order their reviews were first batched
+		size  int
+	)
+	flush := func() {
+		for _, appID := range order {
+			if !opts.DryRun {
+				result, err := store.Ingest(ctx, appID, batch[appID])
+				report.New += result.New
+				report.Duplicate += result.Duplicate
+				report.Failed += result.Failed
+				report.Stale += result.Stale
+				if err != nil {
+					report.problem(Problem{Reason: fmt.Sprintf("storing reviews of app %s: %v", appID, err)})
+				}
+			}
+			delete(batch, appID)
+		}
+		order, size = order[:0], 0
+		if opts.Progress != nil {
+			opts.Progress(report)
+		}
+	}
+
+	for row := range rows {
+		if err := ctx.Err(); err != nil {
+			return report, err
+		}
+		if row.fatal {
+			// keep what was read before
+			flush()
+			return report, fmt.Errorf("reading line %d: %w", row.line, row.err)
+		}
+		report.Rows++
+		review, err := row.review(opts.AppID)
+		if err == nil {
+			key := review.AppID + "/" + review.ID
+			if first, ok := seen[key]; ok {
+				err = fmt.Errorf("repeats the review on line %d", first)
+			} else {
+				seen[key] = row.line
+			}
+		}
+		if err != nil {
+			report.Invalid++
+			report.problem(Problem{Line: row.line, ID: row.fields["id"], Reason: err.Error()})
+			continue
+		}
+
+		report.Valid++
+		if opts.Cutoff > 0 && now.Sub(review.CreatedAt) > opts.Cutoff {
+			report.Stale++
+			continue
+		}
+		if _, ok := batch[review.AppID]; !ok {
+			order = append(order, review.AppID)
+		}
+		batch[review.AppID] = append(batch[review.AppID], review)
+		if size++; size >= batchSize {
+			flush()
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return report, err
+	}
+	flush()
+
+	span.SetAttributes(attribute.Int("rows", report.Rows), attribute.Int("new", report.New))
+	return report, nil
+}
+
+// review validates a row and builds its review.
+func (r row) review(defaultApp string) (model.Review, error) {
+	if r.err != nil {
+		return model.Review{}, r.err
+	}
+	f := r.fields
+	review := model.Review{
+		ID:      strings.TrimSpace(f["id"]),
+		AppID:   strings.TrimSpace(f["app_id"]),
+		Author:  f["author"],
+		Title:   f["title"],
+		Content: f["content"],
+	}
+	if review.ID == "" {
+		return model.Review{}, errors.New("id is missing")
+	}
+	if review.AppID == "" {
+		review.AppID = defaultApp
+	}
+	if review.AppID == "" {
+		return model.Review{}, errors.New("app_id is missing")
+	}
+	if _, err := strconv.ParseUint(review.AppID, 10, 64); err != nil {
+		return model.Review{}, fmt.Errorf("app_id %q is not numeric", review.AppID)
+	}
+
+	rating, err := strconv.Atoi(strings.TrimSpace(f["rating"]))
+	if err != nil || rating < 1 || rating > 5 {
+		return model.Review{}, fmt.Errorf("rating %q is not a whole number from 1 to 5", f["rating"])
+	}
+	review.Rating = rating
+
+	created := strings.TrimSpace(f["created_at"])
+	for _, layout := range timeLayouts {
+		if t, err := time.Parse(layout, created); err == nil {
+			review.CreatedAt = t
+			break
+		}
+	}
+	if review.CreatedAt.IsZero() {
+		return model.Review{}, fmt.Errorf("created_at %q is not a date", created)
+	}
+	if review.CreatedAt.After(time.Now().Add(time.Hour)) {
+		return model.Review{}, fmt.Errorf("created_at %q is in the future", created)
+	}
+	return review, nil
+}
+
+// readRows checks that the file's columns cover the required fields and
+// returns its rows.
+func readRows(r io.Reader, opts Options) (iter.Seq[row], error) {
+	column := func(field string) string {
+		if c := opts.Mapping[field]; c != "" {
+			return c
+		}
+		return field
+	}
+	needed := required
+	if opts.AppID == "" {
+		needed = append(slices.Clone(required), "app_id")
+	}
+
+	switch opts.Format {
+	case FormatCSV:
+		return csvRows(r, column, needed)
+	case FormatNDJSON:
+		return ndjsonRows(r, column), nil
+	}
+	return nil, fmt.Errorf("unknown format %q, want one of %s", opts.Format, strings.Join(Formats, ", "))
+}
+
+func csvRows(r io.Reader, column func(string) string, needed []string) (iter.Seq[row], error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, errors.New("the file is empty")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading the header: %w", err)
+	}
+
+	// columns are matched ignoring case, and a byte order mark
+	index := make(map[string]int)
+	for i, name := range header {
+		if i == 0 {
+			name = strings.TrimPrefix(name, bom)
+		}
+		index[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	positions := make(map[string]int)
+	for _, field := range Fields {
+		if i, ok := index[strings.ToLower(column(field))]; ok {
+			positions[field] = i
+		} else if slices.Contains(needed, field) {
+			return nil, fmt.Errorf("no %q column for %s; map it with %s=<column>", column(field), field, field)
+		}
+	}
+
+	return func(yield func(row) bool) {
+		for {
+			record, err := reader.Read()
+			if err == io.EOF {
+				return
+			}
+			line, _ := reader.FieldPos(0)
+			var parseErr *csv.ParseError
+			if errors.As(err, &parseErr) {
+				if !yield(row{line: parseErr.StartLine, err: fmt.Errorf("unreadable row: %v", parseErr.Err)}) {
+					return
+				}
+				continue
+			}
+			if err != nil {
+				yield(row{line: line, err: err, fatal: true})
+				return
+			}
+
+			fields := make(map[string]string)
+			for field, i := range positions {
+				if i < len(record) {
+					fields[field] = record[i]
+				}
+			}
+			if !yield(row{line: line, fields: fields}) {
+				return
+			}
+		}
+	}, nil
+}
+
+func ndjsonRows(r io.Reader, column func(string) string) iter.Seq[row] {
+	return func(yield func(row) bool) {
+		scanner := bufio.NewScanner(r)
+		scanner.Buffer(make([]byte, 0, 64*1024), maxLine)
+		line := 0
+		for scanner.Scan() {
+			line++
+			text := strings.TrimSpace(scanner.Text())
+			if line == 1 {
+				text = strings.TrimPrefix(text, bom)
+			}
+			if text == "" {
+				continue
+			}
+
+			var object map[string]any
+			if err := json.Unmarshal([]byte(text), &object); err != nil {
+				if !yield(row{line: line, err: errors.New("not a JSON object")}) {
+					return
+				}
+				continue
+			}
+			fields := make(map[string]string)
+			for _, field := range Fields {
+				switch v := object[column(field)].(type) {
+				case string:
+					fields[field] = v
+				case float64:
+					fields[field] = strconv.FormatFloat(v, 'f', -1, 64)
+				case nil:
+				default:
+					fields[field] = fmt.Sprint(v)
+				}
+			}
+			if !yield(row{line: line, fields: fields}) {
+				return
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			yield(row{line: line + 1, err: err, fatal: true})
+		}
+	}
+}
@@ -0,0 +1,168 @@
+package importer_test
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/furqanmk/reviews-browser/internal/importer"
+	"github.com/furqanmk/reviews-browser/internal/ingest"
+	"github.com/furqanmk/reviews-browser/internal/model"
+	"github.com/stretchr/testify/require"
+)
+
+type mockStore struct {
+	inserted []model.Review
+}
+
+func (m *mockStore) InsertReviews(ctx context.Context, appID string, reviews []model.Review) (int, error) {
+	inserted := 0
+	for _, review := range reviews {
+		if slices.ContainsFunc(m.inserted, func(r model.Review) bool { return r.ID == review.ID && r.AppID == appID }) {
+			continue
+		}
+		review.AppID = appID
+		m.inserted = append(m.inserted, review)
+		inserted++
+	}
+	return inserted, nil
+}
+
+const dump = "\uFEFFID,App_ID,Author,Title,Body,Rating,Date\n" +
+	"1,100,Ann,Great,\"Loved it, really\",5,2021-03-04T05:06:07Z\n" +
+	"2,,Bob,Meh,Crashes,2,2021-03-05 10:00:00\n" +
+	"3,100,Cy,Bad,Nope,7,2021-03-05\n" +
+	"4,100,Di,Odd,Hm,3,last week\n" +
+	",100,Ed,None,No id,3,2021-03-05\n" +
+	"1,100,Ann,Great,Again,5,2021-03-04T05:06:07Z\n" +
+	"5,abc,Fay,Ok,Fine,4,2021-03-05\n"
+
+func TestImport_CSV(t *testing.T) {
+	store := &mockStore{}
+	pipeline := ingest.NewPipeline(store, nil, ingest.DetectLanguage)
+	opts := importer.Options{
+		Format:  importer.FormatCSV,
+		AppID:   "200",
+		Mapping: map[string]string{"content": "body", "created_at": "date"},
+	}
+
+	report, err := importer.Import(context.Background(), strings.NewReader(dump), pipeline, opts)
+	require.NoError(t, err)
+	require.Equal(t, 7, report.Rows)
+	require.Equal(t, 2, report.Valid)
+	require.Equal(t, 5, report.Invalid)
+	require.Equal(t, 2, report.New)
+	require.Equal(t, []importer.Problem{
+		{Line: 4, ID: "3", Reason: `rating "7" is not a whole number from 1 to 5`},
+		{Line: 5, ID: "4", Reason: `created_at "last week" is not a date`},
+		{Line: 6, Reason: "id is missing"},
+		{Line: 7, ID: "1", Reason: "repeats the review on line 2"},
+		{Line: 8, ID: "5", Reason: `app_id "abc" is not numeric`},
+	}, report.Problems)
+
+	require.Len(t, store.inserted, 2)
+	require.Equal(t, "100", store.inserted[0].AppID)
+	require.Equal(t, "Loved it, really", store.inserted[0].Content)
+	require.Equal(t, "en", store.inserted[0].Language, "imports go through the pipeline")
+	require.Equal(t, "200", store.inserted[1].AppID, "rows without an app get the default")
+	require.Equal(t, time.Date(2021, 3, 5, 10, 0, 0, 0, time.UTC), store.inserted[1].CreatedAt)
+
+	// importing again finds everything stored already
+	report, err = importer.Import(context.Background(), strings.NewReader(dump), pipeline, opts)
+	require.NoError(t, err)
+	require.Zero(t, report.New)
+	require.Equal(t, 2, report.Duplicate)
+}
+
+func TestImport_Stale(t *testing.T) {
+	store := &mockStore{}
+	now := time.Now().UTC()
+	file := "id,rating,created_at\n" +
+		"1,5," + now.Add(-time.Hour).Format(time.RFC3339) + "\n" +
+		"2,4," + now.Add(-72*time.Hour).Format(time.RFC3339) + "\n"
+	opts := importer.Options{Format: importer.FormatCSV, AppID: "100", Cutoff: 48 * time.Hour, DryRun: true}
+
+	report, err := importer.Import(context.Background(), strings.NewReader(file), ingest.NewPipeline(store, nil), opts)
+	require.NoError(t, err)
+	require.Equal(t, 2, report.Valid)
+	require.Equal(t, 1, report.Stale)
+
+	opts.DryRun = false
+	report, err = importer.Import(context.Background(), strings.NewReader(file), ingest.NewPipeline(store, nil), opts)
+	require.NoError(t, err)
+	require.Equal(t, 1, report.New)
+	require.Equal(t, 1, report.Stale, "reviews past the cutoff aren't stored only to be cleaned up")
+	require.Len(t, store.inserted, 1)
+	require.Equal(t, "1", store.inserted[0].ID)
+}
+
+func TestImport_DryRun(t *testing.T) {
+	store := &mockStore{}
+	var progress []importer.Report
+	report, err := importer.Import(context.Background(), strings.NewReader(dump), ingest.NewPipeline(store, nil), importer.Options{
+		Format:   importer.FormatCSV,
+		AppID:    "200",
+		Mapping:  map[string]string{"content": "body", "created_at": "date"},
+		DryRun:   true,
+		Progress: func(r importer.Report) { progress = append(progress, r) },
+	})
+	require.NoError(t, err)
+	require.True(t, report.DryRun)
+	require.Equal(t, 2, report.Valid)
+	require.Zero(t, report.New)
+	require.Empty(t, store.inserted)
+	require.Equal(t, []importer.Report{report}, progress)
+}
+
+func TestImport_NDJSON(t *testing.T) {
+	store := &mockStore{}
+	file := `{"id": 10642361744, "app_id": "100", "rating": 4, "created_at": "2024-01-02T03:04:05Z", "content": "Solid"}
+
+{"id": "2", "app_id": "100", "stars": 4}
+not json
+{"id": "3", "app_id": "100", "rating": "1", "created_at": "2024-01-02"}
+`
+	report, err := importer.Import(context.Background(), strings.NewReader(file), ingest.NewPipeline(store, nil), importer.Options{Format: importer.FormatNDJSON})
+	require.NoError(t, err)
+	require.Equal(t, 4, report.Rows)
+	require.Equal(t, 2, report.New)
+	require.Equal(t, []importer.Problem{
+		{Line: 3, ID: "2", Reason: `rating "" is not a whole number from 1 to 5`},
+		{Line: 4, Reason: "not a JSON object"},
+	}, report.Problems)
+	require.Equal(t, "10642361744", store.inserted[0].ID)
+	require.Equal(t, 1, store.inserted[1].Rating)
+}
+
+func TestImport_MissingColumns(t *testing.T) {
+	pipeline := ingest.NewPipeline(&mockStore{}, nil)
+	_, err := importer.Import(context.Background(), strings.NewReader("id,rating,created_at\n1,5,2024-01-02\n"), pipeline, importer.Options{Format: importer.FormatCSV})
+	require.ErrorContains(t, err, `no "app_id" column`)
+
+	_, err = importer.Import(context.Background(), strings.NewReader("id,stars,created_at\n"), pipeline, importer.Options{Format: importer.FormatCSV, AppID: "1"})
+	require.ErrorContains(t, err, "rating=<column>")
+
+	_, err = importer.Import(context.Background(), strings.NewReader(""), pipeline, importer.Options{Format: importer.FormatCSV})
+	require.ErrorContains(t, err, "empty")
+
+	_, err = importer.Import(context.Background(), strings.NewReader(""), pipeline, importer.Options{Format: "xml"})
+	require.ErrorContains(t, err, "unknown format")
+}
+
+func TestParseMapping(t *testing.T) {
+	mapping, err := importer.ParseMapping(" created_at = date , content=body")
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"created_at": "date", "content": "body"}, mapping)
+
+	_, err = importer.ParseMapping("stars=rating")
+	require.ErrorContains(t, err, "unknown field")
+	_, err = importer.ParseMapping("rating")
+	require.ErrorContains(t, err, "field=column")
+	_, err = importer.ParseMapping("rating=a,rating=b")
+	require.ErrorContains(t, err, "twice")
+
+	require.Equal(t, importer.FormatNDJSON, importer.FormatOf("dump.JSONL"))
+	require.Equal(t, importer.FormatCSV, importer.FormatOf("dump.csv"))
+}
@@ -0,0 +1,175 @@
+package ingest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/furqanmk/reviews-browser/internal/events"
+	"github.com/furqanmk/reviews-browser/internal/language"
+	"github.com/furqanmk/reviews-browser/internal/model"
+	"github.com/furqanmk/reviews-browser/internal/redact"
+	"github.com/furqanmk/reviews-browser/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = tracing.Tracer("ingest")
+
+// Store is the part of the database reviews are written to.
+type Store interface {
+	// InsertReviews stores an app's reviews that aren't stored yet,
+	// returning how many were new.
+	InsertReviews(ctx context.Context, appID string, reviews []model.Review) (int, error)
+}
+
+// Stage inspects or rewrites a review before it is stored. A stage returning
+// ErrStale drops the review without counting it as failed.
+type Stage func(ctx context.Context, review *model.Review) error
+
+// ErrStale is returned by a stage for a review too old to be kept.
+var ErrStale = errors.New("review is older than the recency cutoff")
+
+// Pipeline runs fetched reviews through a series of stages and stores them.
+type Pipeline struct {
+	store  Store
+	bus    *events.Bus
+	stages []Stage
+}
+
+// Result counts what happened to a batch of reviews.
+type Result struct {
+	Seen      int `json:"seen"`
+	New       int `json:"new"`
+	Duplicate int `json:"duplicate"`
+	Failed    int `json:"failed"`
+	// Stale counts reviews dropped as older than the recency cutoff.
+	Stale int `json:"stale"`
+}
+
+// NewPipeline creates a pipeline that applies stages in order before storing.
+// If bus is not nil, an event is published whenever new reviews are stored.
+func NewPipeline(store Store, bus *events.Bus, stages ...Stage) *Pipeline {
+	return &Pipeline{
+		store:  store,
+		bus:    bus,
+		stages: stages,
+	}
+}
+
+// Ingest processes an app's reviews and stores them in one batch. A review
+// that fails a stage is skipped; the first such error, or the store's, is
+// returned once all reviews have been attempted.
+func (p *Pipeline) Ingest(ctx context.Context, appID string, reviews []model.Review) (Result, error) {
+	ctx, span := tracer.Start(ctx, "ingest.Ingest",
+		trace.WithAttributes(attribute.String("app_id", appID), attribute.Int("reviews", len(reviews))))
+	var (
+		result   = Result{Seen: len(reviews)}
+		firstErr error
+		kept     = make([]model.Review, 0, len(reviews))
+	)
+	for _, review := range reviews {
+		err := p.process(ctx, &review)
+		switch {
+		case errors.Is(err, ErrStale):
+			result.Stale++
+		case err != nil:
+			result.Failed++
+			if firstErr == nil {
+				firstErr = fmt.Errorf("review %s: %w", review.ID, err)
+			}
+		default:
+			kept = append(kept, review)
+		}
+	}
+	if len(kept) > 0 {
+		inserted, err := p.store.InsertReviews(ctx, appID, kept)
+		result.New = inserted
+		if err != nil {
+			// reviews written before the store failed are new all the same
+			result.Failed += len(kept) - inserted
+			if firstErr == nil {
+				firstErr = err
+			}
+		} else {
+			result.Duplicate = len(kept) - inserted
+		}
+	}
+
+	reviewsIngested.WithLabelValues(appID, "new").Add(float64(result.New))
+	reviewsIngested.WithLabelValues(appID, "duplicate").Add(float64(result.Duplicate))
+	reviewsIngested.WithLabelValues(appID, "failed").Add(float64(result.Failed))
+	reviewsIngested.WithLabelValues(appID, "stale").Add(float64(result.Stale))
+
+	if p.bus != nil && result.New > 0 {
+		p.bus.Publish(events.Event{Type: events.ReviewsIngested, AppID: appID, Count: result.New})
+	}
+	span.SetAttributes(
+		attribute.Int("new", result.New),
+		attribute.Int("duplicate", result.Duplicate),
+		attribute.Int("failed", result.Failed),
+		attribute.Int("stale", result.Stale))
+	tracing.End(span, firstErr)
+	return result, firstErr
+}
+
+// process runs a review through every stage, stopping at the first error.
+func (p *Pipeline) process(ctx context.Context, review *model.Review) error {
+	for _, stage := range p.stages {
+		if err := stage(ctx, review); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SkipOlderThan drops reviews older than the recency cutoff, which reads leave
+// out and the next cleanup would remove. cutoff is asked each time, so a
+// reloaded cutoff applies straight away.
+func SkipOlderThan(cutoff func() time.Duration) Stage {
+	return func(ctx context.Context, review *model.Review) error {
+		if time.Since(review.CreatedAt) > cutoff() {
+			return ErrStale
+		}
+		return nil
+	}
+}
+
+// DetectLanguage tags the review with the language of its title and content.
+func DetectLanguage(ctx context.Context, review *model.Review) error {
+	review.Language = language.Detect(review.Title + "\n" + review.Content)
+	return nil
+}
+
+// Redact masks personal information in the review's title and content and
+// records which rules fired. With keepOriginal, the unredacted text is kept on
+// the review so it can be stored in the restricted columns.
+func Redact(redactor *redact.Redactor, keepOriginal bool) Stage {
+	return func(ctx context.Context, review *model.Review) error {
+		title, titleRules := redactor.Redact(review.Title)
+		content, contentRules := redactor.Redact(review.Content)
+		if len(titleRules) == 0 && len(contentRules) == 0 {
+			return nil
+		}
+
+		if keepOriginal {
+			review.Original = &model.OriginalText{Title: review.Title, Content: review.Content}
+		}
+		review.Title, review.Content = title, content
+		review.Redactions = mergeRules(titleRules, contentRules)
+		return nil
+	}
+}
+
+func mergeRules(a, b []string) []string {
+	merged := append([]string{}, a...)
+	for _, rule := range b {
+		if !slices.Contains(merged, rule) {
+			merged = append(merged, rule)
+		}
+	}
+	slices.Sort(merged)
+	return merged
+}
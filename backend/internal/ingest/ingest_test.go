@@ -0,0 +1,120 @@
+package ingest_test
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/furqanmk/reviews-browser/internal/events"
+	"github.com/furqanmk/reviews-browser/internal/ingest"
+	"github.com/furqanmk/reviews-browser/internal/model"
+	"github.com/furqanmk/reviews-browser/internal/redact"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/require"
+)
+
+type mockStore struct {
+	inserted []model.Review
+	batches  int
+}
+
+func (m *mockStore) InsertReviews(ctx context.Context, appID string, reviews []model.Review) (int, error) {
+	m.batches++
+	inserted := 0
+	for _, review := range reviews {
+		if slices.ContainsFunc(m.inserted, func(r model.Review) bool { return r.ID == review.ID }) {
+			continue
+		}
+		m.inserted = append(m.inserted, review)
+		inserted++
+	}
+	return inserted, nil
+}
+
+func TestPipeline_RedactThenDetect(t *testing.T) {
+	redactor, err := redact.New(redact.DefaultRules)
+	require.NoError(t, err)
+
+	for _, keep := range []bool{false, true} {
+		store := &mockStore{}
+		pipeline := ingest.NewPipeline(store, nil, ingest.Redact(redactor, keep), ingest.DetectLanguage)
+
+		_, err := pipeline.Ingest(context.Background(), "123", []model.Review{
+			{ID: "1", Title: "Refund please", Content: "I was charged twice, write to me at me@example.com"},
+			{ID: "2", Title: "Love it", Content: "Nothing personal in this one at all"},
+		})
+		require.NoError(t, err)
+		require.Len(t, store.inserted, 2)
+
+		redacted := store.inserted[0]
+		require.Equal(t, "I was charged twice, write to me at [EMAIL]", redacted.Content)
+		require.Equal(t, []string{"email"}, redacted.Redactions)
+		require.Equal(t, "en", redacted.Language)
+		if keep {
+			require.Equal(t, "I was charged twice, write to me at me@example.com", redacted.Original.Content)
+		} else {
+			require.Nil(t, redacted.Original)
+		}
+
+		require.Empty(t, store.inserted[1].Redactions)
+		require.Nil(t, store.inserted[1].Original)
+	}
+}
+
+func TestPipeline_PublishesNewReviews(t *testing.T) {
+	bus := events.NewBus()
+	sub, unsubscribe := bus.Subscribe(4)
+	defer unsubscribe()
+
+	pipeline := ingest.NewPipeline(&mockStore{}, bus)
+	batch := []model.Review{{ID: "1"}, {ID: "2"}}
+
+	result, err := pipeline.Ingest(context.Background(), "123", batch)
+	require.NoError(t, err)
+	require.Equal(t, ingest.Result{Seen: 2, New: 2}, result)
+
+	event := <-sub
+	require.Equal(t, events.ReviewsIngested, event.Type)
+	require.Equal(t, "123", event.AppID)
+	require.Equal(t, 2, event.Count)
+
+	// nothing new, nothing published
+	result, err = pipeline.Ingest(context.Background(), "123", batch)
+	require.NoError(t, err)
+	require.Equal(t, ingest.Result{Seen: 2, Duplicate: 2}, result)
+	require.Empty(t, sub)
+}
+
+func TestPipeline_CountsResults(t *testing.T) {
+	store := &mockStore{inserted: []model.Review{{ID: "1"}}}
+	pipeline := ingest.NewPipeline(store, nil, ingest.SkipOlderThan(func() time.Duration { return 48 * time.Hour }))
+
+	now := time.Now()
+	result, err := pipeline.Ingest(context.Background(), "metrics-app", []model.Review{
+		{ID: "1", CreatedAt: now}, {ID: "2", CreatedAt: now}, {ID: "3", CreatedAt: now}, {ID: "4", CreatedAt: now.Add(-72 * time.Hour)},
+	})
+	require.NoError(t, err)
+	require.Equal(t, ingest.Result{Seen: 4, New: 2, Duplicate: 1, Stale: 1}, result)
+	require.Equal(t, 1, store.batches, "the reviews are stored in one batch")
+	require.Len(t, store.inserted, 3, "stale reviews aren't stored")
+
+	families, err := prometheus.DefaultGatherer.Gather()
+	require.NoError(t, err)
+	counts := make(map[string]float64)
+	for _, family := range families {
+		if family.GetName() != "reviews_browser_ingest_reviews_total" {
+			continue
+		}
+		for _, m := range family.GetMetric() {
+			labels := make(map[string]string)
+			for _, l := range m.GetLabel() {
+				labels[l.GetName()] = l.GetValue()
+			}
+			if labels["app_id"] == "metrics-app" {
+				counts[labels["result"]] = m.GetCounter().GetValue()
+			}
+		}
+	}
+	require.Equal(t, map[string]float64{"new": 2, "duplicate": 1, "failed": 0, "stale": 1}, counts)
+}
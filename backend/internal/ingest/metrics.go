@@ -0,0 +1,16 @@
+package ingest
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// reviewsIngested counts reviews by what happened to them: "new" reviews were
+// stored, "duplicate" ones were already stored, "failed" ones hit an error and
+// "stale" ones were older than the recency cutoff.
+var reviewsIngested = promauto.NewCounterVec(prometheus.CounterOpts{
+	Namespace: "reviews_browser",
+	Subsystem: "ingest",
+	Name:      "reviews_total",
+	Help:      "Reviews run through the ingestion pipeline, by app and result (new, duplicate, failed or stale).",
+}, []string{"app_id", "result"})
@@ -0,0 +1,188 @@
+// Package jobs runs polls requested through the API in the background, so
+// callers get a job to check on instead of waiting for the App Store.
+package jobs
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/furqanmk/reviews-browser/internal/logging"
+	"github.com/furqanmk/reviews-browser/internal/model"
+)
+
+// Job statuses.
+const (
+	StatusQueued    = "queued"
+	StatusRunning   = "running"
+	StatusSucceeded = "succeeded"
+	StatusFailed    = "failed"
+)
+
+const (
+	// queueSize is how many jobs may wait for a worker.
+	queueSize = 100
+	// workers run jobs concurrently. Polls share the App Store client's rate
+	// limit, so more wouldn't finish them sooner.
+	workers = 2
+	// keepFinished is how long finished jobs can still be looked up.
+	keepFinished = time.Hour
+)
+
+// ErrQueueFull is returned by Enqueue when too many jobs are waiting.
+var ErrQueueFull = errors.New("job queue is full")
+
+var logger = logging.For("jobs")
+
+// PollFunc polls an app, returning the recorded run.
+type PollFunc func(ctx context.Context, appID string) (model.PollRun, error)
+
+// Job is a requested poll of an app.
+type Job struct {
+	ID         string     `json:"id"`
+	AppID      string     `json:"app_id"`
+	Status     string     `json:"status"`
+	CreatedAt  time.Time  `json:"created_at"`
+	StartedAt  *time.Time `json:"started_at,omitempty"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+	// Result is the poll run, once the job has finished.
+	Result *model.PollRun `json:"result,omitempty"`
+	Error  string         `json:"error,omitempty"`
+
+	// requestID is the ID of the request that queued the job, so its logs
+	// can be tied back to it.
+	requestID string
+}
+
+// Done reports whether the job has finished.
+func (j Job) Done() bool {
+	return j.Status == StatusSucceeded || j.Status == StatusFailed
+}
+
+// Queue runs poll jobs, at most one at a time per app.
+type Queue struct {
+	poll    PollFunc
+	pending chan string
+
+	mu   sync.Mutex
+	jobs map[string]*Job
+	// active maps an app to its queued or running job.
+	active map[string]string
+}
+
+// NewQueue creates a queue that runs jobs with poll once Run is called.
+func NewQueue(poll PollFunc) *Queue {
+	return &Queue{
+		poll:    poll,
+		pending: make(chan string, queueSize),
+		jobs:    make(map[string]*Job),
+		active:  make(map[string]string),
+	}
+}
+
+// Enqueue queues a poll of an app. If the app already has a queued or running
+// job, that job is returned instead and created is false.
+func (q *Queue) Enqueue(ctx context.Context, appID string) (job Job, created bool, err error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.prune(time.Now())
+
+	if id, ok := q.active[appID]; ok {
+		return *q.jobs[id], false, nil
+	}
+
+	j := &Job{
+		ID:        newID(),
+		AppID:     appID,
+		Status:    StatusQueued,
+		CreatedAt: time.Now(),
+		requestID: logging.RequestID(ctx),
+	}
+	select {
+	case q.pending <- j.ID:
+	default:
+		return Job{}, false, ErrQueueFull
+	}
+	q.jobs[j.ID] = j
+	q.active[appID] = j.ID
+	return *j, true, nil
+}
+
+// Get looks up a job. Finished jobs are forgotten after an hour.
+func (q *Queue) Get(id string) (Job, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.prune(time.Now())
+
+	j, ok := q.jobs[id]
+	if !ok {
+		return Job{}, false
+	}
+	return *j, true
+}
+
+// Run works through queued jobs until ctx is done. Jobs still queued then are
+// left unfinished.
+func (q *Queue) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case id := <-q.pending:
+					q.run(ctx, id)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// run runs one job, recording its progress.
+func (q *Queue) run(ctx context.Context, id string) {
+	q.mu.Lock()
+	j := q.jobs[id]
+	started := time.Now()
+	j.Status, j.StartedAt = StatusRunning, &started
+	appID, requestID := j.AppID, j.requestID
+	q.mu.Unlock()
+
+	if requestID != "" {
+		ctx = logging.WithRequestID(ctx, requestID)
+	}
+	ctx = logging.With(ctx, "job_id", id)
+	logger.InfoContext(ctx, "running poll job", "app_id", appID)
+	run, err := q.poll(ctx, appID)
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	finished := time.Now()
+	j.FinishedAt, j.Result = &finished, &run
+	j.Status = StatusSucceeded
+	if err != nil {
+		j.Status, j.Error = StatusFailed, err.Error()
+	}
+	delete(q.active, appID)
+}
+
+// prune forgets jobs that finished long enough ago. q.mu must be held.
+func (q *Queue) prune(now time.Time) {
+	for id, j := range q.jobs {
+		if j.FinishedAt != nil && now.Sub(*j.FinishedAt) > keepFinished {
+			delete(q.jobs, id)
+		}
+	}
+}
+
+func newID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
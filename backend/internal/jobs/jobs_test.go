@@ -0,0 +1,76 @@
+package jobs_test
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/furqanmk/reviews-browser/internal/jobs"
+	"github.com/furqanmk/reviews-browser/internal/model"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQueue(t *testing.T) {
+	release := make(chan struct{})
+	var polls atomic.Int32
+	queue := jobs.NewQueue(func(ctx context.Context, appID string) (model.PollRun, error) {
+		<-release
+		polls.Add(1)
+		if appID == "bad" {
+			return model.PollRun{AppID: appID, ErrorClass: model.PollErrorNetwork}, errors.New("connection refused")
+		}
+		return model.PollRun{AppID: appID, Pages: 2, New: 5}, nil
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go queue.Run(ctx)
+
+	job, created, err := queue.Enqueue(ctx, "123")
+	require.NoError(t, err)
+	require.True(t, created)
+	require.NotEmpty(t, job.ID)
+
+	// a second request while the first is in flight gets the same job
+	again, created, err := queue.Enqueue(ctx, "123")
+	require.NoError(t, err)
+	require.False(t, created)
+	require.Equal(t, job.ID, again.ID)
+
+	failing, _, err := queue.Enqueue(ctx, "bad")
+	require.NoError(t, err)
+
+	close(release)
+	job = waitFor(t, queue, job.ID)
+	require.Equal(t, jobs.StatusSucceeded, job.Status)
+	require.Equal(t, 5, job.Result.New)
+	require.NotNil(t, job.StartedAt)
+	require.NotNil(t, job.FinishedAt)
+
+	failing = waitFor(t, queue, failing.ID)
+	require.Equal(t, jobs.StatusFailed, failing.Status)
+	require.Equal(t, "connection refused", failing.Error)
+	require.Equal(t, model.PollErrorNetwork, failing.Result.ErrorClass)
+	require.EqualValues(t, 2, polls.Load())
+
+	// once finished, the app can be polled again
+	next, created, err := queue.Enqueue(ctx, "123")
+	require.NoError(t, err)
+	require.True(t, created)
+	require.NotEqual(t, job.ID, next.ID)
+
+	_, ok := queue.Get("nope")
+	require.False(t, ok)
+}
+
+func waitFor(t *testing.T, queue *jobs.Queue, id string) jobs.Job {
+	t.Helper()
+	var job jobs.Job
+	require.Eventually(t, func() bool {
+		var ok bool
+		job, ok = queue.Get(id)
+		return ok && job.Done()
+	}, time.Second, time.Millisecond)
+	return job
+}
@@ -0,0 +1,69 @@
+package language
+
+// corpus holds short samples of everyday text for each Latin-script language
+// the detector recognises. Profiles are built from these at start-up, so the
+// samples lean on the words that turn up in app reviews.
+var corpus = map[string]string{
+	"en": `This app is great and I really love it. The new update broke the login
+		and now it keeps crashing every time I try to open it. Please fix this as
+		soon as possible because I use it every day with my friends and family.
+		It was working fine before but now the screen goes black. I would give it
+		five stars if you could add dark mode. Customer support never answered my
+		emails. Why do you keep changing things that were not broken? The best
+		thing about this application is how easy it is to share pictures and
+		videos. I have been using it for years and this is the worst version yet.
+		Thank you for listening to your users, the latest changes are amazing.`,
+	"es": `Esta aplicación es muy buena y me encanta. La nueva actualización no
+		funciona y ahora se cierra cada vez que intento abrirla. Por favor
+		arreglen esto lo antes posible porque la uso todos los días con mis
+		amigos y mi familia. Antes funcionaba bien pero ahora la pantalla se
+		queda en negro. Le daría cinco estrellas si pudieran agregar el modo
+		oscuro. El servicio al cliente nunca respondió mis correos. ¿Por qué
+		siguen cambiando cosas que no estaban rotas? Lo mejor de esta aplicación
+		es lo fácil que es compartir fotos y videos. La he usado durante años y
+		esta es la peor versión. Gracias por escuchar a los usuarios.`,
+	"fr": `Cette application est très bien et je l'adore. La nouvelle mise à jour
+		ne marche pas et maintenant elle plante chaque fois que j'essaie de
+		l'ouvrir. S'il vous plaît corrigez cela le plus vite possible parce que
+		je l'utilise tous les jours avec mes amis et ma famille. Avant ça
+		fonctionnait bien mais maintenant l'écran devient noir. Je donnerais cinq
+		étoiles si vous ajoutiez le mode sombre. Le service client n'a jamais
+		répondu à mes messages. Pourquoi changer des choses qui n'étaient pas
+		cassées? Le mieux dans cette application c'est la facilité pour partager
+		des photos et des vidéos. Merci d'écouter vos utilisateurs.`,
+	"de": `Diese App ist sehr gut und ich liebe sie. Das neue Update funktioniert
+		nicht und jetzt stürzt sie jedes Mal ab, wenn ich sie öffnen will. Bitte
+		behebt das so schnell wie möglich, weil ich sie jeden Tag mit meinen
+		Freunden und meiner Familie benutze. Vorher hat alles gut funktioniert,
+		aber jetzt wird der Bildschirm schwarz. Ich würde fünf Sterne geben, wenn
+		ihr einen dunklen Modus hinzufügt. Der Kundendienst hat nie auf meine
+		Nachrichten geantwortet. Warum ändert ihr Dinge, die nicht kaputt waren?
+		Das Beste an dieser Anwendung ist, wie einfach man Bilder und Videos
+		teilen kann. Danke, dass ihr auf eure Nutzer hört.`,
+	"it": `Questa applicazione è molto bella e la adoro. Il nuovo aggiornamento non
+		funziona e adesso si chiude ogni volta che provo ad aprirla. Per favore
+		sistemate questo problema il prima possibile perché la uso tutti i giorni
+		con i miei amici e la mia famiglia. Prima funzionava bene ma adesso lo
+		schermo diventa nero. Darei cinque stelle se aggiungeste la modalità
+		scura. Il servizio clienti non ha mai risposto ai miei messaggi. Perché
+		continuate a cambiare cose che non erano rotte? La cosa migliore di
+		questa app è quanto sia facile condividere foto e video. Grazie per
+		ascoltare gli utenti.`,
+	"pt": `Este aplicativo é muito bom e eu adoro. A nova atualização não funciona
+		e agora ele fecha toda vez que eu tento abrir. Por favor consertem isso o
+		mais rápido possível porque eu uso todos os dias com meus amigos e minha
+		família. Antes funcionava bem mas agora a tela fica preta. Eu daria cinco
+		estrelas se vocês colocassem o modo escuro. O atendimento ao cliente
+		nunca respondeu minhas mensagens. Por que vocês continuam mudando coisas
+		que não estavam quebradas? A melhor coisa desse aplicativo é como é
+		fácil compartilhar fotos e vídeos. Obrigado por ouvir os usuários.`,
+	"nl": `Deze app is heel goed en ik vind hem geweldig. De nieuwe update werkt
+		niet en nu crasht hij elke keer als ik hem probeer te openen. Los dit
+		alsjeblieft zo snel mogelijk op want ik gebruik hem elke dag met mijn
+		vrienden en familie. Eerst werkte alles goed maar nu wordt het scherm
+		zwart. Ik zou vijf sterren geven als jullie een donkere modus toevoegen.
+		De klantenservice heeft nooit op mijn berichten gereageerd. Waarom
+		veranderen jullie dingen die niet kapot waren? Het beste aan deze
+		applicatie is hoe makkelijk je foto's en video's kunt delen. Bedankt dat
+		jullie naar de gebruikers luisteren.`,
+}
@@ -0,0 +1,173 @@
+// Package language identifies the language of short texts such as app reviews.
+//
+// Non-Latin scripts are recognised from their Unicode ranges. Latin-script text
+// is classified with the n-gram rank-order method of Cavnar and Trenkle: the
+// text's most frequent character n-grams are compared against a profile built
+// from a sample of each language, and the closest profile wins.
+package language
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Undetermined is returned when the text is too short or too ambiguous to call.
+const Undetermined = "und"
+
+const (
+	// profileSize is the number of top-ranked n-grams kept per profile.
+	profileSize = 400
+	// minLetters is the shortest text, in letters, that is classified.
+	minLetters = 8
+)
+
+// scripts maps writing systems that (mostly) belong to one language.
+var scripts = []struct {
+	table *unicode.RangeTable
+	lang  string
+}{
+	{unicode.Hiragana, "ja"},
+	{unicode.Katakana, "ja"},
+	{unicode.Hangul, "ko"},
+	{unicode.Han, "zh"},
+	{unicode.Cyrillic, "ru"},
+	{unicode.Arabic, "ar"},
+	{unicode.Hebrew, "he"},
+	{unicode.Greek, "el"},
+	{unicode.Thai, "th"},
+	{unicode.Devanagari, "hi"},
+}
+
+var profiles = make(map[string]map[string]int)
+
+func init() {
+	for lang, text := range corpus {
+		profiles[lang] = rankNGrams(text, profileSize)
+	}
+}
+
+// Detect returns the ISO 639-1 code of the language the text is written in, or
+// Undetermined.
+func Detect(text string) string {
+	if lang := detectScript(text); lang != "" {
+		return lang
+	}
+
+	letters := 0
+	for _, r := range text {
+		if unicode.IsLetter(r) {
+			letters++
+		}
+	}
+	if letters < minLetters {
+		return Undetermined
+	}
+
+	ranks := rankNGrams(text, profileSize)
+	best, bestDistance := Undetermined, -1
+	for _, lang := range Supported() {
+		d := distance(ranks, profiles[lang])
+		if bestDistance < 0 || d < bestDistance {
+			best, bestDistance = lang, d
+		}
+	}
+	return best
+}
+
+// Supported lists the Latin-script languages with an n-gram profile.
+func Supported() []string {
+	langs := make([]string, 0, len(profiles))
+	for lang := range profiles {
+		langs = append(langs, lang)
+	}
+	sort.Strings(langs)
+	return langs
+}
+
+// detectScript returns the language of the dominant non-Latin script, if the
+// text is mostly written in one.
+func detectScript(text string) string {
+	counts := make(map[string]int)
+	letters := 0
+	for _, r := range text {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		letters++
+		for _, s := range scripts {
+			if unicode.Is(s.table, r) {
+				counts[s.lang]++
+				break
+			}
+		}
+	}
+	// Japanese mixes kana with Han characters, so any kana settles it.
+	if counts["ja"] > 0 && counts["ja"]+counts["zh"] > letters/2 {
+		return "ja"
+	}
+	for lang, n := range counts {
+		if n > letters/2 {
+			return lang
+		}
+	}
+	return ""
+}
+
+// rankNGrams returns the top n-grams of length 1 to 3 in the text mapped to
+// their rank, most frequent first.
+func rankNGrams(text string, size int) map[string]int {
+	counts := make(map[string]int)
+	for _, word := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r)
+	}) {
+		runes := []rune("_" + word + "_")
+		for n := 1; n <= 3; n++ {
+			for i := 0; i+n <= len(runes); i++ {
+				gram := string(runes[i : i+n])
+				if gram == "_" {
+					continue
+				}
+				counts[gram]++
+			}
+		}
+	}
+
+	grams := make([]string, 0, len(counts))
+	for gram := range counts {
+		grams = append(grams, gram)
+	}
+	sort.Slice(grams, func(i, j int) bool {
+		if counts[grams[i]] != counts[grams[j]] {
+			return counts[grams[i]] > counts[grams[j]]
+		}
+		return grams[i] < grams[j]
+	})
+	if len(grams) > size {
+		grams = grams[:size]
+	}
+
+	ranks := make(map[string]int, len(grams))
+	for i, gram := range grams {
+		ranks[gram] = i
+	}
+	return ranks
+}
+
+// distance is the out-of-place measure between a text and a language profile.
+func distance(text, profile map[string]int) int {
+	d := 0
+	for gram, rank := range text {
+		profileRank, ok := profile[gram]
+		if !ok {
+			d += profileSize
+			continue
+		}
+		if rank > profileRank {
+			d += rank - profileRank
+		} else {
+			d += profileRank - rank
+		}
+	}
+	return d
+}
@@ -0,0 +1,31 @@
+package language_test
+
+import (
+	"testing"
+
+	"github.com/furqanmk/reviews-browser/internal/language"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDetect(t *testing.T) {
+	cases := map[string]string{
+		"Please snapchat company help me I can't send add friends": "en",
+		"La aplicación no funciona desde la última actualización":  "es",
+		"Impossible de me connecter depuis la mise à jour":         "fr",
+		"Die App stürzt ständig ab, total nervig":                  "de",
+		"Non riesco ad accedere, app inutile":                      "it",
+		"Não consigo entrar na minha conta":                        "pt",
+		"Ik kan niet meer inloggen sinds gisteren":                 "nl",
+		"Очень плохое приложение":                                  "ru",
+		"とても良いアプリです":                                               "ja",
+		"정말 좋은 앱이에요":                                               "ko",
+	}
+	for text, want := range cases {
+		require.Equal(t, want, language.Detect(text), text)
+	}
+}
+
+func TestDetect_TooShort(t *testing.T) {
+	require.Equal(t, language.Undetermined, language.Detect("Ya"))
+	require.Equal(t, language.Undetermined, language.Detect("5/5 !!!"))
+}
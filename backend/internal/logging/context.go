@@ -0,0 +1,45 @@
+package logging
+
+import (
+	"context"
+	"log/slog"
+)
+
+type (
+	attrsKey     struct{}
+	requestIDKey struct{}
+)
+
+// With returns a context whose log records carry the given attributes, as
+// key-value pairs or slog.Attrs, in addition to any it already carries.
+func With(ctx context.Context, args ...any) context.Context {
+	attrs := append([]slog.Attr(nil), contextAttrs(ctx)...)
+	r := slog.Record{}
+	r.Add(args...)
+	r.Attrs(func(a slog.Attr) bool {
+		attrs = append(attrs, a)
+		return true
+	})
+	return context.WithValue(ctx, attrsKey{}, attrs)
+}
+
+// WithRequestID returns a context carrying a request ID, which is logged as
+// request_id.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	ctx = context.WithValue(ctx, requestIDKey{}, id)
+	return With(ctx, "request_id", id)
+}
+
+// RequestID returns the request ID carried by ctx, or "".
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+func contextAttrs(ctx context.Context) []slog.Attr {
+	if ctx == nil {
+		return nil
+	}
+	attrs, _ := ctx.Value(attrsKey{}).([]slog.Attr)
+	return attrs
+}
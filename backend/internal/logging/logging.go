@@ -0,0 +1,151 @@
+// Package logging provides component loggers built on log/slog. Every
+// component logs through a logger from For; Configure chooses the output
+// format and levels for all of them, and may be called again at any time, for
+// instance when the configuration is reloaded.
+//
+// Attributes added to a context with With, such as a request ID or an app ID,
+// are included in every record logged with that context, so they follow a
+// request or poll into the store and App Store client. So are the trace and
+// span IDs of the context's span, if any.
+package logging
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Options selects the output format and levels.
+type Options struct {
+	// Format is "json" or "text".
+	Format string
+	// Level is the least severe level logged by components without an entry
+	// in Levels.
+	Level slog.Level
+	// Levels overrides Level by component name.
+	Levels map[string]slog.Level
+	// Output defaults to stderr.
+	Output io.Writer
+}
+
+var (
+	base atomic.Pointer[slog.Handler]
+
+	mu     sync.Mutex
+	levels = make(map[string]*slog.LevelVar)
+	// fallback is the level of components without their own.
+	fallback = new(slog.LevelVar)
+)
+
+func init() {
+	Configure(Options{})
+}
+
+// Configure sets the format and levels of every component logger, and of the
+// default slog logger, which logs as component "default".
+func Configure(opts Options) {
+	out := opts.Output
+	if out == nil {
+		out = os.Stderr
+	}
+	// levels are checked by the component handlers, so the base logs everything
+	handlerOpts := &slog.HandlerOptions{Level: slog.LevelDebug - 4}
+	var h slog.Handler
+	if strings.EqualFold(opts.Format, "json") {
+		h = slog.NewJSONHandler(out, handlerOpts)
+	} else {
+		h = slog.NewTextHandler(out, handlerOpts)
+	}
+	base.Store(&h)
+
+	mu.Lock()
+	defer mu.Unlock()
+	fallback.Set(opts.Level)
+	for name, level := range levels {
+		if l, ok := opts.Levels[name]; ok {
+			level.Set(l)
+		} else {
+			level.Set(opts.Level)
+		}
+	}
+	for name, l := range opts.Levels {
+		if _, ok := levels[name]; !ok {
+			level := new(slog.LevelVar)
+			level.Set(l)
+			levels[name] = level
+		}
+	}
+
+	slog.SetDefault(slog.New(&handler{level: levelFor("default")}).With("component", "default"))
+}
+
+// For returns the logger of a component, such as "api" or "polling". Its
+// records carry a component attribute and are filtered by the component's
+// level.
+func For(component string) *slog.Logger {
+	mu.Lock()
+	level := levelFor(component)
+	mu.Unlock()
+	return slog.New(&handler{level: level}).With("component", component)
+}
+
+// levelFor returns the level of a component, creating it at the fallback level
+// if needed. mu must be held.
+func levelFor(component string) *slog.LevelVar {
+	level, ok := levels[component]
+	if !ok {
+		level = new(slog.LevelVar)
+		level.Set(fallback.Level())
+		levels[component] = level
+	}
+	return level
+}
+
+// handler filters records by a component level and hands them to the current
+// base handler, replaying the attributes and groups added to the logger.
+type handler struct {
+	level *slog.LevelVar
+	ops   []func(slog.Handler) slog.Handler
+}
+
+func (h *handler) Enabled(ctx context.Context, level slog.Level) bool {
+	return level >= h.level.Level()
+}
+
+func (h *handler) Handle(ctx context.Context, r slog.Record) error {
+	next := *base.Load()
+	for _, op := range h.ops {
+		next = op(next)
+	}
+	attrs := contextAttrs(ctx)
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		attrs = append(attrs[:len(attrs):len(attrs)],
+			slog.String("trace_id", sc.TraceID().String()),
+			slog.String("span_id", sc.SpanID().String()))
+	}
+	if len(attrs) > 0 {
+		r = r.Clone()
+		r.AddAttrs(attrs...)
+	}
+	return next.Handle(ctx, r)
+}
+
+func (h *handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return h.with(func(next slog.Handler) slog.Handler { return next.WithAttrs(attrs) })
+}
+
+func (h *handler) WithGroup(name string) slog.Handler {
+	return h.with(func(next slog.Handler) slog.Handler { return next.WithGroup(name) })
+}
+
+func (h *handler) with(op func(slog.Handler) slog.Handler) *handler {
+	ops := make([]func(slog.Handler) slog.Handler, len(h.ops), len(h.ops)+1)
+	copy(ops, h.ops)
+	return &handler{level: h.level, ops: append(ops, op)}
+}
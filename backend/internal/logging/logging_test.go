@@ -0,0 +1,79 @@
+package logging_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/furqanmk/reviews-browser/internal/logging"
+	"github.com/stretchr/testify/require"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+func TestFor_ComponentLevels(t *testing.T) {
+	var out bytes.Buffer
+	t.Cleanup(func() { logging.Configure(logging.Options{}) })
+
+	// loggers made before Configure follow it
+	polling := logging.For("polling")
+	api := logging.For("api")
+	logging.Configure(logging.Options{
+		Format: "json",
+		Level:  slog.LevelWarn,
+		Levels: map[string]slog.Level{"polling": slog.LevelDebug},
+		Output: &out,
+	})
+
+	polling.Debug("shown")
+	api.Info("hidden")
+	api.Warn("shown too")
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	require.Len(t, lines, 2)
+
+	var record map[string]any
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &record))
+	require.Equal(t, "polling", record["component"])
+	require.Equal(t, "shown", record["msg"])
+
+	// dropping the override falls back to the default level
+	out.Reset()
+	logging.Configure(logging.Options{Level: slog.LevelWarn, Output: &out})
+	polling.Debug("hidden")
+	require.Empty(t, out.String())
+}
+
+func TestWith_ContextAttributes(t *testing.T) {
+	var out bytes.Buffer
+	t.Cleanup(func() { logging.Configure(logging.Options{}) })
+	logging.Configure(logging.Options{Output: &out})
+
+	ctx := logging.WithRequestID(context.Background(), "req-1")
+	ctx = logging.With(ctx, "app_id", "42")
+	require.Equal(t, "req-1", logging.RequestID(ctx))
+
+	logging.For("database").With("table", "reviews").InfoContext(ctx, "store operation")
+	line := out.String()
+	require.Contains(t, line, "component=database")
+	require.Contains(t, line, "table=reviews")
+	require.Contains(t, line, "request_id=req-1")
+	require.Contains(t, line, "app_id=42")
+
+	require.Empty(t, logging.RequestID(context.Background()))
+}
+
+func TestHandler_TraceIDs(t *testing.T) {
+	var out bytes.Buffer
+	t.Cleanup(func() { logging.Configure(logging.Options{}) })
+	logging.Configure(logging.Options{Output: &out})
+
+	ctx, span := sdktrace.NewTracerProvider().Tracer("test").Start(context.Background(), "op")
+	defer span.End()
+
+	logging.For("polling").InfoContext(ctx, "polled app")
+	require.Contains(t, out.String(), "trace_id="+span.SpanContext().TraceID().String())
+	require.Contains(t, out.String(), "span_id="+span.SpanContext().SpanID().String())
+}
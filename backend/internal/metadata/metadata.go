@@ -0,0 +1,112 @@
+// Package metadata keeps the App Store details of tracked apps, such as their
+// names and icons, cached in the store and refreshed on a schedule.
+package metadata
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/furqanmk/reviews-browser/config"
+	"github.com/furqanmk/reviews-browser/internal/logging"
+	"github.com/furqanmk/reviews-browser/internal/model"
+)
+
+var logger = logging.For("metadata")
+
+// checkEvery is how often the refresher looks for apps whose details are due,
+// so apps added since the last check are looked up soon after.
+const checkEvery = 10 * time.Minute
+
+// Store keeps apps and their cached details.
+type Store interface {
+	GetApps(ctx context.Context) ([]model.App, error)
+	SetAppMetadata(ctx context.Context, appID string, metadata model.AppMetadata) error
+}
+
+// Lookup finds how the App Store lists an app, as appstore.Client does.
+type Lookup interface {
+	LookupApp(ctx context.Context, appID string) (model.AppMetadata, error)
+}
+
+// Refresher looks up each tracked app's details once they are
+// MetadataRefreshEvery old, and as soon as it can for apps never looked up.
+type Refresher struct {
+	db     Store
+	lookup Lookup
+	cfg    atomic.Pointer[config.Config]
+}
+
+func NewRefresher(db Store, lookup Lookup, cfg *config.Config) *Refresher {
+	r := &Refresher{db: db, lookup: lookup}
+	r.cfg.Store(cfg)
+	return r
+}
+
+// SetConfig switches the refresher to a new configuration snapshot. A changed
+// MetadataRefreshEvery applies from the next check.
+func (r *Refresher) SetConfig(cfg *config.Config) {
+	r.cfg.Store(cfg)
+}
+
+// Start refreshes the details that are due straight away and then every
+// checkEvery, until ctx is cancelled.
+func (r *Refresher) Start(ctx context.Context) {
+	logger.InfoContext(ctx, "starting metadata refresher")
+
+	go func() {
+		for {
+			if _, err := r.RefreshDue(ctx, time.Now()); err != nil && ctx.Err() == nil {
+				logger.ErrorContext(ctx, "refreshing app metadata failed", "error", err)
+			}
+			timer := time.NewTimer(checkEvery)
+			select {
+			case <-timer.C:
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			}
+		}
+	}()
+}
+
+// RefreshDue refreshes the details of every app not looked up within
+// MetadataRefreshEvery of now, returning how many were refreshed. An app whose
+// lookup fails is logged and left for the next check; the others are still
+// refreshed. It fails only if the apps can't be read, or ctx ends.
+func (r *Refresher) RefreshDue(ctx context.Context, now time.Time) (int, error) {
+	apps, err := r.db.GetApps(ctx)
+	if err != nil {
+		return 0, err
+	}
+	every := r.cfg.Load().MetadataRefreshEvery
+	refreshed := 0
+	for _, app := range apps {
+		if now.Sub(app.Metadata.RefreshedAt) < every {
+			continue
+		}
+		if _, err := r.Refresh(ctx, app.ID); err != nil {
+			if ctx.Err() != nil {
+				return refreshed, ctx.Err()
+			}
+			logger.WarnContext(ctx, "looking up app failed", "app_id", app.ID, "error", err)
+			continue
+		}
+		refreshed++
+	}
+	return refreshed, nil
+}
+
+// Refresh looks up an app's details now and caches them.
+func (r *Refresher) Refresh(ctx context.Context, appID string) (model.AppMetadata, error) {
+	metadata, err := r.lookup.LookupApp(ctx, appID)
+	if err != nil {
+		return model.AppMetadata{}, err
+	}
+	metadata.RefreshedAt = time.Now().UTC().Truncate(time.Second)
+	if err := r.db.SetAppMetadata(ctx, appID, metadata); err != nil {
+		return model.AppMetadata{}, err
+	}
+	logger.DebugContext(ctx, "refreshed app metadata", "app_id", appID, "name", metadata.Name, "version", metadata.Version)
+	return metadata, nil
+}
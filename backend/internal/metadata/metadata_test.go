@@ -0,0 +1,69 @@
+package metadata_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/furqanmk/reviews-browser/config"
+	"github.com/furqanmk/reviews-browser/internal/metadata"
+	"github.com/furqanmk/reviews-browser/internal/model"
+	"github.com/stretchr/testify/require"
+)
+
+type store struct {
+	apps []model.App
+}
+
+func (s *store) GetApps(ctx context.Context) ([]model.App, error) {
+	return s.apps, nil
+}
+
+func (s *store) SetAppMetadata(ctx context.Context, appID string, metadata model.AppMetadata) error {
+	for i := range s.apps {
+		if s.apps[i].ID == appID {
+			s.apps[i].Metadata = metadata
+		}
+	}
+	return nil
+}
+
+type lookup struct {
+	looked []string
+}
+
+func (l *lookup) LookupApp(ctx context.Context, appID string) (model.AppMetadata, error) {
+	l.looked = append(l.looked, appID)
+	if appID == "404" {
+		return model.AppMetadata{}, errors.New("not listed")
+	}
+	return model.AppMetadata{Name: "App " + appID}, nil
+}
+
+func TestRefreshDue(t *testing.T) {
+	now := time.Now()
+	db := &store{apps: []model.App{
+		{ID: "1"},
+		{ID: "2", Metadata: model.AppMetadata{Name: "Fresh", RefreshedAt: now.Add(-time.Hour)}},
+		{ID: "3", Metadata: model.AppMetadata{Name: "Stale", RefreshedAt: now.Add(-25 * time.Hour)}},
+		{ID: "404"},
+	}}
+	client := &lookup{}
+	refresher := metadata.NewRefresher(db, client, &config.Config{MetadataRefreshEvery: 24 * time.Hour})
+
+	refreshed, err := refresher.RefreshDue(context.Background(), now)
+	require.NoError(t, err)
+	require.Equal(t, 2, refreshed)
+	require.Equal(t, []string{"1", "3", "404"}, client.looked, "fresh details are kept, failures don't stop the rest")
+	require.Equal(t, "App 1", db.apps[0].Metadata.Name)
+	require.False(t, db.apps[0].Metadata.RefreshedAt.IsZero())
+	require.Equal(t, "Fresh", db.apps[1].Metadata.Name)
+	require.Equal(t, "App 3", db.apps[2].Metadata.Name)
+
+	// only the failed lookup is due again
+	client.looked = nil
+	_, err = refresher.RefreshDue(context.Background(), now)
+	require.NoError(t, err)
+	require.Equal(t, []string{"404"}, client.looked)
+}
@@ -1,12 +1,65 @@
 package model
 
-import "time"
+import (
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
 
 // App represents an application record.
 type App struct {
 	ID               string
 	LastFetched      time.Time
 	PollEverySeconds int
+	// LastSuccess is when a poll of the app last succeeded. LastError and
+	// LastErrorAt describe the latest failed poll, and are cleared by the
+	// next successful one.
+	LastSuccess time.Time
+	LastError   string
+	LastErrorAt time.Time
+	// Metadata is how the App Store lists the app, as last looked up.
+	Metadata AppMetadata
+}
+
+// AppMetadata describes an app as the App Store lists it. It is looked up
+// periodically and cached with the app; RefreshedAt is zero until the first
+// lookup succeeds.
+type AppMetadata struct {
+	Name      string `json:"name"`
+	Developer string `json:"developer"`
+	IconURL   string `json:"icon_url"`
+	Version   string `json:"version"`
+	Genre     string `json:"genre"`
+	// AverageRating is the store's average over every rating the app has
+	// had, from RatingCount ratings, not only the reviews kept here.
+	AverageRating float64   `json:"average_rating"`
+	RatingCount   int       `json:"rating_count"`
+	RefreshedAt   time.Time `json:"refreshed_at"`
+}
+
+// DataVersion tracks changes to an app's stored reviews, so that responses
+// built from them can be validated without reading the reviews again.
+type DataVersion struct {
+	AppID string
+	// Version counts the changes to the app's reviews, their spam flags and
+	// their triage. It is zero for an app whose reviews haven't changed since
+	// versions were first kept.
+	Version int64
+	// ModifiedAt is when the reviews last changed, by ingestion or otherwise.
+	ModifiedAt time.Time
+	// Oldest is when the oldest review the app had within the recency cutoff
+	// at the last change was written. Once it falls past the cutoff, reads
+	// differ from what they were at that version. It is zero if the app had
+	// no recent reviews.
+	Oldest time.Time
+}
+
+// Current reports whether the version still describes what reads of the
+// app's recent reviews return at now: it is known, and no review counted at
+// it has since aged past the cutoff.
+func (v DataVersion) Current(cutoff time.Duration, now time.Time) bool {
+	return v.Version > 0 && (v.Oldest.IsZero() || now.Before(v.Oldest.Add(cutoff)))
 }
 
 // Review represents a review record.
@@ -18,4 +71,125 @@ type Review struct {
 	Content   string    `json:"content"`
 	Rating    int       `json:"rating"`
 	CreatedAt time.Time `json:"created_at"`
+	Language  string    `json:"language"`
+	// SuspectedSpam is set on ingestion by the spam detector and can be
+	// overridden through the API; SpamReason lists why.
+	SuspectedSpam bool   `json:"suspected_spam"`
+	SpamReason    string `json:"spam_reason,omitempty"`
+	// Redactions names the PII rules that masked part of the text.
+	Redactions []string `json:"redactions,omitempty"`
+	// Original is the text before redaction. It is only kept when configured
+	// and only returned to elevated API keys.
+	Original *OriginalText `json:"original,omitempty"`
+	// Triage is the support team's workflow state. It is stored apart from the
+	// review so cleanups and re-fetches leave it alone.
+	Triage *Triage `json:"triage,omitempty"`
+}
+
+// OriginalText is the unredacted title and content of a review.
+type OriginalText struct {
+	Title   string `json:"title"`
+	Content string `json:"content"`
+}
+
+// Triage statuses.
+const (
+	TriageNew        = "new"
+	TriageInProgress = "in-progress"
+	TriageResolved   = "resolved"
+	TriageIgnored    = "ignored"
+)
+
+// Triage holds the support workflow state of a review.
+type Triage struct {
+	ReviewID  string    `json:"review_id"`
+	AppID     string    `json:"app_id"`
+	Status    string    `json:"status"`
+	Assignee  string    `json:"assignee"`
+	Tags      []string  `json:"tags"`
+	Notes     string    `json:"notes"`
+	UpdatedAt time.Time `json:"updated_at,omitempty"`
+}
+
+// ValidTriageStatus reports whether status is one of the triage statuses.
+func ValidTriageStatus(status string) bool {
+	switch status {
+	case TriageNew, TriageInProgress, TriageResolved, TriageIgnored:
+		return true
+	}
+	return false
+}
+
+// ValidAppID reports whether id can be an App Store app ID, which is numeric.
+func ValidAppID(id string) bool {
+	_, err := strconv.ParseUint(id, 10, 64)
+	return err == nil
+}
+
+// ValidTag reports whether tag can be stored. Tags are kept joined by ";", so
+// a tag may be neither empty nor contain one.
+func ValidTag(tag string) bool {
+	return tag != "" && !strings.Contains(tag, ";")
+}
+
+// Poll error classes.
+const (
+	PollErrorNetwork     = "network"
+	PollErrorHTTPStatus  = "http_status"
+	PollErrorBadResponse = "bad_response"
+	PollErrorCircuitOpen = "circuit_open"
+	PollErrorTimeout     = "timeout"
+	PollErrorCanceled    = "canceled"
+	PollErrorStore       = "store"
+	PollErrorUnknown     = "unknown"
+)
+
+// PollRun records one poll of an app.
+type PollRun struct {
+	AppID      string    `json:"app_id"`
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at"`
+	// Pages counts the feed pages received.
+	Pages     int `json:"pages"`
+	Seen      int `json:"seen"`
+	New       int `json:"new"`
+	Duplicate int `json:"duplicate"`
+	Failed    int `json:"failed"`
+	// Stale counts reviews left out as older than the recency cutoff.
+	Stale int `json:"stale"`
+	// ErrorClass is one of the poll error classes, and empty for a run that
+	// succeeded. Error is the failure itself.
+	ErrorClass string `json:"error_class,omitempty"`
+	Error      string `json:"error,omitempty"`
+}
+
+// Succeeded reports whether the run finished without error.
+func (r PollRun) Succeeded() bool {
+	return r.ErrorClass == ""
+}
+
+// API key roles. Each role may do everything the ones before it may.
+const (
+	// RoleFeed only reads an app's feeds, so its keys can be put in feed URLs.
+	RoleFeed    = "feed"
+	RoleViewer  = "viewer"
+	RoleTriager = "triager"
+	RoleAdmin   = "admin"
+)
+
+// APIKey is a stored API key. Only a hash of the key itself is kept.
+type APIKey struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	Role string `json:"role"`
+	// Apps limits the key to these apps; empty allows every app.
+	Apps       []string   `json:"apps,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	Hash       string     `json:"-"`
+}
+
+// AllowsApp reports whether the key may see an app's data.
+func (k APIKey) AllowsApp(appID string) bool {
+	return len(k.Apps) == 0 || slices.Contains(k.Apps, appID)
 }
@@ -0,0 +1,405 @@
+// Package openapi reads the API's OpenAPI 3.0 description and checks requests
+// and responses against it. It understands the parts of the format the spec
+// uses: paths with path, query and header parameters, JSON request and
+// response bodies, local $refs, and schemas built from type, format, pattern,
+// enum, bounds, properties, items and allOf.
+package openapi
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Spec is an OpenAPI document.
+type Spec struct {
+	OpenAPI    string               `yaml:"openapi"`
+	Paths      map[string]*PathItem `yaml:"paths"`
+	Components Components           `yaml:"components"`
+
+	json     []byte
+	patterns sync.Map // pattern string -> *regexp.Regexp
+}
+
+// PathItem holds the operations on one path, and the parameters they share.
+type PathItem struct {
+	Parameters []*Parameter `yaml:"parameters"`
+	Get        *Operation   `yaml:"get"`
+	Put        *Operation   `yaml:"put"`
+	Post       *Operation   `yaml:"post"`
+	Patch      *Operation   `yaml:"patch"`
+	Delete     *Operation   `yaml:"delete"`
+}
+
+// Operation is one method on a path.
+type Operation struct {
+	OperationID string               `yaml:"operationId"`
+	Summary     string               `yaml:"summary"`
+	Deprecated  bool                 `yaml:"deprecated"`
+	Parameters  []*Parameter         `yaml:"parameters"`
+	RequestBody *RequestBody         `yaml:"requestBody"`
+	Responses   map[string]*Response `yaml:"responses"`
+
+	// params are the path item's and the operation's parameters, with refs
+	// resolved.
+	params []*Parameter
+}
+
+// Params returns the operation's parameters, including those it shares with
+// the other operations on its path.
+func (o *Operation) Params() []*Parameter {
+	return o.params
+}
+
+// Parameter is a path, query or header parameter.
+type Parameter struct {
+	Ref      string  `yaml:"$ref"`
+	Name     string  `yaml:"name"`
+	In       string  `yaml:"in"`
+	Required bool    `yaml:"required"`
+	Schema   *Schema `yaml:"schema"`
+	Example  any     `yaml:"example"`
+}
+
+// RequestBody is an operation's request body.
+type RequestBody struct {
+	Ref      string               `yaml:"$ref"`
+	Required bool                 `yaml:"required"`
+	Content  map[string]MediaType `yaml:"content"`
+}
+
+// Response is one of an operation's responses.
+type Response struct {
+	Ref         string               `yaml:"$ref"`
+	Description string               `yaml:"description"`
+	Content     map[string]MediaType `yaml:"content"`
+}
+
+// MediaType describes a body of one content type.
+type MediaType struct {
+	Schema  *Schema `yaml:"schema"`
+	Example any     `yaml:"example"`
+}
+
+// Components holds the definitions that $refs point at.
+type Components struct {
+	Schemas       map[string]*Schema      `yaml:"schemas"`
+	Parameters    map[string]*Parameter   `yaml:"parameters"`
+	RequestBodies map[string]*RequestBody `yaml:"requestBodies"`
+	Responses     map[string]*Response    `yaml:"responses"`
+}
+
+// Schema describes a JSON value.
+type Schema struct {
+	Ref                  string             `yaml:"$ref"`
+	Type                 string             `yaml:"type"`
+	Format               string             `yaml:"format"`
+	Pattern              string             `yaml:"pattern"`
+	Enum                 []any              `yaml:"enum"`
+	Nullable             bool               `yaml:"nullable"`
+	Minimum              *float64           `yaml:"minimum"`
+	Maximum              *float64           `yaml:"maximum"`
+	MinLength            *int               `yaml:"minLength"`
+	MaxLength            *int               `yaml:"maxLength"`
+	MinItems             *int               `yaml:"minItems"`
+	Required             []string           `yaml:"required"`
+	Properties           map[string]*Schema `yaml:"properties"`
+	AdditionalProperties *Additional        `yaml:"additionalProperties"`
+	Items                *Schema            `yaml:"items"`
+	AllOf                []*Schema          `yaml:"allOf"`
+}
+
+// Additional is a schema's additionalProperties: either whether other
+// properties are allowed at all, or the schema they must match.
+type Additional struct {
+	Allowed bool
+	Schema  *Schema
+}
+
+func (a *Additional) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		return node.Decode(&a.Allowed)
+	}
+	a.Allowed = true
+	return node.Decode(&a.Schema)
+}
+
+// Load parses a spec written in YAML or JSON, and checks that its $refs
+// resolve and its path templates' parameters are declared.
+func Load(data []byte) (*Spec, error) {
+	var s Spec
+	if err := yaml.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("parsing spec: %w", err)
+	}
+	var raw any
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("parsing spec: %w", err)
+	}
+	js, err := json.Marshal(jsonable(raw))
+	if err != nil {
+		return nil, fmt.Errorf("converting spec to JSON: %w", err)
+	}
+	s.json = js
+
+	var errs []error
+	for path, item := range s.Paths {
+		for method, op := range item.operations() {
+			if err := s.prepare(path, item, op); err != nil {
+				errs = append(errs, fmt.Errorf("%s %s: %w", method, path, err))
+			}
+		}
+	}
+	for name, schema := range s.Components.Schemas {
+		if err := s.checkSchema(schema); err != nil {
+			errs = append(errs, fmt.Errorf("schema %s: %w", name, err))
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// JSON returns the spec as JSON.
+func (s *Spec) JSON() []byte {
+	return s.json
+}
+
+// Route is an operation with the method and path it is found at.
+type Route struct {
+	Method    string
+	Path      string
+	Operation *Operation
+}
+
+// Routes lists every operation in the spec, by path and then method.
+func (s *Spec) Routes() []Route {
+	var routes []Route
+	for path, item := range s.Paths {
+		for method, op := range item.operations() {
+			routes = append(routes, Route{Method: method, Path: path, Operation: op})
+		}
+	}
+	slices.SortFunc(routes, func(a, b Route) int {
+		if c := strings.Compare(a.Path, b.Path); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Method, b.Method)
+	})
+	return routes
+}
+
+// Operation finds the operation for a method on a path template, such as
+// "/api/v1/apps/{app_id}/reviews". It returns nil if the spec has none.
+func (s *Spec) Operation(method, path string) *Operation {
+	item, ok := s.Paths[path]
+	if !ok {
+		return nil
+	}
+	return item.operations()[method]
+}
+
+// Response returns the response an operation documents for a status code, or
+// its default response, or nil.
+func (s *Spec) Response(op *Operation, status int) *Response {
+	resp, ok := op.Responses[fmt.Sprint(status)]
+	if !ok {
+		resp, ok = op.Responses["default"]
+	}
+	if !ok {
+		return nil
+	}
+	if resp.Ref != "" {
+		resp = s.Components.Responses[refName(resp.Ref, "responses")]
+	}
+	return resp
+}
+
+// Body returns an operation's request body, or nil if it takes none.
+func (s *Spec) Body(op *Operation) *RequestBody {
+	body := op.RequestBody
+	if body != nil && body.Ref != "" {
+		body = s.Components.RequestBodies[refName(body.Ref, "requestBodies")]
+	}
+	return body
+}
+
+func (item *PathItem) operations() map[string]*Operation {
+	ops := make(map[string]*Operation)
+	for method, op := range map[string]*Operation{
+		http.MethodGet:    item.Get,
+		http.MethodPut:    item.Put,
+		http.MethodPost:   item.Post,
+		http.MethodPatch:  item.Patch,
+		http.MethodDelete: item.Delete,
+	} {
+		if op != nil {
+			ops[method] = op
+		}
+	}
+	return ops
+}
+
+// prepare resolves an operation's parameters and checks its references.
+func (s *Spec) prepare(path string, item *PathItem, op *Operation) error {
+	var errs []error
+	byName := make(map[string]*Parameter)
+	var order []string
+	for _, p := range slices.Concat(item.Parameters, op.Parameters) {
+		if p.Ref != "" {
+			resolved, ok := s.Components.Parameters[refName(p.Ref, "parameters")]
+			if !ok {
+				errs = append(errs, fmt.Errorf("unresolved $ref %q", p.Ref))
+				continue
+			}
+			p = resolved
+		}
+		if err := s.checkSchema(p.Schema); err != nil {
+			errs = append(errs, fmt.Errorf("parameter %s: %w", p.Name, err))
+		}
+		key := p.In + ":" + p.Name
+		if _, ok := byName[key]; !ok {
+			order = append(order, key)
+		}
+		// operation parameters override the path item's
+		byName[key] = p
+	}
+	op.params = nil
+	for _, key := range order {
+		op.params = append(op.params, byName[key])
+	}
+
+	for _, segment := range strings.Split(path, "/") {
+		name, ok := strings.CutPrefix(segment, "{")
+		if !ok {
+			continue
+		}
+		name = strings.TrimSuffix(name, "}")
+		if p, ok := byName["path:"+name]; !ok || !p.Required {
+			errs = append(errs, fmt.Errorf("path parameter %s is not declared as required", name))
+		}
+	}
+
+	if op.RequestBody != nil && op.RequestBody.Ref != "" {
+		if _, ok := s.Components.RequestBodies[refName(op.RequestBody.Ref, "requestBodies")]; !ok {
+			errs = append(errs, fmt.Errorf("unresolved $ref %q", op.RequestBody.Ref))
+		}
+	}
+	if body := s.Body(op); body != nil {
+		for _, media := range body.Content {
+			if err := s.checkSchema(media.Schema); err != nil {
+				errs = append(errs, fmt.Errorf("request body: %w", err))
+			}
+		}
+	}
+
+	if len(op.Responses) == 0 {
+		errs = append(errs, errors.New("no responses"))
+	}
+	for status, resp := range op.Responses {
+		if resp.Ref != "" {
+			var ok bool
+			if resp, ok = s.Components.Responses[refName(resp.Ref, "responses")]; !ok {
+				errs = append(errs, fmt.Errorf("response %s: unresolved $ref %q", status, op.Responses[status].Ref))
+				continue
+			}
+		}
+		for _, media := range resp.Content {
+			if err := s.checkSchema(media.Schema); err != nil {
+				errs = append(errs, fmt.Errorf("response %s: %w", status, err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// checkSchema checks that a schema's $refs resolve and its patterns compile.
+func (s *Spec) checkSchema(schema *Schema) error {
+	if schema == nil {
+		return nil
+	}
+	if schema.Ref != "" {
+		if _, ok := s.Components.Schemas[refName(schema.Ref, "schemas")]; !ok {
+			return fmt.Errorf("unresolved $ref %q", schema.Ref)
+		}
+		return nil
+	}
+	var errs []error
+	if schema.Pattern != "" {
+		if _, err := s.pattern(schema.Pattern); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	for _, sub := range schema.Properties {
+		errs = append(errs, s.checkSchema(sub))
+	}
+	if schema.AdditionalProperties != nil {
+		errs = append(errs, s.checkSchema(schema.AdditionalProperties.Schema))
+	}
+	errs = append(errs, s.checkSchema(schema.Items))
+	for _, sub := range schema.AllOf {
+		errs = append(errs, s.checkSchema(sub))
+	}
+	return errors.Join(errs...)
+}
+
+// resolve follows a schema's $ref, if it has one.
+func (s *Spec) resolve(schema *Schema) *Schema {
+	for schema != nil && schema.Ref != "" {
+		schema = s.Components.Schemas[refName(schema.Ref, "schemas")]
+	}
+	return schema
+}
+
+func (s *Spec) pattern(pattern string) (*regexp.Regexp, error) {
+	if re, ok := s.patterns.Load(pattern); ok {
+		return re.(*regexp.Regexp), nil
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("pattern %q: %w", pattern, err)
+	}
+	s.patterns.Store(pattern, re)
+	return re, nil
+}
+
+// refName returns the name a local $ref such as "#/components/schemas/Review"
+// points at, if it points into the given kind of component.
+func refName(ref, kind string) string {
+	name, ok := strings.CutPrefix(ref, "#/components/"+kind+"/")
+	if !ok {
+		return ""
+	}
+	return name
+}
+
+// jsonable converts YAML mappings with non-string keys, such as status codes,
+// into ones encoding/json can marshal.
+func jsonable(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		for k, sub := range v {
+			v[k] = jsonable(sub)
+		}
+		return v
+	case map[any]any:
+		m := make(map[string]any, len(v))
+		for k, sub := range v {
+			m[fmt.Sprint(k)] = jsonable(sub)
+		}
+		return m
+	case []any:
+		for i, sub := range v {
+			v[i] = jsonable(sub)
+		}
+		return v
+	}
+	return v
+}
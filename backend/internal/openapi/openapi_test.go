@@ -0,0 +1,156 @@
+package openapi_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/furqanmk/reviews-browser/internal/openapi"
+	"github.com/stretchr/testify/require"
+)
+
+const testSpec = `
+openapi: 3.0.3
+info: {title: test, version: "1"}
+paths:
+  /items/{id}:
+    parameters:
+      - name: id
+        in: path
+        required: true
+        schema: {type: string, pattern: '^[0-9]+$'}
+    get:
+      operationId: getItem
+      parameters:
+        - name: limit
+          in: query
+          schema: {type: integer, minimum: 1, maximum: 10}
+        - name: tag
+          in: query
+          schema: {type: array, items: {type: string}}
+      responses:
+        '200':
+          description: ok
+          content:
+            application/json:
+              schema: {$ref: '#/components/schemas/Item'}
+    patch:
+      operationId: patchItem
+      requestBody:
+        required: true
+        content:
+          application/json:
+            schema:
+              type: object
+              additionalProperties: false
+              properties:
+                name: {type: string, minLength: 1}
+      responses:
+        '204': {description: done}
+    put:
+      operationId: uploadItem
+      requestBody:
+        required: true
+        content:
+          text/csv:
+            schema: {type: string}
+      responses:
+        '204': {description: done}
+components:
+  schemas:
+    Item:
+      type: object
+      required: [id]
+      properties:
+        id: {type: string}
+        tags: {type: array, items: {type: string}, nullable: true}
+`
+
+func load(t *testing.T) *openapi.Spec {
+	t.Helper()
+	spec, err := openapi.Load([]byte(testSpec))
+	require.NoError(t, err)
+	return spec
+}
+
+// route validates a request routed like the spec's /items/{id}.
+func route(t *testing.T, spec *openapi.Spec, method, target, body string) []openapi.FieldError {
+	t.Helper()
+	var errs []openapi.FieldError
+	mux := http.NewServeMux()
+	mux.HandleFunc(method+" /items/{id}", func(w http.ResponseWriter, r *http.Request) {
+		errs = spec.ValidateRequest(spec.Operation(method, "/items/{id}"), r)
+	})
+	mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(method, target, strings.NewReader(body)))
+	return errs
+}
+
+func TestLoad(t *testing.T) {
+	spec := load(t)
+	routes := spec.Routes()
+	require.Len(t, routes, 3)
+	require.Equal(t, "getItem", routes[0].Operation.OperationID)
+	require.Len(t, routes[0].Operation.Params(), 3)
+	require.Nil(t, spec.Operation(http.MethodPost, "/items/{id}"))
+	require.Contains(t, string(spec.JSON()), `"operationId":"getItem"`)
+
+	_, err := openapi.Load([]byte(strings.Replace(testSpec, "schemas/Item'", "schemas/Missing'", 1)))
+	require.ErrorContains(t, err, "Missing")
+
+	_, err = openapi.Load([]byte(strings.Replace(testSpec, "required: true\n        schema: {type: string, pattern", "schema: {type: string, pattern", 1)))
+	require.Error(t, err, "path parameters must be required")
+}
+
+func TestValidateRequest(t *testing.T) {
+	spec := load(t)
+
+	require.Empty(t, route(t, spec, http.MethodGet, "/items/42?limit=5&tag=a&tag=b", ""))
+	require.Equal(t, []openapi.FieldError{{In: "path", Name: "id", Reason: "must match ^[0-9]+$"}},
+		route(t, spec, http.MethodGet, "/items/abc", ""))
+	require.Equal(t, []openapi.FieldError{{In: "query", Name: "limit", Reason: "must be an integer"}},
+		route(t, spec, http.MethodGet, "/items/42?limit=many", ""))
+	require.Equal(t, []openapi.FieldError{{In: "query", Name: "limit", Reason: "must be at most 10"}},
+		route(t, spec, http.MethodGet, "/items/42?limit=11", ""))
+	require.Equal(t, []openapi.FieldError{{In: "query", Name: "limit", Reason: "must be given once"}},
+		route(t, spec, http.MethodGet, "/items/42?limit=1&limit=2", ""))
+
+	require.Empty(t, route(t, spec, http.MethodPatch, "/items/42", `{"name": "x"}`))
+	require.Equal(t, []openapi.FieldError{{In: "body", Reason: "is required"}},
+		route(t, spec, http.MethodPatch, "/items/42", ""))
+	require.Equal(t, []openapi.FieldError{{In: "body", Reason: "must be valid JSON"}},
+		route(t, spec, http.MethodPatch, "/items/42", `{"name":`))
+	require.Equal(t, []openapi.FieldError{
+		{In: "body", Name: "colour", Reason: "is not a known property"},
+		{In: "body", Name: "name", Reason: "must be at least 1 characters"},
+	}, route(t, spec, http.MethodPatch, "/items/42", `{"name": "", "colour": "red"}`))
+
+	// other bodies are left for the handler to stream, however large
+	upload := strings.Repeat("1,2\n", openapi.MaxBodyBytes)
+	mux := http.NewServeMux()
+	mux.HandleFunc("PUT /items/{id}", func(w http.ResponseWriter, r *http.Request) {
+		require.Empty(t, spec.ValidateRequest(spec.Operation(http.MethodPut, "/items/{id}"), r))
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		require.Len(t, body, len(upload))
+	})
+	mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPut, "/items/42", strings.NewReader(upload)))
+}
+
+func TestValidateResponse(t *testing.T) {
+	spec := load(t)
+	get := spec.Operation(http.MethodGet, "/items/{id}")
+	patch := spec.Operation(http.MethodPatch, "/items/{id}")
+	jsonHeader := http.Header{"Content-Type": {"application/json; charset=utf-8"}}
+
+	require.NoError(t, spec.ValidateResponse(get, 200, jsonHeader, []byte(`{"id": "1", "tags": null}`)))
+	require.NoError(t, spec.ValidateResponse(patch, 204, http.Header{}, nil))
+
+	require.ErrorContains(t, spec.ValidateResponse(get, 404, jsonHeader, []byte(`{}`)), "status 404 is not documented")
+	require.ErrorContains(t, spec.ValidateResponse(get, 200, http.Header{"Content-Type": {"text/plain"}}, []byte(`x`)), "content type")
+	require.ErrorContains(t, spec.ValidateResponse(get, 200, jsonHeader, []byte(`{"tags": []}`)), "id: is required")
+	// responses are strict: undocumented fields fail
+	require.ErrorContains(t, spec.ValidateResponse(get, 200, jsonHeader, []byte(`{"id": "1", "extra": 1}`)), "extra: is not a known property")
+	require.ErrorContains(t, spec.ValidateResponse(patch, 204, http.Header{}, []byte(`{}`)), "without a body")
+}
@@ -0,0 +1,404 @@
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"mime"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// MaxBodyBytes is the largest request body ValidateRequest reads.
+const MaxBodyBytes = 1 << 20
+
+// FieldError is something wrong with one part of a request.
+type FieldError struct {
+	// In is where the part is: "path", "query", "header" or "body".
+	In string `json:"in"`
+	// Name is the parameter's name, or the path to a field of the body, such
+	// as "tags[0]". It is empty for the body as a whole.
+	Name   string `json:"name,omitempty"`
+	Reason string `json:"reason"`
+}
+
+func (e FieldError) String() string {
+	if e.Name == "" {
+		return e.In + ": " + e.Reason
+	}
+	return e.In + " " + e.Name + ": " + e.Reason
+}
+
+// violation is a value not matching a schema, at a path within the value.
+type violation struct {
+	at     string
+	reason string
+}
+
+// ValidateRequest checks a request's parameters and body against an
+// operation. Path parameters are read with r.PathValue, so the request must
+// have been routed by a pattern using the same wildcard names as the spec. The
+// body is read and replaced, so handlers can still read it.
+func (s *Spec) ValidateRequest(op *Operation, r *http.Request) []FieldError {
+	var errs []FieldError
+	for _, p := range op.params {
+		var values []string
+		switch p.In {
+		case "path":
+			if v := r.PathValue(p.Name); v != "" {
+				values = []string{v}
+			}
+		case "query":
+			values = r.URL.Query()[p.Name]
+		case "header":
+			values = r.Header.Values(p.Name)
+		}
+		if len(values) == 0 {
+			if p.Required {
+				errs = append(errs, FieldError{In: p.In, Name: p.Name, Reason: "is required"})
+			}
+			continue
+		}
+		for _, v := range s.checkParam(p, values) {
+			errs = append(errs, FieldError{In: p.In, Name: p.Name, Reason: v.reason})
+		}
+	}
+	return append(errs, s.checkBody(op, r)...)
+}
+
+// checkParam checks a parameter's raw values, given once each unless its
+// schema is an array.
+func (s *Spec) checkParam(p *Parameter, values []string) []violation {
+	schema := s.merged(p.Schema)
+	if schema == nil {
+		return nil
+	}
+	if schema.Type != "array" {
+		if len(values) > 1 {
+			return []violation{{reason: "must be given once"}}
+		}
+		v, err := coerce(schema, values[0])
+		if err != nil {
+			return []violation{{reason: err.Error()}}
+		}
+		return s.check(schema, v, "", false)
+	}
+
+	items := s.merged(schema.Items)
+	list := make([]any, 0, len(values))
+	for _, raw := range values {
+		v, err := coerce(items, raw)
+		if err != nil {
+			return []violation{{reason: err.Error()}}
+		}
+		list = append(list, v)
+	}
+	return s.check(schema, list, "", false)
+}
+
+// coerce converts a raw parameter value to the JSON type its schema names.
+func coerce(schema *Schema, raw string) (any, error) {
+	if schema == nil {
+		return raw, nil
+	}
+	switch schema.Type {
+	case "integer":
+		n, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return nil, errors.New("must be an integer")
+		}
+		return float64(n), nil
+	case "number":
+		n, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return nil, errors.New("must be a number")
+		}
+		return n, nil
+	case "boolean":
+		switch raw {
+		case "true":
+			return true, nil
+		case "false":
+			return false, nil
+		}
+		return nil, errors.New("must be true or false")
+	}
+	return raw, nil
+}
+
+// checkBody checks a request's body against the operation's request body.
+func (s *Spec) checkBody(op *Operation, r *http.Request) []FieldError {
+	spec := s.Body(op)
+	if spec == nil || r.Body == nil {
+		return nil
+	}
+	// only JSON bodies are checked; others, such as uploaded files, are left
+	// unread for the handler to stream
+	media, ok := spec.Content["application/json"]
+	if !ok {
+		return nil
+	}
+	body, err := io.ReadAll(io.LimitReader(r.Body, MaxBodyBytes+1))
+	r.Body.Close()
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	if err != nil {
+		return []FieldError{{In: "body", Reason: "could not be read"}}
+	}
+	if len(body) > MaxBodyBytes {
+		return []FieldError{{In: "body", Reason: fmt.Sprintf("must be at most %d bytes", MaxBodyBytes)}}
+	}
+	if len(bytes.TrimSpace(body)) == 0 {
+		if spec.Required {
+			return []FieldError{{In: "body", Reason: "is required"}}
+		}
+		return nil
+	}
+
+	if ct := r.Header.Get("Content-Type"); ct != "" && !isJSON(mediaType(ct)) {
+		return []FieldError{{In: "header", Name: "Content-Type", Reason: "must be application/json"}}
+	}
+	var v any
+	if err := json.Unmarshal(body, &v); err != nil {
+		return []FieldError{{In: "body", Reason: "must be valid JSON"}}
+	}
+	var errs []FieldError
+	for _, v := range s.check(media.Schema, v, "", false) {
+		errs = append(errs, FieldError{In: "body", Name: v.at, Reason: v.reason})
+	}
+	return errs
+}
+
+// ValidateResponse checks that an operation documents a response's status and
+// content type, and that a JSON body matches its schema. Responses are held
+// to their schemas strictly: properties the schema doesn't name are refused
+// unless it allows additional properties, so that fields added to a handler
+// must be documented too.
+func (s *Spec) ValidateResponse(op *Operation, status int, header http.Header, body []byte) error {
+	resp := s.Response(op, status)
+	if resp == nil {
+		return fmt.Errorf("status %d is not documented", status)
+	}
+	if len(resp.Content) == 0 {
+		if len(body) > 0 {
+			return fmt.Errorf("status %d is documented without a body, got %q", status, body)
+		}
+		return nil
+	}
+
+	ct := mediaType(header.Get("Content-Type"))
+	media, ok := resp.Content[ct]
+	if !ok {
+		documented := make([]string, 0, len(resp.Content))
+		for ct := range resp.Content {
+			documented = append(documented, ct)
+		}
+		slices.Sort(documented)
+		return fmt.Errorf("status %d: content type %q is not documented, want one of %v", status, ct, documented)
+	}
+	if media.Schema == nil || !isJSON(ct) {
+		return nil
+	}
+
+	var v any
+	if err := json.Unmarshal(body, &v); err != nil {
+		return fmt.Errorf("status %d: body is not JSON: %w", status, err)
+	}
+	var errs []error
+	for _, v := range s.check(media.Schema, v, "", true) {
+		at := v.at
+		if at == "" {
+			at = "body"
+		}
+		errs = append(errs, fmt.Errorf("status %d: %s: %s", status, at, v.reason))
+	}
+	return errors.Join(errs...)
+}
+
+// check matches a JSON value against a schema. In strict mode, properties an
+// object schema doesn't name are violations unless it sets
+// additionalProperties.
+func (s *Spec) check(schema *Schema, v any, at string, strict bool) []violation {
+	schema = s.merged(schema)
+	if schema == nil {
+		return nil
+	}
+	fail := func(format string, args ...any) []violation {
+		return []violation{{at: at, reason: fmt.Sprintf(format, args...)}}
+	}
+
+	if v == nil {
+		if schema.Nullable || schema.Type == "" {
+			return nil
+		}
+		return fail("must not be null")
+	}
+	if len(schema.Enum) > 0 && !slices.ContainsFunc(schema.Enum, func(e any) bool { return fmt.Sprint(e) == fmt.Sprint(v) }) {
+		return fail("must be one of %v", schema.Enum)
+	}
+
+	switch schema.Type {
+	case "string":
+		str, ok := v.(string)
+		if !ok {
+			return fail("must be a string")
+		}
+		return s.checkString(schema, str, at)
+	case "integer", "number":
+		n, ok := v.(float64)
+		if !ok {
+			return fail("must be a number")
+		}
+		if schema.Type == "integer" && n != math.Trunc(n) {
+			return fail("must be an integer")
+		}
+		if schema.Minimum != nil && n < *schema.Minimum {
+			return fail("must be at least %v", *schema.Minimum)
+		}
+		if schema.Maximum != nil && n > *schema.Maximum {
+			return fail("must be at most %v", *schema.Maximum)
+		}
+	case "boolean":
+		if _, ok := v.(bool); !ok {
+			return fail("must be true or false")
+		}
+	case "array":
+		list, ok := v.([]any)
+		if !ok {
+			return fail("must be an array")
+		}
+		if schema.MinItems != nil && len(list) < *schema.MinItems {
+			return fail("must have at least %d items", *schema.MinItems)
+		}
+		var violations []violation
+		for i, item := range list {
+			violations = append(violations, s.check(schema.Items, item, fmt.Sprintf("%s[%d]", at, i), strict)...)
+		}
+		return violations
+	case "object":
+		obj, ok := v.(map[string]any)
+		if !ok {
+			return fail("must be an object")
+		}
+		return s.checkObject(schema, obj, at, strict)
+	}
+	return nil
+}
+
+func (s *Spec) checkString(schema *Schema, str, at string) []violation {
+	fail := func(format string, args ...any) []violation {
+		return []violation{{at: at, reason: fmt.Sprintf(format, args...)}}
+	}
+	n := utf8.RuneCountInString(str)
+	if schema.MinLength != nil && n < *schema.MinLength {
+		return fail("must be at least %d characters", *schema.MinLength)
+	}
+	if schema.MaxLength != nil && n > *schema.MaxLength {
+		return fail("must be at most %d characters", *schema.MaxLength)
+	}
+	switch schema.Format {
+	case "date-time":
+		if _, err := time.Parse(time.RFC3339, str); err != nil {
+			return fail("must be an RFC 3339 date-time")
+		}
+	case "date":
+		if _, err := time.Parse(time.DateOnly, str); err != nil {
+			return fail("must be a date (YYYY-MM-DD)")
+		}
+	}
+	if schema.Pattern != "" {
+		re, err := s.pattern(schema.Pattern)
+		if err != nil {
+			return fail("%v", err)
+		}
+		if !re.MatchString(str) {
+			return fail("must match %s", schema.Pattern)
+		}
+	}
+	return nil
+}
+
+func (s *Spec) checkObject(schema *Schema, obj map[string]any, at string, strict bool) []violation {
+	var violations []violation
+	field := func(name string) string {
+		if at == "" {
+			return name
+		}
+		return at + "." + name
+	}
+	for _, name := range schema.Required {
+		if _, ok := obj[name]; !ok {
+			violations = append(violations, violation{at: field(name), reason: "is required"})
+		}
+	}
+
+	names := make([]string, 0, len(obj))
+	for name := range obj {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		if sub, ok := schema.Properties[name]; ok {
+			violations = append(violations, s.check(sub, obj[name], field(name), strict)...)
+			continue
+		}
+		extra := schema.AdditionalProperties
+		switch {
+		case extra != nil && extra.Schema != nil:
+			violations = append(violations, s.check(extra.Schema, obj[name], field(name), strict)...)
+		case extra != nil && !extra.Allowed, extra == nil && strict:
+			violations = append(violations, violation{at: field(name), reason: "is not a known property"})
+		}
+	}
+	return violations
+}
+
+// merged resolves a schema's $ref and folds its allOf parts into one schema.
+func (s *Spec) merged(schema *Schema) *Schema {
+	schema = s.resolve(schema)
+	if schema == nil || len(schema.AllOf) == 0 {
+		return schema
+	}
+	m := *schema
+	m.AllOf, m.Required = nil, nil
+	m.Properties = make(map[string]*Schema)
+	for _, part := range append(slices.Clone(schema.AllOf), &Schema{Properties: schema.Properties, Required: schema.Required}) {
+		part = s.merged(part)
+		if part == nil {
+			continue
+		}
+		if m.Type == "" {
+			m.Type = part.Type
+		}
+		for name, sub := range part.Properties {
+			m.Properties[name] = sub
+		}
+		for _, name := range part.Required {
+			if !slices.Contains(m.Required, name) {
+				m.Required = append(m.Required, name)
+			}
+		}
+		if part.AdditionalProperties != nil {
+			m.AdditionalProperties = part.AdditionalProperties
+		}
+	}
+	return &m
+}
+
+func mediaType(contentType string) string {
+	mt, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return strings.TrimSpace(contentType)
+	}
+	return mt
+}
+
+func isJSON(mediaType string) bool {
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
@@ -0,0 +1,22 @@
+package polling
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	lastSuccess = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "reviews_browser",
+		Subsystem: "poll",
+		Name:      "last_success_timestamp_seconds",
+		Help:      "Unix time of the last poll that fetched and stored an app's reviews.",
+	}, []string{"app_id"})
+
+	pollRuns = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "reviews_browser",
+		Subsystem: "poll",
+		Name:      "runs_total",
+		Help:      "Polls run, by app and result: ok, or the class of error that failed them.",
+	}, []string{"app_id", "result"})
+)
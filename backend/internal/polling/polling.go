@@ -2,35 +2,49 @@ package polling
 
 import (
 	"context"
-	"log"
+	"errors"
+	"fmt"
+	"net"
 	"sync"
 	"time"
 
 	"github.com/furqanmk/reviews-browser/internal/clients/appstore"
 	"github.com/furqanmk/reviews-browser/internal/database"
+	"github.com/furqanmk/reviews-browser/internal/ingest"
+	"github.com/furqanmk/reviews-browser/internal/logging"
 	"github.com/furqanmk/reviews-browser/internal/model"
+	"github.com/furqanmk/reviews-browser/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var (
+	logger = logging.For("polling")
+	tracer = tracing.Tracer("polling")
 )
 
 // PollingScheduler manages polling of app reviews.
 type PollingScheduler struct {
 	db        *database.DB
 	appClient *appstore.Client
+	pipeline  *ingest.Pipeline
 	wg        sync.WaitGroup
 	stopCh    chan struct{}
 }
 
 // NewPollingAgent creates a new polling agent.
-func NewPollingScheduler(DB *database.DB, AppClient *appstore.Client) *PollingScheduler {
+func NewPollingScheduler(DB *database.DB, AppClient *appstore.Client, Pipeline *ingest.Pipeline) *PollingScheduler {
 	return &PollingScheduler{
 		db:        DB,
 		appClient: AppClient,
+		pipeline:  Pipeline,
 		stopCh:    make(chan struct{}),
 	}
 }
 
 // Start begins polling for all apps.
 func (a *PollingScheduler) Start(ctx context.Context) error {
-	log.Print("Starting polling scheduler...")
+	logger.InfoContext(ctx, "starting polling scheduler")
 
 	apps, err := a.db.GetApps(ctx)
 	if err != nil {
@@ -47,6 +61,8 @@ func (a *PollingScheduler) Start(ctx context.Context) error {
 // pollForReviews schedules polling for a single app.
 func (a *PollingScheduler) pollForReviews(ctx context.Context, app model.App) {
 	lastFetched := app.LastFetched
+	log := logger.With("app_id", app.ID)
+	log.DebugContext(ctx, "polling app", "every", time.Duration(app.PollEverySeconds)*time.Second)
 
 	defer a.wg.Done()
 	for {
@@ -56,27 +72,119 @@ func (a *PollingScheduler) pollForReviews(ctx context.Context, app model.App) {
 			select {
 			case <-time.After(wait):
 			case <-a.stopCh:
-				break
+				return
+			case <-ctx.Done():
+				return
 			}
 		}
 
-		// Fetch recent reviews and update data store
-		reviews, err := a.appClient.FetchRecentReviews(app.ID)
-		if err != nil {
-			log.Printf("Error fetching reviews for app %s: %v", app.ID, err)
-		} else {
-			for _, review := range reviews {
-				_ = a.db.InsertReview(ctx, review, app.ID)
-			}
+		if _, err := a.PollOnce(ctx, app.ID); err != nil {
+			log.ErrorContext(ctx, "poll failed", "error", err)
 		}
-
-		// Update last fetched time
 		lastFetched = time.Now()
-		err = a.db.UpdateLastFetched(ctx, app.ID, lastFetched)
+	}
+}
+
+// PollOnce fetches the recent reviews for one app, stores them and records the
+// run in the app's poll history, returning it. An app that isn't tracked, such
+// as one fetched on demand through the API, has its reviews stored but keeps
+// no poll history or per-app metrics.
+func (a *PollingScheduler) PollOnce(ctx context.Context, appID string) (model.PollRun, error) {
+	return a.poll(ctx, "polling.PollOnce", appID, a.appClient.FetchRecentReviews)
+}
+
+// Backfill is PollOnce for every review the App Store still serves for the
+// app, however many pages back, to fill in its history within the recency
+// cutoff. Reviews already stored are skipped as duplicates, so a backfill can
+// be repeated, and reviews older than the cutoff are counted as stale rather
+// than stored.
+func (a *PollingScheduler) Backfill(ctx context.Context, appID string) (model.PollRun, error) {
+	return a.poll(ctx, "polling.Backfill", appID, a.appClient.FetchAllReviews)
+}
+
+// poll fetches an app's reviews with fetchReviews, stores them and records
+// the run.
+func (a *PollingScheduler) poll(ctx context.Context, spanName, appID string,
+	fetchReviews func(context.Context, string) (appstore.Fetch, error)) (_ model.PollRun, err error) {
+	// everything traced and logged for this poll, down to the store, names
+	// the app
+	ctx, span := tracer.Start(ctx, spanName,
+		trace.WithAttributes(attribute.String("app_id", appID)))
+	defer func() { tracing.End(span, err) }()
+	ctx = logging.With(ctx, "app_id", appID)
+
+	run := model.PollRun{AppID: appID, StartedAt: time.Now()}
+
+	// Fetch reviews and update data store
+	fetch, err := fetchReviews(ctx, appID)
+	run.Pages = fetch.Pages
+	if err != nil {
+		run.ErrorClass = errorClass(err)
+		err = fmt.Errorf("fetching reviews: %w", err)
+	} else {
+		var result ingest.Result
+		result, err = a.pipeline.Ingest(ctx, appID, fetch.Reviews)
+		run.Seen, run.New, run.Duplicate, run.Failed, run.Stale = result.Seen, result.New, result.Duplicate, result.Failed, result.Stale
 		if err != nil {
-			log.Printf("Error updating last fetched time for app %s: %v", app.ID, err)
+			run.ErrorClass = model.PollErrorStore
+			err = fmt.Errorf("storing reviews: %w", err)
 		}
+		logger.InfoContext(ctx, "polled app", "pages", run.Pages,
+			"seen", result.Seen, "new", result.New, "duplicate", result.Duplicate, "failed", result.Failed, "stale", result.Stale)
+	}
+	run.FinishedAt = time.Now()
+	if err != nil {
+		run.Error = err.Error()
+	}
+
+	recordErr := a.db.RecordPoll(ctx, run)
+	tracked := !errors.Is(recordErr, database.ErrNotFound)
+	if tracked {
+		pollRuns.WithLabelValues(appID, runResult(run)).Inc()
+	}
+	if tracked && recordErr != nil {
+		return run, errors.Join(err, fmt.Errorf("recording poll: %w", recordErr))
+	}
+	if err != nil {
+		return run, err
+	}
+	if tracked {
+		lastSuccess.WithLabelValues(appID).SetToCurrentTime()
+	}
+	return run, nil
+}
+
+// errorClass sorts a failed fetch into one of the poll error classes.
+func errorClass(err error) string {
+	var (
+		statusErr *appstore.StatusError
+		netErr    net.Error
+	)
+	switch {
+	case errors.Is(err, appstore.ErrCircuitOpen):
+		return model.PollErrorCircuitOpen
+	case errors.Is(err, context.Canceled):
+		return model.PollErrorCanceled
+	case errors.Is(err, context.DeadlineExceeded):
+		return model.PollErrorTimeout
+	case errors.As(err, &statusErr):
+		return model.PollErrorHTTPStatus
+	case errors.Is(err, appstore.ErrBadResponse):
+		return model.PollErrorBadResponse
+	case errors.As(err, &netErr) && netErr.Timeout():
+		return model.PollErrorTimeout
+	case errors.As(err, &netErr):
+		return model.PollErrorNetwork
+	}
+	return model.PollErrorUnknown
+}
+
+// runResult labels a run for the poll metrics: "ok", or its error class.
+func runResult(run model.PollRun) string {
+	if run.Succeeded() {
+		return "ok"
 	}
+	return run.ErrorClass
 }
 
 // Stop halts all polling goroutines.
@@ -0,0 +1,104 @@
+// Package ratelimit keeps per-client request budgets in memory, as token
+// buckets that refill steadily over a minute.
+package ratelimit
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// Window is the period a budget's limit applies to.
+const Window = time.Minute
+
+// Decision is the outcome of asking for a request, and the state of the
+// client's budget afterwards.
+type Decision struct {
+	Allowed bool
+	// Limit is the budget's size: the requests allowed per Window.
+	Limit int
+	// Remaining is how many more requests would be allowed right now.
+	Remaining int
+	// Reset is how long until the budget is full again.
+	Reset time.Duration
+	// RetryAfter is how long until the next request would be allowed, if
+	// this one wasn't.
+	RetryAfter time.Duration
+}
+
+// Limiter tracks many clients' budgets. Budgets that have refilled are dropped
+// now and then, so idle clients cost nothing.
+type Limiter struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+	limit  int
+}
+
+// New creates a limiter with no clients.
+func New() *Limiter {
+	return &Limiter{buckets: make(map[string]*bucket)}
+}
+
+// Allow takes one request from a client's budget of limit requests per Window,
+// if it has one left. limit must be positive.
+func (l *Limiter) Allow(client string, limit int, now time.Time) Decision {
+	return l.take(client, limit, 1, now)
+}
+
+// Peek reports a client's budget without taking from it.
+func (l *Limiter) Peek(client string, limit int, now time.Time) Decision {
+	return l.take(client, limit, 0, now)
+}
+
+func (l *Limiter) take(client string, limit int, n float64, now time.Time) Decision {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.sweep(now)
+
+	rate := float64(limit) / Window.Seconds()
+	b, ok := l.buckets[client]
+	if !ok || b.limit != limit {
+		// a new client, or a changed limit, starts with a full budget
+		b = &bucket{tokens: float64(limit), last: now, limit: limit}
+		l.buckets[client] = b
+	}
+	if elapsed := now.Sub(b.last); elapsed > 0 {
+		b.tokens = math.Min(float64(limit), b.tokens+elapsed.Seconds()*rate)
+		b.last = now
+	}
+
+	d := Decision{Limit: limit}
+	if b.tokens >= n {
+		b.tokens -= n
+		d.Allowed = true
+	} else {
+		d.RetryAfter = seconds((n - b.tokens) / rate)
+	}
+	d.Remaining = int(b.tokens)
+	d.Reset = seconds((float64(limit) - b.tokens) / rate)
+	return d
+}
+
+// sweep drops budgets that have had time to refill, at most once a Window.
+// l.mu must be held.
+func (l *Limiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < Window {
+		return
+	}
+	l.lastSweep = now
+	for client, b := range l.buckets {
+		if now.Sub(b.last) >= Window {
+			delete(l.buckets, client)
+		}
+	}
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
@@ -0,0 +1,73 @@
+package ratelimit_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/furqanmk/reviews-browser/internal/ratelimit"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAllow_SpendsBudget(t *testing.T) {
+	l := ratelimit.New()
+	now := time.Now()
+
+	for i := range 3 {
+		d := l.Allow("a", 3, now)
+		require.True(t, d.Allowed)
+		require.Equal(t, 3, d.Limit)
+		require.Equal(t, 2-i, d.Remaining)
+	}
+
+	d := l.Allow("a", 3, now)
+	require.False(t, d.Allowed)
+	require.Equal(t, 0, d.Remaining)
+	require.Equal(t, 20*time.Second, d.RetryAfter)
+	require.Equal(t, time.Minute, d.Reset)
+
+	// other clients have their own budgets
+	require.True(t, l.Allow("b", 3, now).Allowed)
+}
+
+func TestAllow_Refills(t *testing.T) {
+	l := ratelimit.New()
+	now := time.Now()
+	for range 6 {
+		l.Allow("a", 6, now)
+	}
+	require.False(t, l.Allow("a", 6, now).Allowed)
+
+	// one request comes back every 10 seconds
+	d := l.Allow("a", 6, now.Add(10*time.Second))
+	require.True(t, d.Allowed)
+	require.Equal(t, 0, d.Remaining)
+
+	// and the budget never grows past its limit
+	d = l.Allow("a", 6, now.Add(time.Hour))
+	require.True(t, d.Allowed)
+	require.Equal(t, 5, d.Remaining)
+}
+
+func TestPeek_DoesNotSpend(t *testing.T) {
+	l := ratelimit.New()
+	now := time.Now()
+	l.Allow("a", 10, now)
+
+	for range 3 {
+		d := l.Peek("a", 10, now)
+		require.True(t, d.Allowed)
+		require.Equal(t, 9, d.Remaining)
+	}
+	require.Equal(t, 10, l.Peek("b", 10, now).Remaining)
+}
+
+func TestAllow_LimitChangeResets(t *testing.T) {
+	l := ratelimit.New()
+	now := time.Now()
+	l.Allow("a", 1, now)
+	require.False(t, l.Allow("a", 1, now).Allowed)
+
+	d := l.Allow("a", 5, now)
+	require.True(t, d.Allowed)
+	require.Equal(t, 4, d.Remaining)
+}
@@ -0,0 +1,160 @@
+package redact
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Rule detects one kind of personal information and the mask that replaces it.
+type Rule struct {
+	Name    string
+	Pattern *regexp.Regexp
+	Mask    string
+	// Group is the capture group to mask; 0 masks the whole match.
+	Group int
+	// Valid, if set, rejects matches that fit the pattern but aren't PII.
+	Valid func(match string) bool
+}
+
+// Rules are the built-in redaction rules, keyed by name. They are applied in
+// DefaultRules order so that, for instance, a card number isn't first
+// half-masked as a phone number.
+var Rules = map[string]Rule{
+	"email": {
+		Name:    "email",
+		Pattern: regexp.MustCompile(`(?i)[a-z0-9._%+-]+@[a-z0-9.-]+\.[a-z]{2,}`),
+		Mask:    "[EMAIL]",
+	},
+	"card_number": {
+		Name:    "card_number",
+		Pattern: regexp.MustCompile(`\b\d(?:[ -]?\d){12,18}\b`),
+		Mask:    "[CARD]",
+		Valid:   luhn,
+	},
+	"order_id": {
+		Name:    "order_id",
+		Pattern: regexp.MustCompile(`(?i)\b(?:order|invoice|receipt|transaction)\s*(?:id|number|no\.?|#)?\s*[:#]?\s*([a-z0-9][a-z0-9-]{5,})`),
+		Mask:    "[ORDER_ID]",
+		Group:   1,
+		Valid:   hasDigit,
+	},
+	"phone": {
+		Name:    "phone",
+		Pattern: regexp.MustCompile(`\+?\(?\d[\d\s().-]{7,}\d`),
+		Mask:    "[PHONE]",
+		Valid:   func(match string) bool { return countDigits(match) >= 9 },
+	},
+}
+
+// DefaultRules lists every built-in rule in the order they are applied.
+var DefaultRules = []string{"email", "card_number", "order_id", "phone"}
+
+// Redactor masks personal information in text using a set of rules.
+type Redactor struct {
+	rules []Rule
+}
+
+// New creates a redactor from rule names. Unknown names are an error.
+func New(names []string) (*Redactor, error) {
+	r := &Redactor{}
+	wanted := make(map[string]bool)
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if _, ok := Rules[name]; !ok {
+			return nil, fmt.Errorf("unknown redaction rule %q", name)
+		}
+		wanted[name] = true
+	}
+	for _, name := range DefaultRules {
+		if wanted[name] {
+			r.rules = append(r.rules, Rules[name])
+		}
+	}
+	return r, nil
+}
+
+// Redact masks personal information in text, returning the masked text and the
+// names of the rules that fired, sorted.
+func (r *Redactor) Redact(text string) (string, []string) {
+	var fired []string
+	for _, rule := range r.rules {
+		masked, n := rule.apply(text)
+		if n > 0 {
+			text = masked
+			fired = append(fired, rule.Name)
+		}
+	}
+	sort.Strings(fired)
+	return text, fired
+}
+
+// apply masks every valid match of the rule, returning the new text and the
+// number of matches masked.
+func (rule Rule) apply(text string) (string, int) {
+	var (
+		out   strings.Builder
+		last  int
+		count int
+	)
+	for _, m := range rule.Pattern.FindAllStringSubmatchIndex(text, -1) {
+		start, end := m[2*rule.Group], m[2*rule.Group+1]
+		if start < 0 {
+			continue
+		}
+		if rule.Valid != nil && !rule.Valid(text[start:end]) {
+			continue
+		}
+		out.WriteString(text[last:start])
+		out.WriteString(rule.Mask)
+		last = end
+		count++
+	}
+	if count == 0 {
+		return text, 0
+	}
+	out.WriteString(text[last:])
+	return out.String(), count
+}
+
+func countDigits(s string) int {
+	n := 0
+	for _, r := range s {
+		if unicode.IsDigit(r) {
+			n++
+		}
+	}
+	return n
+}
+
+func hasDigit(s string) bool {
+	return countDigits(s) > 0
+}
+
+// luhn reports whether the digits in s pass the Luhn checksum used by payment
+// card numbers.
+func luhn(s string) bool {
+	var digits []int
+	for _, r := range s {
+		if r >= '0' && r <= '9' {
+			digits = append(digits, int(r-'0'))
+		}
+	}
+	sum := 0
+	for i := len(digits) - 1; i >= 0; i-- {
+		d := digits[i]
+		if (len(digits)-i)%2 == 0 {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+	}
+	return len(digits) >= 13 && sum%10 == 0
+}
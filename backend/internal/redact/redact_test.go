@@ -0,0 +1,58 @@
+package redact_test
+
+import (
+	"testing"
+
+	"github.com/furqanmk/reviews-browser/internal/redact"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedact(t *testing.T) {
+	r, err := redact.New(redact.DefaultRules)
+	require.NoError(t, err)
+
+	cases := []struct {
+		text  string
+		want  string
+		fired []string
+	}{
+		{
+			text:  "Email me at jane.doe+apps@example.co.uk please",
+			want:  "Email me at [EMAIL] please",
+			fired: []string{"email"},
+		},
+		{
+			text:  "Call +1 (415) 555-0132 or 020 7946 0958",
+			want:  "Call [PHONE] or [PHONE]",
+			fired: []string{"phone"},
+		},
+		{
+			text:  "Charged twice on 4111 1111 1111 1111, order #MK3JT9S7DN",
+			want:  "Charged twice on [CARD], order #[ORDER_ID]",
+			fired: []string{"card_number", "order_id"},
+		},
+		{
+			text: "Version 17.0.1 broke it on 2023-11-15, ordered pizza instead",
+			want: "Version 17.0.1 broke it on 2023-11-15, ordered pizza instead",
+		},
+	}
+	for _, c := range cases {
+		got, fired := r.Redact(c.text)
+		require.Equal(t, c.want, got, c.text)
+		require.Equal(t, c.fired, fired, c.text)
+	}
+}
+
+func TestNew_UnknownRule(t *testing.T) {
+	_, err := redact.New([]string{"email", "ssn"})
+	require.Error(t, err)
+}
+
+func TestNew_Subset(t *testing.T) {
+	r, err := redact.New([]string{"phone"})
+	require.NoError(t, err)
+
+	got, fired := r.Redact("me@example.com 415 555 0132")
+	require.Equal(t, "me@example.com [PHONE]", got)
+	require.Equal(t, []string{"phone"}, fired)
+}
@@ -0,0 +1,96 @@
+package spam
+
+import (
+	"hash/fnv"
+	"strings"
+	"unicode"
+)
+
+const (
+	// shingleSize is the length, in runes, of the character shingles compared.
+	shingleSize = 4
+	// signatureSize is the number of hash functions in a MinHash signature.
+	signatureSize = 128
+	// minShingles is the fewest shingles a text needs to be compared. Texts
+	// with fewer are all alike: an empty text signs as every other empty text
+	// does, and a word or two shares most of its few shingles by chance.
+	minShingles = 5
+)
+
+// Signature is a MinHash sketch of a text. The fraction of positions at which
+// two signatures agree estimates the Jaccard similarity of their shingle sets.
+type Signature [signatureSize]uint64
+
+// seeds are the multipliers that derive each hash function from one base hash.
+var seeds = func() [signatureSize]uint64 {
+	var s [signatureSize]uint64
+	x := uint64(0x9E3779B97F4A7C15)
+	for i := range s {
+		// splitmix64 gives well-spread odd multipliers
+		x += 0x9E3779B97F4A7C15
+		z := x
+		z = (z ^ (z >> 30)) * 0xBF58476D1CE4E5B9
+		z = (z ^ (z >> 27)) * 0x94D049BB133111EB
+		s[i] = (z ^ (z >> 31)) | 1
+	}
+	return s
+}()
+
+// Normalize lower-cases text and reduces it to letters and digits separated by
+// single spaces, so punctuation and spacing don't hide a copy.
+func Normalize(text string) string {
+	return strings.Join(strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}), " ")
+}
+
+// Sign computes the MinHash signature of a text.
+func Sign(text string) Signature {
+	sig, _ := sign(text)
+	return sig
+}
+
+// sign computes the MinHash signature of a text and counts the shingles it was
+// computed from.
+func sign(text string) (Signature, int) {
+	var sig Signature
+	for i := range sig {
+		sig[i] = ^uint64(0)
+	}
+
+	runes := []rune(Normalize(text))
+	if len(runes) == 0 {
+		return sig, 0
+	}
+	size := shingleSize
+	if len(runes) < size {
+		size = len(runes)
+	}
+
+	shingles := 0
+	for i := 0; i+size <= len(runes); i++ {
+		shingles++
+		h := fnv.New64a()
+		h.Write([]byte(string(runes[i : i+size])))
+		base := h.Sum64()
+		for j, seed := range seeds {
+			v := base * seed
+			v ^= v >> 29
+			if v < sig[j] {
+				sig[j] = v
+			}
+		}
+	}
+	return sig, shingles
+}
+
+// Similarity estimates the Jaccard similarity of the texts behind two signatures.
+func (s Signature) Similarity(other Signature) float64 {
+	same := 0
+	for i := range s {
+		if s[i] == other[i] {
+			same++
+		}
+	}
+	return float64(same) / signatureSize
+}
@@ -0,0 +1,138 @@
+package spam
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/furqanmk/reviews-browser/internal/model"
+)
+
+// Reasons a review can be flagged for.
+const (
+	ReasonDuplicate      = "near_duplicate"
+	ReasonRepeatedAuthor = "repeated_author"
+	ReasonShortBurst     = "short_text_burst"
+	// ReasonManual marks a flag set by hand through the API.
+	ReasonManual = "manual"
+)
+
+// Options tunes the detector.
+type Options struct {
+	// DuplicateThreshold is the estimated similarity above which two reviews
+	// are treated as copies of each other.
+	DuplicateThreshold float64
+	// ShortWords is the word count at or below which a review counts as short.
+	ShortWords int
+	// BurstWindow and BurstSize define a burst: at least BurstSize short
+	// reviews posted within BurstWindow of each other.
+	BurstWindow time.Duration
+	BurstSize   int
+}
+
+// DefaultOptions are tuned to catch pasted complaints and "good app" floods
+// without flagging ordinary terse reviews.
+var DefaultOptions = Options{
+	DuplicateThreshold: 0.8,
+	ShortWords:         3,
+	BurstWindow:        15 * time.Minute,
+	BurstSize:          5,
+}
+
+// Detector flags suspected spam by comparing a new review with the reviews
+// already stored for the same app. It is safe for concurrent use.
+type Detector struct {
+	opts Options
+
+	mu sync.Mutex
+	// signatures caches the signatures of stored reviews by review ID, so each
+	// is signed once rather than on every check
+	signatures map[string]sketch
+}
+
+// sketch is a review's signature and the number of shingles behind it.
+type sketch struct {
+	sig      Signature
+	shingles int
+}
+
+// NewDetector creates a detector with the given options.
+func NewDetector(opts Options) *Detector {
+	return &Detector{opts: opts, signatures: make(map[string]sketch)}
+}
+
+// Check returns the reasons the review looks like spam given the app's existing
+// reviews, or nil if it looks genuine. The signatures of existing reviews are
+// cached until Forget is called for them. Reviews too short to sign reliably,
+// including empty ones, are never near-duplicates.
+func (d *Detector) Check(review model.Review, existing []model.Review) []string {
+	var reasons []string
+
+	sig, shingles := sign(review.Title + " " + review.Content)
+	short := d.isShort(review)
+	burst := 1
+
+	// a text too short to compare can't be a duplicate
+	comparable := shingles >= minShingles
+
+	var duplicate, repeatedAuthor bool
+	for _, other := range existing {
+		if other.ID == review.ID {
+			continue
+		}
+		if comparable && !duplicate {
+			if s := d.signature(other); s.shingles >= minShingles && s.sig.Similarity(sig) >= d.opts.DuplicateThreshold {
+				duplicate = true
+			}
+		}
+		if !repeatedAuthor && review.Author != "" && strings.EqualFold(other.Author, review.Author) {
+			repeatedAuthor = true
+		}
+		if short && d.isShort(other) && absDuration(review.CreatedAt.Sub(other.CreatedAt)) <= d.opts.BurstWindow {
+			burst++
+		}
+	}
+
+	if duplicate {
+		reasons = append(reasons, ReasonDuplicate)
+	}
+	if repeatedAuthor {
+		reasons = append(reasons, ReasonRepeatedAuthor)
+	}
+	if short && burst >= d.opts.BurstSize {
+		reasons = append(reasons, ReasonShortBurst)
+	}
+	return reasons
+}
+
+// Forget drops the cached signatures of reviews that are no longer stored.
+func (d *Detector) Forget(ids ...string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for _, id := range ids {
+		delete(d.signatures, id)
+	}
+}
+
+// signature returns a stored review's signature, signing it on first use.
+func (d *Detector) signature(review model.Review) sketch {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	s, ok := d.signatures[review.ID]
+	if !ok {
+		s.sig, s.shingles = sign(review.Title + " " + review.Content)
+		d.signatures[review.ID] = s
+	}
+	return s
+}
+
+func (d *Detector) isShort(review model.Review) bool {
+	return len(strings.Fields(Normalize(review.Content))) <= d.opts.ShortWords
+}
+
+func absDuration(d time.Duration) time.Duration {
+	if d < 0 {
+		return -d
+	}
+	return d
+}
@@ -0,0 +1,67 @@
+package spam_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/furqanmk/reviews-browser/internal/model"
+	"github.com/furqanmk/reviews-browser/internal/spam"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSimilarity(t *testing.T) {
+	a := spam.Sign("This app keeps crashing after the latest update, please fix it!")
+	b := spam.Sign("this app keeps crashing after the latest update please fix it")
+	c := spam.Sign("Lovely design and the new widgets are really handy.")
+
+	require.Equal(t, 1.0, a.Similarity(b))
+	require.Less(t, a.Similarity(c), 0.2)
+}
+
+func TestCheck(t *testing.T) {
+	now := time.Now()
+	detector := spam.NewDetector(spam.DefaultOptions)
+	existing := []model.Review{
+		{ID: "1", Author: "alice", Content: "Crashes every time I open the camera since the update", CreatedAt: now.Add(-time.Hour)},
+		{ID: "2", Author: "bob", Content: "good app", CreatedAt: now.Add(-time.Minute)},
+		{ID: "3", Author: "carol", Content: "nice", CreatedAt: now.Add(-2 * time.Minute)},
+		{ID: "4", Author: "dave", Content: "great", CreatedAt: now.Add(-3 * time.Minute)},
+		{ID: "5", Author: "erin", Content: "love it", CreatedAt: now.Add(-4 * time.Minute)},
+	}
+
+	genuine := model.Review{ID: "6", Author: "frank", Content: "Would love an option to mute stories from a friend", CreatedAt: now}
+	require.Empty(t, detector.Check(genuine, existing))
+
+	copied := model.Review{ID: "7", Author: "grace", Content: "Crashes every time I open the camera since the update!!", CreatedAt: now}
+	require.Equal(t, []string{spam.ReasonDuplicate}, detector.Check(copied, existing))
+
+	sameAuthor := model.Review{ID: "8", Author: "Alice", Content: "Still waiting on a fix for notifications", CreatedAt: now}
+	require.Equal(t, []string{spam.ReasonRepeatedAuthor}, detector.Check(sameAuthor, existing))
+
+	burst := model.Review{ID: "9", Author: "heidi", Content: "Amazing!", CreatedAt: now}
+	require.Equal(t, []string{spam.ReasonShortBurst}, detector.Check(burst, existing))
+}
+
+func TestCheck_ShortTexts(t *testing.T) {
+	detector := spam.NewDetector(spam.DefaultOptions)
+	stored := []model.Review{{ID: "1", Author: "alice"}, {ID: "2", Author: "bob", Content: "ok"}}
+
+	// empty texts all sign alike, and so do very short ones, so neither is
+	// taken for a copy
+	require.Empty(t, detector.Check(model.Review{ID: "3", Author: "carol", Content: "  "}, stored))
+	require.Empty(t, detector.Check(model.Review{ID: "4", Author: "dave", Content: "OK!"}, stored))
+}
+
+func TestCheck_CachesSignatures(t *testing.T) {
+	detector := spam.NewDetector(spam.DefaultOptions)
+	stored := []model.Review{{ID: "1", Content: "Crashes every time I open the camera since the update"}}
+	copied := model.Review{ID: "2", Content: "Crashes every time I open the camera since the update!!"}
+	require.Equal(t, []string{spam.ReasonDuplicate}, detector.Check(copied, stored))
+
+	// a stored review is signed once, so its signature outlives a changed text
+	stored[0].Content = "Lovely design and the new widgets are really handy."
+	require.Equal(t, []string{spam.ReasonDuplicate}, detector.Check(copied, stored))
+
+	detector.Forget("1")
+	require.Empty(t, detector.Check(copied, stored))
+}
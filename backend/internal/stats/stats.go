@@ -0,0 +1,42 @@
+package stats
+
+import (
+	"math"
+
+	"github.com/furqanmk/reviews-browser/internal/language"
+	"github.com/furqanmk/reviews-browser/internal/model"
+)
+
+// Summary aggregates a set of reviews.
+type Summary struct {
+	Total         int            `json:"total"`
+	AverageRating float64        `json:"average_rating"`
+	Ratings       map[int]int    `json:"ratings"`
+	Languages     map[string]int `json:"languages"`
+}
+
+// Summarize computes volume, rating distribution and language breakdown.
+func Summarize(reviews []model.Review) Summary {
+	summary := Summary{
+		Ratings:   map[int]int{1: 0, 2: 0, 3: 0, 4: 0, 5: 0},
+		Languages: make(map[string]int),
+	}
+
+	ratingSum := 0
+	for _, review := range reviews {
+		summary.Total++
+		summary.Ratings[review.Rating]++
+		ratingSum += review.Rating
+
+		lang := review.Language
+		if lang == "" {
+			lang = language.Undetermined
+		}
+		summary.Languages[lang]++
+	}
+
+	if summary.Total > 0 {
+		summary.AverageRating = math.Round(float64(ratingSum)/float64(summary.Total)*100) / 100
+	}
+	return summary
+}
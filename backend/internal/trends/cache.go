@@ -0,0 +1,73 @@
+package trends
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultCacheTTL is how long a computed report is served before the reviews
+// are scanned again.
+const DefaultCacheTTL = 10 * time.Minute
+
+type cacheEntry struct {
+	version int64
+	report  Report
+	expires time.Time
+}
+
+// Cache keeps computed reports in memory so repeated requests for the same app
+// and window don't rescan the reviews. Reports are kept against the version of
+// the app's reviews they were computed from, so a change to the reviews, made
+// by this process or any other, is seen on the next request.
+type Cache struct {
+	ttl     time.Duration
+	mu      sync.Mutex
+	entries map[string]cacheEntry
+}
+
+// NewCache creates a report cache whose entries live for ttl.
+func NewCache(ttl time.Duration) *Cache {
+	return &Cache{
+		ttl:     ttl,
+		entries: make(map[string]cacheEntry),
+	}
+}
+
+// Get returns the cached report for the app and options, computing and storing
+// it with compute on a miss. A report computed from another version of the
+// app's reviews is a miss.
+func (c *Cache) Get(appID string, version int64, opts Options, compute func() (Report, error)) (Report, error) {
+	key := fmt.Sprintf("%s|%s|%d|%d", appID, opts.Window, opts.Limit, opts.MinCount)
+	now := time.Now()
+
+	c.mu.Lock()
+	entry, ok := c.entries[key]
+	c.mu.Unlock()
+	if ok && entry.version == version && now.Before(entry.expires) {
+		return entry.report, nil
+	}
+
+	report, err := compute()
+	if err != nil {
+		return Report{}, err
+	}
+
+	c.mu.Lock()
+	c.entries[key] = cacheEntry{version: version, report: report, expires: now.Add(c.ttl)}
+	c.mu.Unlock()
+	return report, nil
+}
+
+// Invalidate drops all cached reports for an app.
+func (c *Cache) Invalidate(appID string) {
+	prefix := appID + "|"
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for key := range c.entries {
+		if strings.HasPrefix(key, prefix) {
+			delete(c.entries, key)
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package trends
+
+import (
+	"strings"
+	"unicode"
+)
+
+// stopWords holds common English words that carry no signal on their own.
+var stopWords = map[string]bool{}
+
+func init() {
+	for _, w := range strings.Fields(`
+		a about above after again against all also am an and any are aren't as at
+		be because been before being below between both but by can can't cannot
+		could couldn't did didn't do does doesn't doing don't down during each even
+		ever every few for from further get gets got had hadn't has hasn't have
+		haven't having he her here hers herself him himself his how i i'm i've if
+		in into is isn't it it's its itself just let's like me more most much my
+		myself no nor not now of off on once only or other ought our ours ourselves
+		out over own really same she should shouldn't so some still such than that
+		that's the their theirs them themselves then there there's these they
+		they're this those through to too under until up us very was wasn't we
+		we're were weren't what when where which while who whom why will with
+		won't would wouldn't you you're your yours yourself yourselves app apps
+		im ive dont cant doesnt didnt isnt wont thats
+	`) {
+		stopWords[w] = true
+	}
+}
+
+// Tokenize splits text into lower-cased words, dropping punctuation. Apostrophes
+// inside a word are kept so contractions can be matched against the stop-word list.
+func Tokenize(text string) []string {
+	var (
+		tokens []string
+		cur    strings.Builder
+	)
+	flush := func() {
+		if cur.Len() > 0 {
+			tokens = append(tokens, strings.Trim(cur.String(), "'"))
+			cur.Reset()
+		}
+	}
+	for _, r := range strings.ToLower(text) {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			cur.WriteRune(r)
+		case r == '\'' || r == '’':
+			cur.WriteRune('\'')
+		default:
+			flush()
+		}
+	}
+	flush()
+	return tokens
+}
+
+// IsStopWord reports whether the token should be ignored.
+func IsStopWord(token string) bool {
+	return len([]rune(token)) < 2 || stopWords[token]
+}
+
+// Stem reduces a word to a crude root by stripping common English suffixes, so
+// that "crashes", "crashing" and "crashed" are counted together. It is loosely
+// modelled on the first steps of the Porter stemmer.
+func Stem(word string) string {
+	if len(word) <= 3 {
+		return word
+	}
+
+	switch {
+	case strings.HasSuffix(word, "sses"):
+		return strings.TrimSuffix(word, "es")
+	case strings.HasSuffix(word, "ies"):
+		return keepIfShort(word, strings.TrimSuffix(word, "ies")+"y")
+	case strings.HasSuffix(word, "es") && hasAnySuffix(strings.TrimSuffix(word, "es"), "sh", "ch", "x", "zz"):
+		return strings.TrimSuffix(word, "es")
+	case strings.HasSuffix(word, "s") && !hasAnySuffix(word, "ss", "us", "is"):
+		return keepIfShort(word, strings.TrimSuffix(word, "s"))
+	}
+
+	for _, suffix := range []string{"ingly", "edly", "ing", "ed"} {
+		if !strings.HasSuffix(word, suffix) {
+			continue
+		}
+		stem := strings.TrimSuffix(word, suffix)
+		if len(stem) < 3 || !strings.ContainsAny(stem, "aeiouy") {
+			return word
+		}
+		if hasAnySuffix(stem, "at", "bl", "iz") {
+			return stem + "e"
+		}
+		return undouble(stem)
+	}
+	return word
+}
+
+func keepIfShort(word, stem string) string {
+	if len(stem) < 3 {
+		return word
+	}
+	return stem
+}
+
+func hasAnySuffix(s string, suffixes ...string) bool {
+	for _, suffix := range suffixes {
+		if strings.HasSuffix(s, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// undouble collapses a trailing doubled consonant left behind by suffix
+// stripping ("logged" -> "logg" -> "log").
+func undouble(stem string) string {
+	n := len(stem)
+	if n < 2 || stem[n-1] != stem[n-2] {
+		return stem
+	}
+	switch stem[n-1] {
+	case 'l', 's', 'z', 'e', 'o':
+		return stem
+	}
+	return stem[:n-1]
+}
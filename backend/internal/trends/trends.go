@@ -0,0 +1,210 @@
+package trends
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/furqanmk/reviews-browser/internal/model"
+)
+
+// Term is a unigram or bigram along with how often it appears.
+type Term struct {
+	Term          string  `json:"term"`
+	Count         int     `json:"count"`
+	PreviousCount int     `json:"previous_count"`
+	Rising        float64 `json:"rising"`
+}
+
+// Report holds the trending terms for an app over a window.
+type Report struct {
+	AppID           string    `json:"app_id"`
+	WindowHrs       int       `json:"window_hrs"`
+	GeneratedAt     time.Time `json:"generated_at"`
+	ReviewsCurrent  int       `json:"reviews_current"`
+	ReviewsPrevious int       `json:"reviews_previous"`
+	Unigrams        []Term    `json:"unigrams"`
+	Bigrams         []Term    `json:"bigrams"`
+}
+
+// Options controls how a report is computed.
+type Options struct {
+	Window time.Duration
+	Limit  int
+	// MinCount drops terms seen in fewer reviews than this in the current window.
+	MinCount int
+}
+
+// Analyze extracts the top unigrams and bigrams from reviews in the window ending
+// at now, and scores each against the window immediately before it.
+func Analyze(appID string, reviews []model.Review, now time.Time, opts Options) Report {
+	if opts.MinCount <= 0 {
+		opts.MinCount = 1
+	}
+
+	var (
+		currentStart  = now.Add(-opts.Window)
+		previousStart = currentStart.Add(-opts.Window)
+		current       = newCounter()
+		previous      = newCounter()
+	)
+
+	for _, review := range reviews {
+		switch {
+		case !review.CreatedAt.Before(currentStart) && !review.CreatedAt.After(now):
+			current.add(review)
+		case !review.CreatedAt.Before(previousStart) && review.CreatedAt.Before(currentStart):
+			previous.add(review)
+		}
+	}
+
+	return Report{
+		AppID:           appID,
+		WindowHrs:       int(opts.Window / time.Hour),
+		GeneratedAt:     now,
+		ReviewsCurrent:  current.docs,
+		ReviewsPrevious: previous.docs,
+		Unigrams:        rank(current, previous, current.unigrams, previous.unigrams, opts),
+		Bigrams:         rank(current, previous, current.bigrams, previous.bigrams, opts),
+	}
+}
+
+// counter tallies document frequencies, so a term repeated within one review
+// counts once.
+type counter struct {
+	docs     int
+	unigrams map[string]int
+	bigrams  map[string]int
+	// surface remembers how each stemmed key was most often written, so the
+	// report shows "crashes" rather than "crash" when that is what users wrote.
+	surface map[string]map[string]int
+}
+
+func newCounter() *counter {
+	return &counter{
+		unigrams: make(map[string]int),
+		bigrams:  make(map[string]int),
+		surface:  make(map[string]map[string]int),
+	}
+}
+
+func (c *counter) add(review model.Review) {
+	c.docs++
+
+	seen := make(map[string]bool)
+	for _, text := range []string{review.Title, review.Content} {
+		// Bigrams are built per text so the last word of a title isn't paired with
+		// the first word of the content, and never across a removed stop word.
+		var prev, prevWord string
+		for _, token := range Tokenize(text) {
+			if IsStopWord(token) {
+				prev = ""
+				continue
+			}
+			stem := Stem(token)
+			c.note(stem, token)
+			if !seen[stem] {
+				seen[stem] = true
+				c.unigrams[stem]++
+			}
+			if prev != "" {
+				key := prev + " " + stem
+				c.note(key, prevWord+" "+token)
+				if !seen[key] {
+					seen[key] = true
+					c.bigrams[key]++
+				}
+			}
+			prev, prevWord = stem, token
+		}
+	}
+}
+
+func (c *counter) note(key, word string) {
+	if c.surface[key] == nil {
+		c.surface[key] = make(map[string]int)
+	}
+	c.surface[key][word]++
+}
+
+func (c *counter) display(key string) string {
+	best, bestCount := key, 0
+	for word, count := range c.surface[key] {
+		if count > bestCount || count == bestCount && word < best {
+			best, bestCount = word, count
+		}
+	}
+	return best
+}
+
+// Counter tallies the terms of reviews added to it one at a time, for callers
+// that stream reviews rather than hold them all.
+type Counter struct {
+	c *counter
+}
+
+// NewCounter returns a Counter that has seen no reviews.
+func NewCounter() *Counter {
+	return &Counter{c: newCounter()}
+}
+
+// Add counts the terms of a review.
+func (c *Counter) Add(review model.Review) {
+	c.c.add(review)
+}
+
+// Top returns up to limit unigrams and bigrams seen in the most reviews,
+// leaving out those seen in fewer than minCount. There is no earlier window to
+// score them against, so only Term and Count are set.
+func (c *Counter) Top(limit, minCount int) (unigrams, bigrams []Term) {
+	opts := Options{Limit: limit, MinCount: max(minCount, 1)}
+	none := newCounter()
+	unigrams = rank(c.c, none, c.c.unigrams, none.unigrams, opts)
+	bigrams = rank(c.c, none, c.c.bigrams, none.bigrams, opts)
+	for _, terms := range [][]Term{unigrams, bigrams} {
+		for i := range terms {
+			terms[i].Rising = 0
+		}
+	}
+	return unigrams, bigrams
+}
+
+func rank(current, previous *counter, cur, prev map[string]int, opts Options) []Term {
+	terms := make([]Term, 0, len(cur))
+	for key, count := range cur {
+		if count < opts.MinCount {
+			continue
+		}
+		terms = append(terms, Term{
+			Term:          current.display(key),
+			Count:         count,
+			PreviousCount: prev[key],
+			Rising:        rising(count, current.docs, prev[key], previous.docs),
+		})
+	}
+
+	sort.Slice(terms, func(i, j int) bool {
+		if terms[i].Count != terms[j].Count {
+			return terms[i].Count > terms[j].Count
+		}
+		if terms[i].Rising != terms[j].Rising {
+			return terms[i].Rising > terms[j].Rising
+		}
+		return strings.Compare(terms[i].Term, terms[j].Term) < 0
+	})
+
+	if opts.Limit > 0 && len(terms) > opts.Limit {
+		terms = terms[:opts.Limit]
+	}
+	return terms
+}
+
+// rising is the log2 ratio of a term's share of reviews in the current window
+// to its share in the previous one. Add-one smoothing keeps brand new terms
+// finite; a positive score means the term is becoming more common.
+func rising(count, docs, prevCount, prevDocs int) float64 {
+	cur := float64(count+1) / float64(docs+1)
+	prev := float64(prevCount+1) / float64(prevDocs+1)
+	return math.Round(math.Log2(cur/prev)*1000) / 1000
+}
@@ -0,0 +1,104 @@
+package trends_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/furqanmk/reviews-browser/internal/model"
+	"github.com/furqanmk/reviews-browser/internal/trends"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStem(t *testing.T) {
+	cases := map[string]string{
+		"crashes":  "crash",
+		"crashing": "crash",
+		"crashed":  "crash",
+		"logged":   "log",
+		"updating": "update",
+		"stories":  "story",
+		"glass":    "glass",
+		"bus":      "bus",
+		"mode":     "mode",
+	}
+	for word, want := range cases {
+		require.Equal(t, want, trends.Stem(word), word)
+	}
+}
+
+func TestTokenize(t *testing.T) {
+	tokens := trends.Tokenize("Dark-mode PLEASE! It doesn’t work.")
+	require.Equal(t, []string{"dark", "mode", "please", "it", "doesn't", "work"}, tokens)
+}
+
+func TestAnalyze(t *testing.T) {
+	now := time.Now()
+	review := func(id, content string, age time.Duration) model.Review {
+		return model.Review{ID: id, Content: content, CreatedAt: now.Add(-age)}
+	}
+	reviews := []model.Review{
+		review("1", "Please add dark mode", time.Hour),
+		review("2", "Dark mode when?", 2*time.Hour),
+		review("3", "Stuck in a login loop, login loop again", 3*time.Hour),
+		review("4", "Login loops after the update", 4*time.Hour),
+		review("5", "Login works fine", 30*time.Hour),
+		review("6", "Too old to count", 60*time.Hour),
+	}
+
+	report := trends.Analyze("123", reviews, now, trends.Options{Window: 24 * time.Hour, MinCount: 2})
+
+	require.Equal(t, 4, report.ReviewsCurrent)
+	require.Equal(t, 1, report.ReviewsPrevious)
+
+	bigrams := map[string]trends.Term{}
+	for _, term := range report.Bigrams {
+		bigrams[term.Term] = term
+	}
+	require.Contains(t, bigrams, "dark mode")
+	require.Contains(t, bigrams, "login loop")
+	require.Equal(t, 2, bigrams["login loop"].Count)
+
+	var login trends.Term
+	for _, term := range report.Unigrams {
+		if term.Term == "login" {
+			login = term
+		}
+	}
+	require.Equal(t, 2, login.Count)
+	require.Equal(t, 1, login.PreviousCount)
+	require.Less(t, login.Rising, bigrams["dark mode"].Rising)
+}
+
+func TestCounter(t *testing.T) {
+	counter := trends.NewCounter()
+	for _, content := range []string{"Login loop again", "Login loop", "Dark mode please", "login"} {
+		counter.Add(model.Review{Content: content})
+	}
+
+	unigrams, bigrams := counter.Top(2, 2)
+	require.Equal(t, []trends.Term{{Term: "login", Count: 3}, {Term: "loop", Count: 2}}, unigrams)
+	require.Equal(t, []trends.Term{{Term: "login loop", Count: 2}}, bigrams)
+}
+
+func TestCache(t *testing.T) {
+	cache := trends.NewCache(time.Minute)
+	opts := trends.Options{Window: time.Hour}
+	calls := 0
+	compute := func() (trends.Report, error) {
+		calls++
+		return trends.Report{AppID: "123"}, nil
+	}
+
+	_, _ = cache.Get("123", 1, opts, compute)
+	_, _ = cache.Get("123", 1, opts, compute)
+	require.Equal(t, 1, calls)
+
+	// a new version of the reviews is computed afresh, once
+	_, _ = cache.Get("123", 2, opts, compute)
+	_, _ = cache.Get("123", 2, opts, compute)
+	require.Equal(t, 2, calls)
+
+	cache.Invalidate("123")
+	_, _ = cache.Get("123", 2, opts, compute)
+	require.Equal(t, 3, calls)
+}
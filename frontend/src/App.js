@@ -63,6 +63,44 @@ function ReviewCard({ review }) {
     );
 }
 
+// statusHints explain the failures a visitor can run into, since the browser
+// sends no API key
+const statusHints = {
+    401: 'This server requires an API key to read reviews, and the browser doesn\'t send one',
+    403: 'Your API key may not see this app',
+    404: 'The server doesn\'t know this app',
+    429: 'Too many requests; wait a minute and try again',
+    503: 'The App Store can\'t be reached right now; try again later',
+};
+
+// problemMessage explains a failed response, from its status and the readable
+// detail of its RFC 7807 problem body
+const problemMessage = async (response) => {
+    const body = await response.json().catch(() => ({}));
+    const detail = body.detail || body.title || response.statusText;
+    const hint = statusHints[response.status];
+    return hint ? `${hint} (${detail})` : detail;
+};
+
+// waitForJob polls a queued job every second until it has finished, and
+// fails unless it succeeded
+const waitForJob = async (location) => {
+    for (;;) {
+        const response = await fetch(location);
+        if (!response.ok) {
+            throw new Error(await problemMessage(response));
+        }
+        const job = (await response.json()).data;
+        if (job.status === 'succeeded') {
+            return job;
+        }
+        if (job.status === 'failed') {
+            throw new Error(job.error || 'Fetching from the App Store failed');
+        }
+        await new Promise((resolve) => setTimeout(resolve, 1000));
+    }
+};
+
 // Main app component
 function App() {
     const [reviews, setReviews] = useState([]);
@@ -81,20 +119,38 @@ function App() {
         setError(null);
         
         try {
-            const url = `/api/reviews_by_app?app_id=${appID}`;
-            const response = await fetch(url);
-            const reviews = await response.json();
-
-            if (reviews == null) {
-              setReviews([]);
-            } else {
-              setReviews(reviews);
+            const path = `/api/v1/apps/${encodeURIComponent(appID)}`;
+            // stored reviews carry an ETag, so the browser only downloads
+            // them again when they have changed; apps with nothing stored
+            // yet are fetched from the App Store by a queued poll, and read
+            // again once it has finished
+            const loadStored = async () => {
+                const response = await fetch(`${path}/reviews?limit=500`);
+                if (!response.ok) {
+                    throw new Error(await problemMessage(response));
+                }
+                return (await response.json()).data;
+            };
+            let stored = await loadStored();
+            if (stored.length === 0) {
+                const response = await fetch(`${path}/fetch`, { method: 'POST' });
+                if (!response.ok) {
+                    throw new Error(await problemMessage(response));
+                }
+                const job = (await response.json()).data;
+                await waitForJob(response.headers.get('Location') || `/api/v1/jobs/${job.id}`);
+                stored = await loadStored();
             }
+            setReviews(stored);
 
             setAppId(appID);
             
         } catch (err) {
-            setError('Failed to fetch reviews. Please try again.');
+            // fetch itself only throws a TypeError when the server can't be
+            // reached; anything else has been explained already
+            setError(err instanceof TypeError
+                ? `Could not reach the server: ${err.message}. Please try again.`
+                : err.message);
             console.error('Error fetching reviews:', err);
         } finally {
             setLoading(false);